@@ -0,0 +1,47 @@
+package gitbrag
+
+import (
+	"fmt"
+
+	"github.com/radulucut/gitbrag/internal"
+	"github.com/spf13/cobra"
+)
+
+// initCache adds the cache flags and the cache command. Any other arguments
+// of gitbrag cache are directories, so that a directory named cache can still
+// be scanned with the flags of the scan.
+func (r *Root) initCache() {
+	r.Cmd.Flags().Bool("no-cache", false, "always run git log instead of reusing the commits cached from earlier runs")
+
+	cache := &cobra.Command{
+		RunE:  r.RunCache,
+		Use:   "cache",
+		Short: "Manage the cache of commits read from repositories",
+		Args:  cobra.ArbitraryArgs,
+	}
+	cache.Flags().AddFlagSet(r.Cmd.Flags())
+	cache.AddCommand(&cobra.Command{
+		RunE:  r.RunCacheClear,
+		Use:   "clear",
+		Short: "Remove all cached commits",
+		Args:  cobra.NoArgs,
+	})
+	r.Cmd.AddCommand(cache)
+}
+
+// RunCache scans the directory named cache and the other directories given
+func (r *Root) RunCache(cmd *cobra.Command, args []string) error {
+	return r.RunRoot(cmd, append([]string{"cache"}, args...))
+}
+
+func (r *Root) RunCacheClear(cmd *cobra.Command, args []string) error {
+	dir, err := internal.DefaultCacheDir()
+	if err != nil {
+		return fmt.Errorf("could not find cache directory: %w", err)
+	}
+	if err := internal.ClearCache(dir); err != nil {
+		return fmt.Errorf("could not clear cache: %w", err)
+	}
+	r.printer.Printf("Cache cleared: %s\n", dir)
+	return nil
+}
@@ -4,7 +4,10 @@ import (
 	"fmt"
 	"os"
 	"regexp"
+	"runtime"
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/radulucut/gitbrag/internal"
 	"github.com/radulucut/gitbrag/internal/utils"
@@ -66,15 +69,46 @@ Examples:
   gitbrag ./ --since 2024-01-01
   gitbrag ./ --since 2024-01-01 --until 2024-12-31
   gitbrag ./ --since 7d
+  gitbrag ./ --since 3mo
+  gitbrag ./ --since 14d --until 7d
+
+  # The same card from a CI runner and a laptop, or by the local time of each author
+  gitbrag ./ --since 2025-01-01 --tz Europe/Bucharest -O stats.png --heatmap
+  gitbrag ./ --since 30d --tz UTC --author-time --format json
+
+  # Count rebased and cherry-picked work on the day it was written
+  gitbrag ./ --range last-week --date-field author
+
+  # Named periods, weeks start on Monday unless --week-start is set
+  gitbrag ./ --range last-week
+  gitbrag ./ --range this-quarter
+  gitbrag ./ --range 2025-Q3 --by-author
+  gitbrag ./ --range this-week --week-start sunday
 
   # Filter by author name or email
   gitbrag ./ --author "John Doe"
   gitbrag ./ --since 7d --author john@example.com
 
+  # Roll up a team, without the bots
+  gitbrag ~/work --author jane@example.com --author john@example.com
+  gitbrag ~/work --authors-file team.txt --exclude-author '\[bot\]' --exclude-author renovate
+
+  # Credit pair programming partners from the Co-authored-by trailers, with
+  # the whole commit or an even share of its lines
+  gitbrag ~/work --by-author --coauthors credit
+  gitbrag ~/work --author jane@example.com --coauthors split
+
+  # Merge the identities of a person in every repository, with the syntax of
+  # .mailmap, ~/.config/gitbrag/mailmap is read by default
+  gitbrag ~/work --by-author --mailmap-file identities.txt
+
   # Output statistics to PNG file
   gitbrag ./ -O stats.png
   gitbrag ./ --output stats.png --background "#282a36"
 
+  # Output statistics to SVG file
+  gitbrag ./ -O stats.svg
+
   # Use custom background and foreground colors
   gitbrag ./ -O stats.png -B fff
   gitbrag ./ -O stats.png --color "#50fa7b"
@@ -84,6 +118,51 @@ Examples:
   # Show language breakdown (top 3 + Others)
   gitbrag ./ -O stats.png --lang
 
+  # Show statistics per repository
+  gitbrag ~/work --by-repo
+  gitbrag ~/work --by-repo -O stats.png
+
+  # Show a leaderboard of authors
+  gitbrag ./ --since 14d --by-author
+  gitbrag ./ --since 14d --by-author -O stats.png
+
+  # Output statistics as JSON
+  gitbrag ./ --format json
+  gitbrag ./ --since 30d --format json > stats.json
+  gitbrag ./ --since 30d -O stats.json
+
+  # Draw a calendar heatmap of daily commits or lines changed
+  gitbrag ./ --since 2025-01-01 -O stats.png --heatmap
+  gitbrag ./ --since 2025-01-01 -O stats.png --heatmap=lines
+
+  # Choose the refs to read commits from, local branches by default
+  gitbrag ./ --refs remotes
+  gitbrag ./ --refs branches,tags
+  gitbrag ./ --refs 'origin/release/*'
+
+  # Count the changes of merges, skip merges or only follow the mainline
+  gitbrag ./ --merges include
+  gitbrag ./ --since 30d --author john@example.com --merges exclude
+  gitbrag ./ --refs main --merges first-parent
+
+  # Show the lines kept by renamed and copied files separately
+  gitbrag ./ --moved
+
+  # Show how much the binary files grew or shrank next to their count
+  gitbrag ./ --binary-bytes
+
+  # Also count generated files such as lockfiles and files marked with
+  # linguist-generated, linguist-vendored or linguist-documentation
+  gitbrag ./ --include-generated
+
+  # Count cherry-picked and rebased copies of a change once
+  gitbrag ~/work --refs all --dedupe
+
+  # Only count some files, or skip files with gitignore-style patterns, also
+  # read from the .gitbragignore file of each repository
+  gitbrag ./ --include 'src/**' --include '**/*.go'
+  gitbrag ./ --exclude '**/testdata/' --exclude 'docs/**' --exclude '!docs/api/**'
+
   # Exclude files matching regex pattern
   gitbrag ./ --exclude-files '.*\.lock$'
   gitbrag ./ --exclude-files 'package-lock\.json'
@@ -91,6 +170,16 @@ Examples:
   # Exclude directories matching regex pattern
   gitbrag ./ --exclude-dirs 'node_modules|vendor'
   gitbrag ./ --exclude-dirs '.*test.*'
+
+  # Limit the number of repositories scanned in parallel
+  gitbrag ~/projects --jobs 4
+
+  # Read the history without a git binary
+  gitbrag ~/projects --backend native
+
+  # Skip or clear the cache of commits read from unchanged repositories
+  gitbrag ~/projects --no-cache
+  gitbrag cache clear
 `,
 		Version: version,
 		RunE:    root.RunRoot,
@@ -101,37 +190,117 @@ Examples:
 	root.Cmd.SetErr(root.printer.ErrWriter)
 
 	flags := root.Cmd.Flags()
-	flags.String("since", "", "specific date (e.g. 2024-01-01 12:03:04) or duration (e.g. 1d)")
-	flags.String("until", "", "specific date (e.g. 2024-12-31 23:59:59)")
-	flags.String("author", "", "filter by author name or email")
-	flags.StringP("output", "O", "", "export statistics to PNG file (e.g. stats.png)")
+	flags.String("since", "", "specific date (e.g. 2024-01-01 12:03:04) or duration before now in years, months, weeks, days, hours and minutes (e.g. 7d, 2w, 3mo, 1y6mo)")
+	flags.String("until", "", "specific date (e.g. 2024-12-31 23:59:59) or duration before now (e.g. 7d)")
+	flags.String("range", "", "named period instead of --since and --until: today, yesterday, this-week, last-week, this-month, last-month, this-quarter, last-quarter, this-year, last-year, a year (2025), a quarter (2025-Q3) or a month (2025-10)")
+	flags.String("week-start", "monday", "first day of the weeks of --range this-week and last-week")
+	flags.String("tz", "local", "time zone of dates, of the period label and of the days commits are grouped in, an IANA name (e.g. Europe/Bucharest or UTC)")
+	flags.String("date-field", internal.DateCommitter, "date of the commits filtered by the period and grouped into days: committer, changed by rebases and cherry-picks, or author, when the change was written")
+	flags.Bool("author-time", false, "group commits into days in the time zone of their author, from the commit, instead of --tz")
+	flags.StringArray("author", nil, "filter by author name or email, a regular expression matched against 'Name <email>' ignoring case, repeatable")
+	flags.StringArray("exclude-author", nil, "skip the commits of authors matching a regular expression, repeatable (e.g. 'dependabot')")
+	flags.String("authors-file", "", "file listing the authors to count, one 'Name <email>', email or name per line")
+	flags.String("coauthors", internal.CoauthorsIgnore, "how to attribute commits to the co-authors of their Co-authored-by trailers: credit them with the whole commit, split the lines between the authors or ignore them")
+	flags.String("mailmap-file", "", "mailmap merging author identities in every repository, after the .mailmap of each repository (default gitbrag/mailmap in the user config directory)")
+	flags.Bool("no-mailmap", false, "ignore the .mailmap files of the repositories")
+	flags.String("format", internal.FormatText, "output format: "+strings.Join(internal.RendererFormats(), ", "))
+	flags.StringP("output", "O", "", "export statistics to file, the format is chosen by extension unless --format is set (e.g. stats.png, stats.svg, stats.json, stats.txt)")
 	flags.StringP("background", "B", "", "background color in hex format (e.g. #282a36 or 282a36), transparent by default")
 	flags.StringP("color", "C", "", "text color in hex format (e.g. #f8f8f2 or f8f8f2)")
-	flags.Bool("lang", false, "show language breakdown with top 3 languages and others (PNG output only)")
+	flags.Bool("lang", false, "show language breakdown with top 3 languages and others (PNG and SVG output only)")
+	flags.String("heatmap", "", "draw a calendar heatmap of daily activity: commits or lines (PNG and SVG output only)")
+	flags.Lookup("heatmap").NoOptDefVal = internal.HeatmapCommits
+	flags.Bool("by-repo", false, "show statistics per repository")
+	flags.Bool("by-author", false, "show a leaderboard of authors ranked by lines changed")
 	flags.String("exclude-files", "", "exclude files matching regex pattern (e.g. '.*package-lock.json$')")
+	flags.StringArray("include", nil, "only count files matching a gitignore-style pattern (e.g. 'src/**'), repeatable")
+	flags.StringArray("exclude", nil, "skip files matching a gitignore-style pattern (e.g. '**/*.md' or '!README.md' to count it again), repeatable, applied after .gitbragignore")
 	flags.String("exclude-dirs", "", "exclude directories matching regex pattern (e.g. 'node_modules|vendor')")
+	flags.IntP("jobs", "j", 0, "number of repositories scanned in parallel (default number of CPUs)")
+	flags.StringSlice("refs", []string{internal.RefsBranches}, "refs to read commits from: head, branches, remotes, tags, all or ref globs (e.g. origin/release/*)")
+	flags.String("merges", "", "how to count merge commits: include their changes, exclude them or follow only the first-parent history (by default merges are counted without their changes)")
+	flags.Bool("moved", false, "show the lines kept by renamed and copied files, which are not counted as insertions")
+	flags.Bool("binary-bytes", false, "show the size change of binary files next to their count")
+	flags.Bool("include-generated", false, "count generated, vendored and documentation files, which are skipped by default (see linguist-generated in .gitattributes)")
+	flags.Bool("dedupe", false, "count cherry-picked and rebased copies of a change once, also across repositories (reads every changed file, slower)")
+	flags.String("backend", internal.BackendExec, "how to read the history: exec runs git log, native reads the repositories without a git binary")
 
 	root.initVersion()
+	root.initCache()
 
 	return root, nil
 }
 
 func (r *Root) RunRoot(cmd *cobra.Command, args []string) error {
-	since, err := r.parseSinceFlag(cmd.Flag("since").Value.String())
+	loc, err := utils.LoadLocation(cmd.Flag("tz").Value.String())
+	if err != nil {
+		return fmt.Errorf("invalid tz: %w", err)
+	}
+	authorTime, _ := cmd.Flags().GetBool("author-time")
+	dateField := cmd.Flag("date-field").Value.String()
+	since, err := r.parseDateFlag(cmd.Flag("since").Value.String(), loc)
 	if err != nil {
 		return err
 	}
-	until, err := r.parseUntilFlag(cmd.Flag("until").Value.String())
+	until, err := r.parseDateFlag(cmd.Flag("until").Value.String(), loc)
 	if err != nil {
 		return err
 	}
-	author := cmd.Flag("author").Value.String()
+	var periodLabel string
+	if periodFlag := cmd.Flag("range").Value.String(); periodFlag != "" {
+		if cmd.Flags().Changed("since") || cmd.Flags().Changed("until") {
+			return fmt.Errorf("--range can't be used with --since or --until")
+		}
+		weekStart, err := utils.ParseWeekday(cmd.Flag("week-start").Value.String())
+		if err != nil {
+			return fmt.Errorf("invalid week-start: %w", err)
+		}
+		period, err := utils.ParsePeriod(periodFlag, r.time.Now().In(loc), weekStart)
+		if err != nil {
+			return err
+		}
+		since, until, periodLabel = period.Since, period.Until, period.Label
+	}
+	if !since.IsZero() && !until.IsZero() && until.Before(since) {
+		return fmt.Errorf("invalid period: until %s is before since %s", until.Format(time.RFC3339), since.Format(time.RFC3339))
+	}
+	authors, _ := cmd.Flags().GetStringArray("author")
+	excludeAuthors, _ := cmd.Flags().GetStringArray("exclude-author")
+	authorsFile := cmd.Flag("authors-file").Value.String()
+	coauthors := cmd.Flag("coauthors").Value.String()
+	mailmapFile := cmd.Flag("mailmap-file").Value.String()
+	noMailmap, _ := cmd.Flags().GetBool("no-mailmap")
+	// Without --format, the output file extension chooses the format
+	var format string
+	if cmd.Flags().Changed("format") {
+		format = cmd.Flag("format").Value.String()
+	}
 	output := cmd.Flag("output").Value.String()
 	background := cmd.Flag("background").Value.String()
 	color := cmd.Flag("color").Value.String()
 	lang, _ := cmd.Flags().GetBool("lang")
+	heatmap := cmd.Flag("heatmap").Value.String()
+	byRepo, _ := cmd.Flags().GetBool("by-repo")
+	byAuthor, _ := cmd.Flags().GetBool("by-author")
 	excludeFiles := cmd.Flag("exclude-files").Value.String()
+	include, _ := cmd.Flags().GetStringArray("include")
+	exclude, _ := cmd.Flags().GetStringArray("exclude")
 	excludeDirs := cmd.Flag("exclude-dirs").Value.String()
+	noCache, _ := cmd.Flags().GetBool("no-cache")
+	backend := cmd.Flag("backend").Value.String()
+	refs, _ := cmd.Flags().GetStringSlice("refs")
+	dedupe, _ := cmd.Flags().GetBool("dedupe")
+	merges := cmd.Flag("merges").Value.String()
+	moved, _ := cmd.Flags().GetBool("moved")
+	binaryBytes, _ := cmd.Flags().GetBool("binary-bytes")
+	generated, _ := cmd.Flags().GetBool("include-generated")
+	jobs, _ := cmd.Flags().GetInt("jobs")
+	if jobs < 0 {
+		return fmt.Errorf("invalid jobs: %d, must be at least 1", jobs)
+	}
+	if jobs == 0 {
+		jobs = runtime.NumCPU()
+	}
 
 	var excludeFilesRegexp *regexp.Regexp
 	if excludeFiles != "" {
@@ -149,34 +318,78 @@ func (r *Root) RunRoot(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	return r.core.Run(&internal.RunOptions{
-		Dirs:         args,
-		Since:        since,
-		Until:        until,
-		Author:       author,
-		Output:       output,
-		Background:   background,
-		Color:        color,
-		Lang:         lang,
-		ExcludeFiles: excludeFilesRegexp,
-		ExcludeDirs:  excludeDirsRegexp,
+	// The default mailmap is only read when it exists
+	if !cmd.Flags().Changed("mailmap-file") {
+		if path, err := internal.DefaultMailmapFile(); err == nil {
+			if _, err := os.Stat(path); err == nil {
+				mailmapFile = path
+			}
+		}
+	}
+
+	// The cache is skipped when there is no cache directory
+	var cacheDir string
+	if !noCache {
+		cacheDir, _ = internal.DefaultCacheDir()
+	}
+
+	return r.core.Run(cmd.Context(), &internal.RunOptions{
+		Dirs:           args,
+		Format:         format,
+		Since:          since,
+		Until:          until,
+		PeriodLabel:    periodLabel,
+		DateField:      dateField,
+		Location:       loc,
+		AuthorTime:     authorTime,
+		Authors:        authors,
+		ExcludeAuthors: excludeAuthors,
+		AuthorsFile:    authorsFile,
+		Coauthors:      coauthors,
+		MailmapFile:    mailmapFile,
+		NoMailmap:      noMailmap,
+		Output:         output,
+		Background:     background,
+		Color:          color,
+		Lang:           lang,
+		Heatmap:        heatmap,
+		ByRepo:         byRepo,
+		ByAuthor:       byAuthor,
+		ExcludeFiles:   excludeFilesRegexp,
+		Include:        include,
+		Exclude:        exclude,
+		ExcludeDirs:    excludeDirsRegexp,
+		CacheDir:       cacheDir,
+		Jobs:           jobs,
+		Backend:        backend,
+		Refs:           refs,
+		Dedupe:         dedupe,
+		Merges:         merges,
+		Moved:          moved,
+		BinaryBytes:    binaryBytes,
+		Generated:      generated,
 	})
 }
 
-func (r *Root) parseSinceFlag(flag string) (time.Time, error) {
+// parseDateFlag parses a date in the time zone, or a duration before now such
+// as 7d or 3mo
+func (r *Root) parseDateFlag(flag string, loc *time.Location) (time.Time, error) {
 	if flag == "" {
 		return time.Time{}, nil
 	}
 	d, err := utils.ParseDuration(flag)
 	if err == nil {
-		return r.time.Now().Add(-d), nil
+		return d.Before(r.time.Now().In(loc)), nil
 	}
-	return utils.ParseDateTime(flag)
-}
-
-func (r *Root) parseUntilFlag(flag string) (time.Time, error) {
-	if flag == "" {
-		return time.Time{}, nil
+	t, dateErr := utils.ParseDateTime(flag, loc)
+	if dateErr == nil {
+		return t, nil
+	}
+	// Dates have separators, a value of only letters and digits is a duration
+	for _, c := range flag {
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+			return time.Time{}, dateErr
+		}
 	}
-	return utils.ParseDateTime(flag)
+	return time.Time{}, err
 }
@@ -2,10 +2,15 @@ package gitbrag
 
 import (
 	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
 	"os"
+	"os/exec"
 	"path/filepath"
-	"runtime"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/radulucut/gitbrag/internal"
 	"github.com/radulucut/gitbrag/mocks"
@@ -36,7 +41,15 @@ func Test_Default(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	assert.Equal(t, " 2 files changed\n11 insertions(+)\n 1 deletions(-)\n", out.String())
+	assert.Equal(t, ` 2 commits
+ 2 active days
+ 2 files changed
+11 insertions(+)
+ 1 deletions(-)
+
+First commit: Mar 10, 2025 10:00:00
+Last commit:  Mar 12, 2025 09:30:00
+`, out.String())
 }
 
 func Test_AuthorFlag(t *testing.T) {
@@ -63,7 +76,15 @@ func Test_AuthorFlag(t *testing.T) {
 		t.Fatal(err)
 	}
 	// Only the second commit by John Doe should be counted (1 file changed, 0 insertions, 1 deletion)
-	assert.Equal(t, "1 files changed\n0 insertions(+)\n1 deletions(-)\n", out.String())
+	assert.Equal(t, `1 commits
+1 active days
+1 files changed
+0 insertions(+)
+1 deletions(-)
+
+First commit: Mar 12, 2025 09:30:00
+Last commit:  Mar 12, 2025 09:30:00
+`, out.String())
 }
 
 func Test_AuthorFlagByEmail(t *testing.T) {
@@ -90,15 +111,160 @@ func Test_AuthorFlagByEmail(t *testing.T) {
 		t.Fatal(err)
 	}
 	// Only the second commit by john.doe@example.com should be counted (1 file changed, 0 insertions, 1 deletion)
-	assert.Equal(t, "1 files changed\n0 insertions(+)\n1 deletions(-)\n", out.String())
+	assert.Equal(t, `1 commits
+1 active days
+1 files changed
+0 insertions(+)
+1 deletions(-)
+
+First commit: Mar 12, 2025 09:30:00
+Last commit:  Mar 12, 2025 09:30:00
+`, out.String())
 }
 
-func Test_PNG_Output_1(t *testing.T) {
-	// skip if not macOS
-	if runtime.GOOS != "darwin" {
-		t.Skip("skipping on non-macOS")
+func Test_MultipleAuthors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	timeMock := mocks.NewMockTime(ctrl)
+	timeMock.EXPECT().Now().Return(defaultCurrentTime).AnyTimes()
+
+	testDir := createGitRepo(t)
+	authorsFile := filepath.Join(t.TempDir(), "team.txt")
+	if err := os.WriteFile(authorsFile, []byte("# team\nJohn Doe <JOHN.DOE@example.com>\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(args ...string) string {
+		return mustRunGitbrag(t, timeMock, append([]string{testDir}, args...)...)
+	}
+
+	both := ` 2 commits
+ 2 active days
+ 2 files changed
+11 insertions(+)
+ 1 deletions(-)
+
+First commit: Mar 10, 2025 10:00:00
+Last commit:  Mar 12, 2025 09:30:00
+`
+	john := `1 commits
+1 active days
+1 files changed
+0 insertions(+)
+1 deletions(-)
+
+First commit: Mar 12, 2025 09:30:00
+Last commit:  Mar 12, 2025 09:30:00
+`
+	for _, backend := range []string{"exec", "native"} {
+		assert.Equal(t, both, run("--backend", backend, "--author", "John Doe", "--author", "test@example.com"), backend)
+		assert.Equal(t, john, run("--backend", backend, "--author", "JOHN.DOE@EXAMPLE.COM"), backend)
+		assert.Equal(t, john, run("--backend", backend, "--exclude-author", "^Test User"), backend)
+		assert.Equal(t, john, run("--backend", backend, "--authors-file", authorsFile), backend)
+		assert.Equal(t, both, run("--backend", backend, "--authors-file", authorsFile, "--author", "Test"), backend)
+	}
+}
+
+func Test_Mailmap(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	timeMock := mocks.NewMockTime(ctrl)
+	timeMock.EXPECT().Now().Return(defaultCurrentTime).AnyTimes()
+
+	// John Doe also committed as Test User from an old laptop
+	testDir := createGitRepo(t)
+	if err := os.WriteFile(filepath.Join(testDir, ".mailmap"), []byte("John Doe <john.doe@example.com> <TEST@example.com>\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(args ...string) string {
+		out := mustRunGitbrag(t, timeMock, append([]string{testDir, "--by-author", "--format", "json"}, args...)...)
+		var report internal.JSONReport
+		if err := json.Unmarshal([]byte(out), &report); err != nil {
+			t.Fatal(err)
+		}
+		var authors []string
+		for _, author := range report.Authors {
+			authors = append(authors, fmt.Sprintf("%s <%s> %d", author.Name, author.Email, author.Commits))
+		}
+		return strings.Join(authors, ", ")
+	}
+
+	for _, backend := range []string{"exec", "native"} {
+		assert.Equal(t, "John Doe <john.doe@example.com> 2", run("--backend", backend), backend)
+		assert.Equal(t, "John Doe <john.doe@example.com> 2", run("--backend", backend, "--author", "^John Doe <john"), backend)
+		assert.Equal(t, "Test User <test@example.com> 1, John Doe <john.doe@example.com> 1", run("--backend", backend, "--no-mailmap"), backend)
+	}
+
+	// The user mailmap applies to every repository and wins over the one of the repository
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	if err := os.Mkdir(filepath.Join(configHome, "gitbrag"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	mailmap := "# laptop\nJ. Doe <jd@example.com> <john.doe@example.com>\n"
+	if err := os.WriteFile(filepath.Join(configHome, "gitbrag", "mailmap"), []byte(mailmap), 0644); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "J. Doe <jd@example.com> 2", run())
+	assert.Equal(t, "Test User <test@example.com> 1, J. Doe <jd@example.com> 1", run("--no-mailmap"))
+	assert.Equal(t, "John Doe <john.doe@example.com> 2", run("--mailmap-file", ""))
+}
+
+func Test_Coauthors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	timeMock := mocks.NewMockTime(ctrl)
+	timeMock.EXPECT().Now().Return(defaultCurrentTime).AnyTimes()
+
+	// Test User paired with John Doe on a commit adding 5 lines
+	testDir := createGitRepo(t)
+	if err := os.WriteFile(filepath.Join(testDir, "pair.go"), []byte("package main\n\n// pair\n\nvar x = 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, testDir, "add", "pair.go")
+	runGit(t, testDir, "commit", "-m", "Pair on x\n\nCo-authored-by: John Doe <john.doe@example.com>")
+
+	run := func(args ...string) string {
+		out := mustRunGitbrag(t, timeMock, append([]string{testDir, "--by-author", "--format", "json"}, args...)...)
+		var report internal.JSONReport
+		if err := json.Unmarshal([]byte(out), &report); err != nil {
+			t.Fatal(err)
+		}
+		stats := []string{fmt.Sprintf("%d +%d -%d", report.Commits, report.Insertions, report.Deletions)}
+		for _, author := range report.Authors {
+			stats = append(stats, fmt.Sprintf("%s %d +%d -%d", author.Email, author.Commits, author.Insertions, author.Deletions))
+		}
+		return strings.Join(stats, ", ")
+	}
+
+	for _, backend := range []string{"exec", "native"} {
+		assert.Equal(t, "3 +16 -1, test@example.com 2 +16 -0, john.doe@example.com 1 +0 -1", run("--backend", backend), backend)
+		assert.Equal(t, "3 +16 -1, test@example.com 2 +16 -0, john.doe@example.com 2 +5 -1", run("--backend", backend, "--coauthors", "credit"), backend)
+		assert.Equal(t, "3 +16 -1, test@example.com 2 +14 -0, john.doe@example.com 2 +2 -1", run("--backend", backend, "--coauthors", "split"), backend)
+		assert.Equal(t, "1 +0 -1, john.doe@example.com 1 +0 -1", run("--backend", backend, "--author", "John"), backend)
+		assert.Equal(t, "2 +5 -1, john.doe@example.com 2 +5 -1", run("--backend", backend, "--author", "John", "--coauthors", "credit"), backend)
+		assert.Equal(t, "2 +2 -1, john.doe@example.com 2 +2 -1", run("--backend", backend, "--author", "John", "--coauthors", "split"), backend)
 	}
 
+	_, err := runGitbrag(t, timeMock, testDir, "--coauthors", "pair")
+	assert.EqualError(t, err, "invalid coauthors: pair, must be one of credit, split, ignore")
+}
+
+func Test_InvalidAuthor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	timeMock := mocks.NewMockTime(ctrl)
+
+	_, err := runGitbrag(t, timeMock, ".", "--exclude-author", "[bot")
+	assert.EqualError(t, err, `invalid author pattern "[bot": unterminated bracket expression`)
+}
+
+func Test_PNG_Output_1(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
@@ -131,15 +297,10 @@ func Test_PNG_Output_1(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	assert.Equal(t, expectedPNG, actualPNG)
+	assertPNGClose(t, expectedPNG, actualPNG)
 }
 
 func Test_PNG_Output_2(t *testing.T) {
-	// skip if not macOS
-	if runtime.GOOS != "darwin" {
-		t.Skip("skipping on non-macOS")
-	}
-
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
@@ -172,15 +333,10 @@ func Test_PNG_Output_2(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	assert.Equal(t, expectedPNG, actualPNG)
+	assertPNGClose(t, expectedPNG, actualPNG)
 }
 
 func Test_PNG_Output_Lang(t *testing.T) {
-	// skip if not macOS
-	if runtime.GOOS != "darwin" {
-		t.Skip("skipping on non-macOS")
-	}
-
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
@@ -213,7 +369,33 @@ func Test_PNG_Output_Lang(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	assert.Equal(t, expectedPNG, actualPNG)
+	assertPNGClose(t, expectedPNG, actualPNG)
+}
+
+func Test_SVG_Output(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	timeMock := mocks.NewMockTime(ctrl)
+	timeMock.EXPECT().Now().Return(defaultCurrentTime).AnyTimes()
+
+	testDir := createGitRepo(t)
+
+	out := mustRunGitbrag(t, timeMock, testDir, "--output", filepath.Join(testDir, "stats.SVG"), "-B", "000", "-C", "fff")
+	assert.Equal(t, "Statistics exported to test_gitbrag_Test_SVG_Output/stats.SVG\n", out)
+
+	actualSVG, err := os.ReadFile(filepath.Join(testDir, "stats.SVG"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	svg := string(actualSVG)
+	assert.True(t, strings.HasPrefix(svg, "<svg "))
+	assert.Contains(t, svg, `<rect width="800" height="800" fill="#000000"/>`)
+	assert.Contains(t, svg, `fill="#ffffff">Mar 10, 2025 - Mar 12, 2025</text>`)
+	assert.Contains(t, svg, `fill="#ffffff"> 2 files changed</text>`)
+	assert.Contains(t, svg, `>11 insertions(+)</text>`)
+	assert.Contains(t, svg, `> 1 deletions(-) </text>`)
+	assert.Contains(t, svg, `fill="#ffffff">2 commits on 2 days</text>`)
 }
 
 func Test_ExcludeFiles(t *testing.T) {
@@ -239,5 +421,1315 @@ func Test_ExcludeFiles(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	assert.Equal(t, "1 files changed\n3 insertions(+)\n1 deletions(-)\n", out.String())
+	assert.Equal(t, `2 commits
+2 active days
+1 files changed
+3 insertions(+)
+1 deletions(-)
+
+First commit: Mar 10, 2025 10:00:00
+Last commit:  Mar 12, 2025 09:30:00
+`, out.String())
+}
+
+func Test_IncludeExclude(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	timeMock := mocks.NewMockTime(ctrl)
+	timeMock.EXPECT().Now().Return(defaultCurrentTime).AnyTimes()
+
+	testDir := "test_gitbrag_" + t.Name()
+	t.Cleanup(func() {
+		os.RemoveAll(testDir)
+	})
+	initGitRepo(t, testDir)
+	runGit(t, testDir, "checkout", "-q", "main")
+	files := map[string]string{
+		".gitbragignore":       "docs/\n",
+		"docs/guide.md":        strings.Repeat("guide\n", 10),
+		"docs/api/users.md":    strings.Repeat("users\n", 5),
+		"src/app/app.go":       strings.Repeat("// app\n", 20),
+		"src/app/app_test.go":  strings.Repeat("// test\n", 30),
+		"scripts/release.bash": "echo release\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(testDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runGit(t, testDir, "add", ".")
+	runGit(t, testDir, "commit", "-q", "-m", "add files")
+
+	run := func(args ...string) string {
+		out := mustRunGitbrag(t, timeMock, append([]string{testDir, "--refs", "main", "--format", "json"}, args...)...)
+		var report struct {
+			FilesChanged int            `json:"filesChanged"`
+			Insertions   int            `json:"insertions"`
+			Languages    map[string]int `json:"languages"`
+		}
+		if err := json.Unmarshal([]byte(out), &report); err != nil {
+			t.Fatal(err)
+		}
+		return fmt.Sprintf("%d files, %d insertions, %v", report.FilesChanged, report.Insertions, report.Languages)
+	}
+
+	// .gitbragignore skips docs/
+	assert.Equal(t, "6 files, 63 insertions, map[Go:58 Shell:1 TypeScript:3]", run())
+	assert.Equal(t, "2 files, 50 insertions, map[Go:50]", run("--include", "src/**"))
+	assert.Equal(t, "2 files, 28 insertions, map[Go:28]", run("--include", "**/*.go", "--exclude", "**/*_test.go"))
+	// The command line patterns come after .gitbragignore
+	assert.Equal(t, "7 files, 68 insertions, map[Go:58 Markdown:5 Shell:1 TypeScript:3]", run("--exclude", "!docs/api/**"))
+}
+
+func Test_JSONFormat(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	timeMock := mocks.NewMockTime(ctrl)
+	timeMock.EXPECT().Now().Return(defaultCurrentTime).AnyTimes()
+
+	testDir := createGitRepo(t)
+
+	out := mustRunGitbrag(t, timeMock, testDir, "--format", "json")
+	assert.Equal(t, `{
+  "schemaVersion": 1,
+  "dateRange": {
+    "since": null,
+    "until": null,
+    "label": ""
+  },
+  "repositories": 1,
+  "refSelection": [
+    "branches"
+  ],
+  "refs": [
+    "refs/heads/feature",
+    "refs/heads/main"
+  ],
+  "filesChanged": 2,
+  "insertions": 11,
+  "deletions": 1,
+  "commits": 2,
+  "activeDays": 2,
+  "firstCommit": "2025-03-10T10:00:00Z",
+  "lastCommit": "2025-03-12T09:30:00Z",
+  "languages": {
+    "Go": 8,
+    "TypeScript": 4
+  }
+}
+`, out)
+}
+
+type csvRenderer struct{}
+
+func (r *csvRenderer) Render(w io.Writer, report *internal.Report, opts *internal.RunOptions) error {
+	_, err := fmt.Fprintf(w, "commits,insertions,deletions\n%d,%d,%d\n", report.Total.Commits, report.Total.Insertions, report.Total.Deletions)
+	return err
+}
+
+func Test_CustomRenderer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	timeMock := mocks.NewMockTime(ctrl)
+	timeMock.EXPECT().Now().Return(defaultCurrentTime).AnyTimes()
+
+	internal.RegisterRenderer("csv", func(opts *internal.RunOptions) (internal.Renderer, error) {
+		return &csvRenderer{}, nil
+	}, ".csv")
+
+	testDir := createGitRepo(t)
+
+	out := mustRunGitbrag(t, timeMock, testDir, "--format", "csv")
+	assert.Equal(t, "commits,insertions,deletions\n2,11,1\n", out)
+
+	// The format is picked by the extension of the output file
+	out = mustRunGitbrag(t, timeMock, testDir, "-O", filepath.Join(testDir, "stats.csv"))
+	assert.Equal(t, "Statistics exported to test_gitbrag_Test_CustomRenderer/stats.csv\n", out)
+
+	actual, err := os.ReadFile(filepath.Join(testDir, "stats.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "commits,insertions,deletions\n2,11,1\n", string(actual))
+}
+
+func Test_JSONFormat_DateRange(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	timeMock := mocks.NewMockTime(ctrl)
+	timeMock.EXPECT().Now().Return(defaultCurrentTime).AnyTimes()
+
+	testDir := createGitRepo(t)
+
+	out := mustRunGitbrag(t, timeMock, testDir, "--format", "json", "--since", "2024-01-01T00:00:00Z", "--author", "John Doe")
+	assert.Equal(t, `{
+  "schemaVersion": 1,
+  "dateRange": {
+    "since": "2024-01-01T00:00:00Z",
+    "until": null,
+    "label": "Since Jan 1, 2024"
+  },
+  "repositories": 1,
+  "refSelection": [
+    "branches"
+  ],
+  "refs": [
+    "refs/heads/feature",
+    "refs/heads/main"
+  ],
+  "filesChanged": 1,
+  "insertions": 0,
+  "deletions": 1,
+  "commits": 1,
+  "activeDays": 1,
+  "firstCommit": "2025-03-12T09:30:00Z",
+  "lastCommit": "2025-03-12T09:30:00Z",
+  "languages": {
+    "TypeScript": 1
+  }
+}
+`, out)
+}
+
+func Test_RelativeDates(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	timeMock := mocks.NewMockTime(ctrl)
+	timeMock.EXPECT().Now().Return(time.Date(2025, 3, 31, 12, 0, 0, 0, time.UTC)).AnyTimes()
+
+	testDir := createGitRepo(t)
+
+	run := func(args ...string) (string, error) {
+		out, err := runGitbrag(t, timeMock, append([]string{testDir, "--format", "json"}, args...)...)
+		if err != nil {
+			return "", err
+		}
+		var report internal.JSONReport
+		if err := json.Unmarshal([]byte(out), &report); err != nil {
+			t.Fatal(err)
+		}
+		return fmt.Sprintf("%s, %d commits", report.DateRange.Label, report.Commits), nil
+	}
+
+	tests := []struct {
+		args     []string
+		expected string
+		err      string
+	}{
+		{[]string{"--since", "1mo"}, "Since Feb 28, 2025 12:00:00, 2 commits", ""},
+		{[]string{"--since", "3w"}, "Since Mar 10, 2025 12:00:00, 1 commits", ""},
+		{[]string{"--since", "1y"}, "Since Mar 31, 2024 12:00:00, 2 commits", ""},
+		{[]string{"--until", "20d"}, "Until Mar 11, 2025 12:00:00, 1 commits", ""},
+		{[]string{"--since", "1mo", "--until", "2w"}, "Feb 28, 2025 12:00:00 - Mar 17, 2025 12:00:00, 2 commits", ""},
+		{[]string{"--since", "3w", "--until", "19d"}, "Mar 10, 2025 12:00:00 - Mar 12, 2025 12:00:00, 1 commits", ""},
+		{[]string{"--since", "3weeks"}, "", `invalid duration: 3weeks: unknown unit "weeks", expected y, mo, w, d, h or m`},
+		{[]string{"--until", "7"}, "", "invalid duration: 7: 7 has no unit, expected y, mo, w, d, h or m"},
+		{[]string{"--until", "2025-13-01"}, "", "invalid datetime: 2025-13-01"},
+		{[]string{"--since", "7d", "--until", "14d"}, "", "invalid period: until 2025-03-17T12:00:00Z is before since 2025-03-24T12:00:00Z"},
+	}
+	for _, backend := range []string{"exec", "native"} {
+		for _, tt := range tests {
+			out, err := run(append([]string{"--backend", backend}, tt.args...)...)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err, tt.args)
+			} else {
+				assert.NoError(t, err, tt.args)
+			}
+			assert.Equal(t, tt.expected, out, tt.args)
+		}
+	}
+}
+
+func Test_Range(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Monday, Mar 17, 2025
+	timeMock := mocks.NewMockTime(ctrl)
+	timeMock.EXPECT().Now().Return(time.Date(2025, 3, 17, 8, 0, 0, 0, time.UTC)).AnyTimes()
+
+	testDir := createGitRepo(t)
+
+	run := func(args ...string) (string, error) {
+		out, err := runGitbrag(t, timeMock, append([]string{testDir, "--format", "json"}, args...)...)
+		if err != nil {
+			return "", err
+		}
+		var report internal.JSONReport
+		if err := json.Unmarshal([]byte(out), &report); err != nil {
+			t.Fatal(err)
+		}
+		return fmt.Sprintf("%s, %s - %s, %d commits", report.DateRange.Label,
+			report.DateRange.Since.Format(time.RFC3339), report.DateRange.Until.Format(time.RFC3339), report.Commits), nil
+	}
+
+	tests := []struct {
+		args     []string
+		expected string
+		err      string
+	}{
+		{[]string{"--range", "last-week"}, "Week of Mar 10, 2025, 2025-03-10T00:00:00Z - 2025-03-16T23:59:59Z, 2 commits", ""},
+		{[]string{"--range", "last-week", "--week-start", "tuesday"}, "Week of Mar 4, 2025, 2025-03-04T00:00:00Z - 2025-03-10T23:59:59Z, 1 commits", ""},
+		{[]string{"--range", "this-week"}, "Week of Mar 17, 2025, 2025-03-17T00:00:00Z - 2025-03-23T23:59:59Z, 0 commits", ""},
+		{[]string{"--range", "this-month"}, "March 2025, 2025-03-01T00:00:00Z - 2025-03-31T23:59:59Z, 2 commits", ""},
+		{[]string{"--range", "2025-Q1"}, "Q1 2025, 2025-01-01T00:00:00Z - 2025-03-31T23:59:59Z, 2 commits", ""},
+		{[]string{"--range", "2024"}, "2024, 2024-01-01T00:00:00Z - 2024-12-31T23:59:59Z, 0 commits", ""},
+		{[]string{"--range", "2025-03-12"}, "Mar 12, 2025, 2025-03-12T00:00:00Z - 2025-03-12T23:59:59Z, 1 commits", ""},
+		{[]string{"--range", "last-week", "--since", "7d"}, "", "--range can't be used with --since or --until"},
+		{[]string{"--range", "last-week", "--week-start", "mon"}, "", `invalid week-start: unknown weekday "mon", expected monday, tuesday, wednesday, thursday, friday, saturday or sunday`},
+		{[]string{"--range", "2025-13"}, "", "invalid range: 2025-13: month 13 is not between 01 and 12"},
+	}
+	for _, tt := range tests {
+		out, err := run(tt.args...)
+		if tt.err != "" {
+			assert.EqualError(t, err, tt.err, tt.args)
+		} else {
+			assert.NoError(t, err, tt.args)
+		}
+		assert.Equal(t, tt.expected, out, tt.args)
+	}
+}
+
+func Test_TimeZone(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	timeMock := mocks.NewMockTime(ctrl)
+	timeMock.EXPECT().Now().Return(time.Date(2025, 3, 17, 8, 0, 0, 0, time.UTC)).AnyTimes()
+
+	// A commit late in the evening in New York, on Mar 13 in UTC
+	testDir := createGitRepo(t)
+	if err := os.WriteFile(filepath.Join(testDir, "late.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, testDir, "add", "late.go")
+	cmd := withCommitDate(exec.Command("git", "-c", "user.name=Test User", "-c", "user.email=test@example.com", "commit", "-m", "late"), "2025-03-12T23:30:00-04:00")
+	cmd.Dir = testDir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatal(err, string(out))
+	}
+
+	run := func(args ...string) string {
+		out := mustRunGitbrag(t, timeMock, append([]string{testDir, "--format", "json"}, args...)...)
+		var report internal.JSONReport
+		if err := json.Unmarshal([]byte(out), &report); err != nil {
+			t.Fatal(err)
+		}
+		return fmt.Sprintf("%s, %s, %d commits, %d days, %s", report.DateRange.Label, report.DateRange.Since.Format(time.RFC3339),
+			report.Commits, report.ActiveDays, report.LastCommit.Format(time.RFC3339))
+	}
+
+	for _, backend := range []string{"exec", "native"} {
+		assert.Equal(t, "Since Mar 12, 2025, 2025-03-12T00:00:00Z, 2 commits, 2 days, 2025-03-13T03:30:00Z",
+			run("--backend", backend, "--since", "2025-03-12", "--tz", "UTC"), backend)
+		assert.Equal(t, "Since Mar 12, 2025, 2025-03-12T00:00:00+02:00, 2 commits, 2 days, 2025-03-13T05:30:00+02:00",
+			run("--backend", backend, "--since", "2025-03-12", "--tz", "Europe/Bucharest"), backend)
+		assert.Equal(t, "Since Mar 12, 2025, 2025-03-12T00:00:00-04:00, 2 commits, 1 days, 2025-03-12T23:30:00-04:00",
+			run("--backend", backend, "--since", "2025-03-12", "--tz", "America/New_York"), backend)
+		assert.Equal(t, "Since Mar 12, 2025, 2025-03-12T00:00:00Z, 2 commits, 1 days, 2025-03-12T23:30:00-04:00",
+			run("--backend", backend, "--since", "2025-03-12", "--tz", "UTC", "--author-time"), backend)
+		// Absolute dates with a time zone keep it, the label is in --tz
+		assert.Equal(t, "Since Mar 12, 2025 02:00:00, 2025-03-12T02:00:00+02:00, 2 commits, 2 days, 2025-03-13T05:30:00+02:00",
+			run("--backend", backend, "--since", "2025-03-12T00:00:00Z", "--tz", "Europe/Bucharest"), backend)
+		assert.Equal(t, "Week of Mar 10, 2025, 2025-03-10T00:00:00+02:00, 3 commits, 3 days, 2025-03-13T05:30:00+02:00",
+			run("--backend", backend, "--range", "last-week", "--tz", "Europe/Bucharest"), backend)
+	}
+
+	_, err := runGitbrag(t, timeMock, testDir, "--tz", "Mars/Olympus_Mons")
+	assert.EqualError(t, err, `invalid tz: unknown time zone "Mars/Olympus_Mons", expected an IANA name such as Europe/Bucharest, UTC or local`)
+}
+
+func Test_DateField(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	timeMock := mocks.NewMockTime(ctrl)
+	timeMock.EXPECT().Now().Return(time.Date(2025, 3, 17, 8, 0, 0, 0, time.UTC)).AnyTimes()
+
+	// Written on Friday, rebased on Monday morning
+	testDir := createGitRepo(t)
+	if err := os.WriteFile(filepath.Join(testDir, "rebased.go"), []byte("package main\n\nfunc rebased() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, testDir, "add", "rebased.go")
+	cmd := exec.Command("git", "-c", "user.name=Test User", "-c", "user.email=test@example.com", "commit", "-m", "rebased")
+	cmd.Env = append(os.Environ(), "GIT_AUTHOR_DATE=2025-03-14T16:00:00Z", "GIT_COMMITTER_DATE=2025-03-17T07:00:00Z")
+	cmd.Dir = testDir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatal(err, string(out))
+	}
+
+	run := func(args ...string) string {
+		out := mustRunGitbrag(t, timeMock, append([]string{testDir, "--format", "json", "--tz", "UTC"}, args...)...)
+		var report internal.JSONReport
+		if err := json.Unmarshal([]byte(out), &report); err != nil {
+			t.Fatal(err)
+		}
+		summary := fmt.Sprintf("%s, %d commits, %d insertions, %d days", report.DateRange.Label, report.Commits, report.Insertions, report.ActiveDays)
+		if report.LastCommit != nil {
+			summary += ", " + report.LastCommit.Format(time.RFC3339)
+		}
+		return summary
+	}
+
+	for _, backend := range []string{"exec", "native"} {
+		assert.Equal(t, "Week of Mar 10, 2025, 2 commits, 11 insertions, 2 days, 2025-03-12T09:30:00Z",
+			run("--backend", backend, "--range", "last-week"), backend)
+		assert.Equal(t, "Week of Mar 10, 2025, 2 commits, 11 insertions, 2 days, 2025-03-12T09:30:00Z",
+			run("--backend", backend, "--range", "last-week", "--date-field", "committer"), backend)
+		assert.Equal(t, "Week of Mar 10, 2025, 3 commits, 14 insertions, 3 days, 2025-03-14T16:00:00Z",
+			run("--backend", backend, "--range", "last-week", "--date-field", "author"), backend)
+		assert.Equal(t, "Week of Mar 17, 2025, 1 commits, 3 insertions, 1 days, 2025-03-17T07:00:00Z",
+			run("--backend", backend, "--range", "this-week"), backend)
+		assert.Equal(t, "Week of Mar 17, 2025, 0 commits, 0 insertions, 0 days",
+			run("--backend", backend, "--range", "this-week", "--date-field", "author"), backend)
+		assert.Equal(t, "Since Mar 14, 2025 08:00:00, 1 commits, 3 insertions, 1 days, 2025-03-14T16:00:00Z",
+			run("--backend", backend, "--since", "3d", "--date-field", "author"), backend)
+	}
+
+	_, err := runGitbrag(t, timeMock, testDir, "--date-field", "written")
+	assert.EqualError(t, err, "invalid date-field: written, must be one of committer, author")
+}
+
+func Test_JSONFormat_NoRepositories(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	timeMock := mocks.NewMockTime(ctrl)
+	timeMock.EXPECT().Now().Return(defaultCurrentTime).AnyTimes()
+
+	testDir := t.TempDir()
+
+	out := mustRunGitbrag(t, timeMock, testDir, "--format", "json")
+	assert.Equal(t, `{
+  "schemaVersion": 1,
+  "dateRange": {
+    "since": null,
+    "until": null,
+    "label": ""
+  },
+  "repositories": 0,
+  "refSelection": [
+    "branches"
+  ],
+  "refs": [],
+  "filesChanged": 0,
+  "insertions": 0,
+  "deletions": 0,
+  "commits": 0,
+  "activeDays": 0,
+  "firstCommit": null,
+  "lastCommit": null,
+  "languages": {}
+}
+`, out)
+}
+
+func Test_JSONOutput_NoRepositories(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	timeMock := mocks.NewMockTime(ctrl)
+	timeMock.EXPECT().Now().Return(defaultCurrentTime).AnyTimes()
+
+	testDir := t.TempDir()
+	outputFile := filepath.Join(testDir, "stats.json")
+
+	out := mustRunGitbrag(t, timeMock, testDir, "-O", outputFile)
+	assert.Equal(t, "Statistics exported to "+outputFile+"\n", out)
+
+	// The document is written with zero totals
+	data, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report internal.JSONReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 0, report.Repositories)
+	assert.Equal(t, 0, report.Commits)
+	assert.Equal(t, 0, report.Insertions)
+}
+
+func Test_UnsupportedFormat(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	timeMock := mocks.NewMockTime(ctrl)
+	timeMock.EXPECT().Now().Return(defaultCurrentTime).AnyTimes()
+
+	testDir := createGitRepo(t)
+
+	_, err := runGitbrag(t, timeMock, testDir, "--format", "xml")
+	assert.EqualError(t, err, "unsupported format: xml")
+}
+
+func Test_FormatWithOutput(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	timeMock := mocks.NewMockTime(ctrl)
+	timeMock.EXPECT().Now().Return(defaultCurrentTime).AnyTimes()
+
+	testDir := createGitRepo(t)
+
+	run := func(args ...string) (string, error) {
+		return runGitbrag(t, timeMock, append([]string{testDir}, args...)...)
+	}
+
+	// An explicit format is used whatever the extension is
+	outputFile := filepath.Join(testDir, "stats.out")
+	_, err := run("--format", "text", "-O", outputFile)
+	assert.NoError(t, err)
+	data, err := os.ReadFile(outputFile)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "11 insertions(+)")
+
+	// Without it the extension chooses the format
+	outputFile = filepath.Join(testDir, "stats.txt")
+	_, err = run("-O", outputFile)
+	assert.NoError(t, err)
+	data, err = os.ReadFile(outputFile)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "11 insertions(+)")
+
+	// The extension of another format is a mistake
+	outputFile = filepath.Join(testDir, "stats.png")
+	_, err = run("--format", "json", "-O", outputFile)
+	assert.EqualError(t, err, "output file "+outputFile+" has the extension of the png format, not json")
+	assert.NoFileExists(t, outputFile)
+}
+
+func Test_ByRepo(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	timeMock := mocks.NewMockTime(ctrl)
+	timeMock.EXPECT().Now().Return(defaultCurrentTime).AnyTimes()
+
+	testDir := "test_gitbrag_" + t.Name()
+	t.Cleanup(func() {
+		os.RemoveAll(testDir)
+	})
+	createSmallGitRepo(t, filepath.Join(testDir, "small"))
+	initGitRepo(t, filepath.Join(testDir, "app"))
+
+	out := mustRunGitbrag(t, timeMock, testDir, "--by-repo")
+	assert.Equal(t, `REPOSITORY  COMMITS  FILES  INSERTIONS  DELETIONS
+app               2      2          11          1
+small             1      1           1          0
+
+ 3 commits
+ 3 active days
+ 3 files changed
+12 insertions(+)
+ 1 deletions(-)
+
+First commit: Mar 10, 2025 10:00:00
+Last commit:  Mar 12, 2025 09:30:00
+`, out)
+}
+
+func Test_Jobs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	timeMock := mocks.NewMockTime(ctrl)
+	timeMock.EXPECT().Now().Return(defaultCurrentTime).AnyTimes()
+
+	testDir := "test_gitbrag_" + t.Name()
+	t.Cleanup(func() {
+		os.RemoveAll(testDir)
+	})
+	for i := 1; i <= 6; i++ {
+		createSmallGitRepo(t, filepath.Join(testDir, fmt.Sprintf("small-%d", i)))
+	}
+	initGitRepo(t, filepath.Join(testDir, "app"))
+	// Repositories that git log fails on
+	for _, name := range []string{"broken-a", "broken-b"} {
+		createSmallGitRepo(t, filepath.Join(testDir, name))
+		runGit(t, filepath.Join(testDir, name), "config", "core.repositoryformatversion", "99")
+	}
+
+	absDir, err := filepath.Abs(testDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `Warning: could not get git stats for '` + filepath.Join(absDir, "broken-a") + `': failed to execute git command: exit status 128
+Warning: could not get git stats for '` + filepath.Join(absDir, "broken-b") + `': failed to execute git command: exit status 128
+REPOSITORY  COMMITS  FILES  INSERTIONS  DELETIONS
+app               2      2          11          1
+small-1           1      1           1          0
+small-2           1      1           1          0
+small-3           1      1           1          0
+small-4           1      1           1          0
+small-5           1      1           1          0
+small-6           1      1           1          0
+
+ 8 commits
+ 3 active days
+ 8 files changed
+17 insertions(+)
+ 1 deletions(-)
+
+First commit: Mar 10, 2025 10:00:00
+Last commit:  Mar 12, 2025 09:30:00
+`
+
+	// The output must not depend on the number of workers
+	for _, jobs := range []string{"1", "3", "16"} {
+		out := mustRunGitbrag(t, timeMock, testDir, "--by-repo", "--jobs", jobs)
+		assert.Equal(t, expected, out, "--jobs "+jobs)
+	}
+}
+
+func Test_BrokenRepoNextToNested(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	timeMock := mocks.NewMockTime(ctrl)
+	timeMock.EXPECT().Now().Return(defaultCurrentTime).AnyTimes()
+
+	testDir := "test_gitbrag_" + t.Name()
+	t.Cleanup(func() {
+		os.RemoveAll(testDir)
+	})
+	// The subdirectories next to a repository are only searched when it can't be read
+	createSmallGitRepo(t, filepath.Join(testDir, "team", "broken"))
+	runGit(t, filepath.Join(testDir, "team", "broken"), "config", "core.repositoryformatversion", "99")
+	createSmallGitRepo(t, filepath.Join(testDir, "team", "services", "api"))
+	createSmallGitRepo(t, filepath.Join(testDir, "other", "app"))
+	createSmallGitRepo(t, filepath.Join(testDir, "other", "libs", "hidden"))
+
+	absDir, err := filepath.Abs(testDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `Warning: could not get git stats for '` + filepath.Join(absDir, "team", "broken") + `': failed to execute git command: exit status 128
+REPOSITORY  COMMITS  FILES  INSERTIONS  DELETIONS
+api               1      1           1          0
+app               1      1           1          0
+
+2 commits
+1 active days
+2 files changed
+2 insertions(+)
+0 deletions(-)
+
+First commit: Mar 11, 2025 12:00:00
+Last commit:  Mar 11, 2025 12:00:00
+`
+
+	for _, jobs := range []string{"1", "4"} {
+		out := mustRunGitbrag(t, timeMock, testDir, "--by-repo", "--jobs", jobs)
+		assert.Equal(t, expected, out, "--jobs "+jobs)
+	}
+}
+
+func Test_Cache(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	timeMock := mocks.NewMockTime(ctrl)
+	timeMock.EXPECT().Now().Return(defaultCurrentTime).AnyTimes()
+
+	cacheHome := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", cacheHome)
+	cacheDir := filepath.Join(cacheHome, "gitbrag")
+
+	testDir := createGitRepo(t)
+
+	run := func(args ...string) string {
+		return mustRunGitbrag(t, timeMock, args...)
+	}
+
+	assert.Contains(t, run(testDir), "11 insertions(+)\n")
+	entries, err := os.ReadDir(cacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, entries, 1)
+
+	// Change the cached commits to tell whether git log runs again
+	entryPath := filepath.Join(cacheDir, entries[0].Name())
+	entry, err := os.ReadFile(entryPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, string(entry), `"Path":"main.go","Insertions":8`)
+	entry = bytes.Replace(entry, []byte(`"Path":"main.go","Insertions":8`), []byte(`"Path":"main.go","Insertions":80`), 1)
+	if err := os.WriteFile(entryPath, entry, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The cached commits are used while the branches haven't moved
+	assert.Contains(t, run(testDir), "83 insertions(+)\n")
+	// Excluded files are applied to the cached commits
+	assert.Contains(t, run(testDir, "--exclude-files", `\.ts$`), "80 insertions(+)\n")
+	// --no-cache always runs git log
+	assert.Contains(t, run(testDir, "--no-cache"), "11 insertions(+)\n")
+	// The author filters and the period are applied to the cached commits
+	assert.Contains(t, run(testDir, "--author", "John Doe"), "0 insertions(+)\n1 deletions(-)\n")
+	assert.Contains(t, run(testDir, "--since", "2025-03-11"), "0 insertions(+)\n1 deletions(-)\n")
+	assert.Contains(t, run(testDir, "--since", "7d"), "83 insertions(+)\n")
+	assert.Contains(t, run(testDir, "--since", "2025-03-11", "--date-field", "author"), "0 insertions(+)\n1 deletions(-)\n")
+	entries, err = os.ReadDir(cacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, entries, 1)
+
+	// A new commit moves the branch, only the new commit is read and the
+	// entry is replaced
+	if err := os.WriteFile(filepath.Join(testDir, "README.md"), []byte("# Test\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, testDir, "add", ".")
+	runGit(t, testDir, "commit", "-m", "add readme")
+	assert.Contains(t, run(testDir), "84 insertions(+)\n")
+	entries, err = os.ReadDir(cacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, entries, 1)
+
+	assert.Equal(t, "Cache cleared: "+cacheDir+"\n", run("cache", "clear"))
+	_, err = os.Stat(cacheDir)
+	assert.True(t, os.IsNotExist(err))
+
+	// The history is read again after clearing the cache
+	assert.Contains(t, run(testDir), "12 insertions(+)\n")
+}
+
+func Test_DirectoryNamedCache(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	timeMock := mocks.NewMockTime(ctrl)
+	timeMock.EXPECT().Now().Return(defaultCurrentTime).AnyTimes()
+
+	// gitbrag cache scans ./cache like any other directory
+	t.Chdir(t.TempDir())
+	createSmallGitRepo(t, "cache")
+
+	out := mustRunGitbrag(t, timeMock, "cache")
+	assert.Contains(t, out, "1 commits\n")
+	assert.Contains(t, out, "1 insertions(+)\n")
+
+	// With the flags of the scan
+	out = mustRunGitbrag(t, timeMock, "cache", "--no-cache", "--format", "json")
+	assert.Contains(t, out, `"insertions": 1`)
+}
+
+func Test_InvalidJobs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	timeMock := mocks.NewMockTime(ctrl)
+
+	_, err := runGitbrag(t, timeMock, ".", "--jobs", "-1")
+	assert.EqualError(t, err, "invalid jobs: -1, must be at least 1")
+}
+
+func Test_JobsHelp(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	timeMock := mocks.NewMockTime(ctrl)
+
+	out := mustRunGitbrag(t, timeMock, "--help")
+	// The help is the same on every machine
+	assert.Contains(t, out, "number of repositories scanned in parallel (default number of CPUs)\n")
+}
+
+func Test_NativeBackend(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	timeMock := mocks.NewMockTime(ctrl)
+	timeMock.EXPECT().Now().Return(defaultCurrentTime).AnyTimes()
+
+	testDir := createGitRepo(t)
+	runGit(t, testDir, "gc", "-q")
+	if err := os.WriteFile(filepath.Join(testDir, "README.md"), []byte("# Test\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, testDir, "add", ".")
+	runGit(t, testDir, "commit", "-m", "add readme")
+
+	run := func(args ...string) string {
+		return mustRunGitbrag(t, timeMock, append([]string{testDir, "--by-author", "--no-cache"}, args...)...)
+	}
+
+	// Packed and loose objects give the same statistics as git log
+	expected := `#  AUTHOR                           COMMITS  FILES  INSERTIONS  DELETIONS
+1  Test User <test@example.com>           2      3          12          0
+2  John Doe <john.doe@example.com>        1      1           0          1
+
+ 3 commits
+ 3 active days
+ 3 files changed
+12 insertions(+)
+ 1 deletions(-)
+
+First commit: Mar 10, 2025 10:00:00
+Last commit:  Mar 12, 2025 09:30:00
+`
+	assert.Equal(t, expected, run())
+	assert.Equal(t, expected, run("--backend", "native"))
+}
+
+func Test_InvalidBackend(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	timeMock := mocks.NewMockTime(ctrl)
+
+	_, err := runGitbrag(t, timeMock, ".", "--backend", "libgit2")
+	assert.EqualError(t, err, "unsupported backend: libgit2")
+}
+
+func Test_Refs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	timeMock := mocks.NewMockTime(ctrl)
+	timeMock.EXPECT().Now().Return(defaultCurrentTime).AnyTimes()
+
+	// After a fresh clone the feature branch only exists as origin/feature
+	testDir := "test_gitbrag_" + t.Name()
+	t.Cleanup(func() {
+		os.RemoveAll(testDir)
+	})
+	initGitRepo(t, filepath.Join(testDir, "origin"))
+	runGit(t, testDir, "clone", "-q", "origin", "clone")
+	cloneDir := filepath.Join(testDir, "clone")
+
+	run := func(args ...string) string {
+		return mustRunGitbrag(t, timeMock, append([]string{cloneDir, "--no-cache"}, args...)...)
+	}
+
+	// Local branches by default
+	assert.Contains(t, run(), " 1 commits\n")
+	assert.Contains(t, run("--refs", "head"), " 1 commits\n")
+	assert.Contains(t, run("--refs", "remotes"), " 2 commits\n")
+	assert.Contains(t, run("--refs", "origin/feat*"), " 2 commits\n")
+	assert.Contains(t, run("--refs", "branches,refs/remotes/origin/*"), " 2 commits\n")
+	assert.Contains(t, run("--refs", "release/*"), "0 commits\n")
+
+	// The refs that were read are listed in the JSON output
+	var report internal.JSONReport
+	if err := json.Unmarshal([]byte(run("--refs", "head,remotes", "--format", "json")), &report); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"head", "remotes"}, report.RefSelection)
+	assert.Equal(t, []string{"HEAD", "refs/remotes/origin/HEAD", "refs/remotes/origin/feature", "refs/remotes/origin/main"}, report.Refs)
+}
+
+func Test_InvalidRefs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	timeMock := mocks.NewMockTime(ctrl)
+
+	_, err := runGitbrag(t, timeMock, ".", "--refs", "release/[0-9")
+	assert.EqualError(t, err, "invalid refs: unterminated bracket in ref pattern: release/[0-9")
+}
+
+func Test_Merges(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	timeMock := mocks.NewMockTime(ctrl)
+	timeMock.EXPECT().Now().Return(defaultCurrentTime).AnyTimes()
+
+	// The feature branch of John Doe is merged on main by Test User
+	testDir := "test_gitbrag_" + t.Name()
+	t.Cleanup(func() {
+		os.RemoveAll(testDir)
+	})
+	initGitRepo(t, testDir)
+	runGit(t, testDir, "checkout", "-q", "main")
+	runGit(t, testDir, "merge", "-q", "--no-ff", "-m", "merge feature", "feature")
+
+	run := func(args ...string) string {
+		return mustRunGitbrag(t, timeMock, append([]string{testDir, "--refs", "main", "--by-author"}, args...)...)
+	}
+
+	// By default the merge is counted as a commit without its changes
+	defaultOutput := `#  AUTHOR                           COMMITS  FILES  INSERTIONS  DELETIONS
+1  Test User <test@example.com>           2      2          11          0
+2  John Doe <john.doe@example.com>        1      1           0          1
+
+ 3 commits
+ 3 active days
+ 2 files changed
+11 insertions(+)
+ 1 deletions(-)
+
+First commit: Mar 10, 2025 10:00:00
+Last commit:  Mar 12, 2025 09:30:00
+`
+	// The merge is also credited with the deletion of the feature branch
+	includeOutput := `#  AUTHOR                           COMMITS  FILES  INSERTIONS  DELETIONS
+1  Test User <test@example.com>           2      2          11          1
+2  John Doe <john.doe@example.com>        1      1           0          1
+
+ 3 commits
+ 3 active days
+ 2 files changed
+11 insertions(+)
+ 2 deletions(-)
+
+First commit: Mar 10, 2025 10:00:00
+Last commit:  Mar 12, 2025 09:30:00
+`
+	excludeOutput := `#  AUTHOR                           COMMITS  FILES  INSERTIONS  DELETIONS
+1  Test User <test@example.com>           1      2          11          0
+2  John Doe <john.doe@example.com>        1      1           0          1
+
+ 2 commits
+ 2 active days
+ 2 files changed
+11 insertions(+)
+ 1 deletions(-)
+
+First commit: Mar 10, 2025 10:00:00
+Last commit:  Mar 12, 2025 09:30:00
+`
+	// Only what landed on main, the feature commit is part of the merge
+	firstParentOutput := `#  AUTHOR                        COMMITS  FILES  INSERTIONS  DELETIONS
+1  Test User <test@example.com>        2      2          11          1
+
+ 2 commits
+ 2 active days
+ 2 files changed
+11 insertions(+)
+ 1 deletions(-)
+
+First commit: Mar 10, 2025 10:00:00
+Last commit:  Mar 11, 2025 12:00:00
+`
+	for _, backend := range []string{"exec", "native"} {
+		assert.Equal(t, defaultOutput, run("--backend", backend), backend)
+		assert.Equal(t, includeOutput, run("--backend", backend, "--merges", "include"), backend)
+		assert.Equal(t, excludeOutput, run("--backend", backend, "--merges", "exclude"), backend)
+		assert.Equal(t, firstParentOutput, run("--backend", backend, "--merges", "first-parent"), backend)
+	}
+}
+
+func Test_InvalidMerges(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	timeMock := mocks.NewMockTime(ctrl)
+
+	_, err := runGitbrag(t, timeMock, ".", "--merges", "all")
+	assert.EqualError(t, err, "invalid merges: all, must be one of include, exclude, first-parent")
+}
+
+func Test_Renames(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	timeMock := mocks.NewMockTime(ctrl)
+	timeMock.EXPECT().Now().Return(defaultCurrentTime).AnyTimes()
+
+	// main.go is moved as is, main.ts is moved with a new line
+	testDir := "test_gitbrag_" + t.Name()
+	t.Cleanup(func() {
+		os.RemoveAll(testDir)
+	})
+	initGitRepo(t, testDir)
+	runGit(t, testDir, "checkout", "-q", "main")
+	runGit(t, testDir, "mv", "main.go", "app.go")
+	if err := os.Mkdir(filepath.Join(testDir, "web"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, testDir, "mv", "main.ts", "web/main.ts")
+	f, err := os.OpenFile(filepath.Join(testDir, "web", "main.ts"), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString("console.log(\"moved\");\n"); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	runGit(t, testDir, "add", ".")
+	runGit(t, testDir, "commit", "-q", "-m", "move files")
+
+	run := func(args ...string) string {
+		return mustRunGitbrag(t, timeMock, append([]string{testDir, "--refs", "main"}, args...)...)
+	}
+
+	for _, backend := range []string{"exec", "native"} {
+		// The moved files count once and only the new line is inserted
+		assert.Equal(t, ` 2 commits
+ 2 active days
+ 2 files changed
+12 insertions(+)
+ 0 deletions(-)
+
+First commit: Mar 10, 2025 10:00:00
+Last commit:  Mar 11, 2025 12:00:00
+`, run("--backend", backend), backend)
+
+		assert.Equal(t, ` 2 commits
+ 2 active days
+ 2 files changed
+12 insertions(+)
+ 0 deletions(-)
+11 moved lines
+
+First commit: Mar 10, 2025 10:00:00
+Last commit:  Mar 11, 2025 12:00:00
+`, run("--backend", backend, "--moved"), backend)
+	}
+}
+
+func Test_BinaryFiles(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	timeMock := mocks.NewMockTime(ctrl)
+	timeMock.EXPECT().Now().Return(defaultCurrentTime).AnyTimes()
+
+	// A binary asset is added, then grows
+	testDir := "test_gitbrag_" + t.Name()
+	t.Cleanup(func() {
+		os.RemoveAll(testDir)
+	})
+	initGitRepo(t, testDir)
+	runGit(t, testDir, "checkout", "-q", "main")
+	asset := filepath.Join(testDir, "sprite.bin")
+	if err := os.WriteFile(asset, bytes.Repeat([]byte{0, 1, 2, 3}, 512), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, testDir, "add", ".")
+	runGit(t, testDir, "commit", "-q", "-m", "add sprite")
+	if err := os.WriteFile(asset, bytes.Repeat([]byte{0, 1, 2, 3}, 1024), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, testDir, "commit", "-q", "-a", "-m", "grow sprite")
+
+	run := func(args ...string) string {
+		return mustRunGitbrag(t, timeMock, append([]string{testDir, "--refs", "main"}, args...)...)
+	}
+
+	for _, backend := range []string{"exec", "native"} {
+		assert.Equal(t, ` 3 commits
+ 2 active days
+ 3 files changed
+11 insertions(+)
+ 0 deletions(-)
+ 1 binary file
+
+First commit: Mar 10, 2025 10:00:00
+Last commit:  Mar 11, 2025 12:00:00
+`, run("--backend", backend), backend)
+
+		assert.Equal(t, ` 3 commits
+ 2 active days
+ 3 files changed
+11 insertions(+)
+ 0 deletions(-)
+ 1 binary file (+4.0 KiB)
+
+First commit: Mar 10, 2025 10:00:00
+Last commit:  Mar 11, 2025 12:00:00
+`, run("--backend", backend, "--binary-bytes"), backend)
+
+		assert.Contains(t, run("--backend", backend, "--binary-bytes", "--format", "json"), `"binaryBytes": 4096`, backend)
+	}
+}
+
+func Test_GeneratedFiles(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	timeMock := mocks.NewMockTime(ctrl)
+	timeMock.EXPECT().Now().Return(defaultCurrentTime).AnyTimes()
+
+	// Protobuf code and a lockfile are generated, the assets are vendored and
+	// the snapshots are generated in .gitattributes
+	testDir := "test_gitbrag_" + t.Name()
+	t.Cleanup(func() {
+		os.RemoveAll(testDir)
+	})
+	initGitRepo(t, testDir)
+	runGit(t, testDir, "checkout", "-q", "main")
+	files := map[string]string{
+		".gitattributes":      "assets/** linguist-vendored\n*.snap linguist-generated\n",
+		"api/user.pb.go":      strings.Repeat("// generated\n", 100),
+		"package-lock.json":   strings.Repeat("{}\n", 50),
+		"assets/lib.js":       strings.Repeat("lib();\n", 20),
+		"web/__ui.snap":       strings.Repeat("snapshot\n", 10),
+		"web/package-lock.go": "package web\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(testDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runGit(t, testDir, "add", ".")
+	runGit(t, testDir, "commit", "-q", "-m", "add generated files")
+
+	run := func(args ...string) string {
+		return mustRunGitbrag(t, timeMock, append([]string{testDir, "--refs", "main"}, args...)...)
+	}
+
+	for _, backend := range []string{"exec", "native"} {
+		// Only main.go, main.ts, .gitattributes and web/package-lock.go are counted
+		assert.Equal(t, ` 2 commits
+ 2 active days
+ 4 files changed
+14 insertions(+)
+ 0 deletions(-)
+
+First commit: Mar 10, 2025 10:00:00
+Last commit:  Mar 11, 2025 12:00:00
+`, run("--backend", backend), backend)
+
+		assert.Equal(t, `  2 commits
+  2 active days
+  8 files changed
+194 insertions(+)
+  0 deletions(-)
+
+First commit: Mar 10, 2025 10:00:00
+Last commit:  Mar 11, 2025 12:00:00
+`, run("--backend", backend, "--include-generated"), backend)
+	}
+}
+
+func Test_Dedupe(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	timeMock := mocks.NewMockTime(ctrl)
+	timeMock.EXPECT().Now().Return(defaultCurrentTime).AnyTimes()
+
+	// The feature commit is cherry-picked on a release branch, and the
+	// repository is cloned next to it
+	testDir := "test_gitbrag_" + t.Name()
+	t.Cleanup(func() {
+		os.RemoveAll(testDir)
+	})
+	appDir := filepath.Join(testDir, "app")
+	initGitRepo(t, appDir)
+	runGit(t, appDir, "checkout", "-q", "-b", "release", "main")
+	runGit(t, appDir, "cherry-pick", "feature")
+	runGit(t, appDir, "checkout", "-q", "main")
+	runGit(t, testDir, "clone", "-q", "app", "mirror")
+
+	run := func(args ...string) string {
+		return mustRunGitbrag(t, timeMock, append([]string{testDir, "--refs", "all", "--by-repo"}, args...)...)
+	}
+
+	assert.Equal(t, `REPOSITORY  COMMITS  FILES  INSERTIONS  DELETIONS
+app               3      2          11          2
+mirror            3      2          11          2
+
+ 6 commits
+ 3 active days
+ 4 files changed
+22 insertions(+)
+ 4 deletions(-)
+
+First commit: Mar 10, 2025 10:00:00
+Last commit:  Mar 12, 2025 09:30:00
+`, run())
+
+	// The cherry-pick was committed first and is kept, the feature commit and
+	// the copies of the mirror are dropped
+	expected := `REPOSITORY  COMMITS  FILES  INSERTIONS  DELETIONS
+app               2      2          11          1
+mirror            0      0           0          0
+
+ 2 commits
+ 2 active days
+ 2 files changed
+11 insertions(+)
+ 1 deletions(-)
+
+First commit: Mar 10, 2025 10:00:00
+Last commit:  Mar 11, 2025 12:00:00
+`
+	assert.Equal(t, expected, run("--dedupe"))
+	assert.Equal(t, expected, run("--dedupe", "--backend", "native"))
+
+	// Merges have no patch, the copy of the mirror is dropped
+	runGit(t, appDir, "merge", "-q", "--no-ff", "-m", "Merge release", "release")
+	os.RemoveAll(filepath.Join(testDir, "mirror"))
+	runGit(t, testDir, "clone", "-q", "app", "mirror")
+	expected = `REPOSITORY  COMMITS  FILES  INSERTIONS  DELETIONS
+app               3      2          11          2
+mirror            0      0           0          0
+
+ 3 commits
+ 2 active days
+ 2 files changed
+11 insertions(+)
+ 2 deletions(-)
+
+First commit: Mar 10, 2025 10:00:00
+Last commit:  Mar 11, 2025 12:00:00
+`
+	assert.Equal(t, expected, run("--dedupe", "--merges", "include"))
+	assert.Equal(t, expected, run("--dedupe", "--merges", "include", "--backend", "native"))
+}
+
+func Test_ByRepo_JSON(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	timeMock := mocks.NewMockTime(ctrl)
+	timeMock.EXPECT().Now().Return(defaultCurrentTime).AnyTimes()
+
+	testDir := "test_gitbrag_" + t.Name()
+	t.Cleanup(func() {
+		os.RemoveAll(testDir)
+	})
+	createSmallGitRepo(t, filepath.Join(testDir, "small"))
+	initGitRepo(t, filepath.Join(testDir, "app"))
+
+	absDir, err := filepath.Abs(testDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := mustRunGitbrag(t, timeMock, testDir, "--by-repo", "--format", "json")
+
+	var report internal.JSONReport
+	if err := json.Unmarshal([]byte(out), &report); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, report.Repositories)
+	assert.Equal(t, []string{"refs/heads/feature", "refs/heads/main"}, report.Refs)
+	assert.Equal(t, []internal.JSONRepo{
+		{
+			Path: filepath.Join(absDir, "app"),
+			Name: "app",
+			Refs: []string{"refs/heads/feature", "refs/heads/main"},
+			JSONStats: internal.JSONStats{
+				FilesChanged: 2,
+				Insertions:   11,
+				Deletions:    1,
+				Commits:      2,
+				ActiveDays:   2,
+				FirstCommit:  timePtr(time.Date(2025, 3, 10, 10, 0, 0, 0, time.UTC)),
+				LastCommit:   timePtr(time.Date(2025, 3, 12, 9, 30, 0, 0, time.UTC)),
+				Languages:    map[string]int{"Go": 8, "TypeScript": 4},
+			},
+		},
+		{
+			Path: filepath.Join(absDir, "small"),
+			Name: "small",
+			Refs: []string{"refs/heads/main"},
+			JSONStats: internal.JSONStats{
+				FilesChanged: 1,
+				Insertions:   1,
+				Deletions:    0,
+				Commits:      1,
+				ActiveDays:   1,
+				FirstCommit:  timePtr(time.Date(2025, 3, 11, 12, 0, 0, 0, time.UTC)),
+				LastCommit:   timePtr(time.Date(2025, 3, 11, 12, 0, 0, 0, time.UTC)),
+				Languages:    map[string]int{"Markdown": 1},
+			},
+		},
+	}, report.Repos)
+}
+
+func Test_ByAuthor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	timeMock := mocks.NewMockTime(ctrl)
+	timeMock.EXPECT().Now().Return(defaultCurrentTime).AnyTimes()
+
+	testDir := createGitRepo(t)
+
+	out := mustRunGitbrag(t, timeMock, testDir, "--by-author")
+	assert.Equal(t, `#  AUTHOR                           COMMITS  FILES  INSERTIONS  DELETIONS
+1  Test User <test@example.com>           1      2          11          0
+2  John Doe <john.doe@example.com>        1      1           0          1
+
+ 2 commits
+ 2 active days
+ 2 files changed
+11 insertions(+)
+ 1 deletions(-)
+
+First commit: Mar 10, 2025 10:00:00
+Last commit:  Mar 12, 2025 09:30:00
+`, out)
+}
+
+func Test_ByAuthor_JSON(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	timeMock := mocks.NewMockTime(ctrl)
+	timeMock.EXPECT().Now().Return(defaultCurrentTime).AnyTimes()
+
+	testDir := "test_gitbrag_" + t.Name()
+	t.Cleanup(func() {
+		os.RemoveAll(testDir)
+	})
+	initGitRepo(t, filepath.Join(testDir, "app"))
+	createSmallGitRepo(t, filepath.Join(testDir, "small"))
+
+	out := mustRunGitbrag(t, timeMock, testDir, "--by-author", "--format", "json")
+
+	var report internal.JSONReport
+	if err := json.Unmarshal([]byte(out), &report); err != nil {
+		t.Fatal(err)
+	}
+	// Test User's commits are merged across both repositories
+	assert.Equal(t, []internal.JSONAuthor{
+		{
+			Rank:  1,
+			Name:  "Test User",
+			Email: "test@example.com",
+			JSONStats: internal.JSONStats{
+				FilesChanged: 3,
+				Insertions:   12,
+				Deletions:    0,
+				Commits:      2,
+				ActiveDays:   2,
+				FirstCommit:  timePtr(time.Date(2025, 3, 10, 10, 0, 0, 0, time.UTC)),
+				LastCommit:   timePtr(time.Date(2025, 3, 11, 12, 0, 0, 0, time.UTC)),
+				Languages:    map[string]int{"Go": 8, "TypeScript": 3, "Markdown": 1},
+			},
+		},
+		{
+			Rank:  2,
+			Name:  "John Doe",
+			Email: "john.doe@example.com",
+			JSONStats: internal.JSONStats{
+				FilesChanged: 1,
+				Insertions:   0,
+				Deletions:    1,
+				Commits:      1,
+				ActiveDays:   1,
+				FirstCommit:  timePtr(time.Date(2025, 3, 12, 9, 30, 0, 0, time.UTC)),
+				LastCommit:   timePtr(time.Date(2025, 3, 12, 9, 30, 0, 0, time.UTC)),
+				Languages:    map[string]int{"TypeScript": 1},
+			},
+		},
+	}, report.Authors)
 }
@@ -1,23 +1,67 @@
 package gitbrag
 
 import (
+	"bytes"
+	"image/png"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"testing"
 	"time"
+
+	"github.com/radulucut/gitbrag/internal"
+	"github.com/radulucut/gitbrag/internal/utils"
+	"github.com/stretchr/testify/assert"
 )
 
 var (
 	defaultCurrentTime = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
 )
 
+func TestMain(m *testing.M) {
+	// Commit dates are printed in local time, use UTC so outputs don't depend on the machine
+	time.Local = time.UTC
+
+	// Keep the commit cache of the tests out of the user cache directory
+	cacheDir, err := os.MkdirTemp("", "gitbrag-cache")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("XDG_CACHE_HOME", cacheDir)
+	// and the user mailmap out of the tests
+	configDir, err := os.MkdirTemp("", "gitbrag-config")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("XDG_CONFIG_HOME", configDir)
+	code := m.Run()
+	os.RemoveAll(cacheDir)
+	os.RemoveAll(configDir)
+	os.Exit(code)
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+// withCommitDate sets both the author and committer dates of a git command
+func withCommitDate(cmd *exec.Cmd, date string) *exec.Cmd {
+	cmd.Env = append(os.Environ(), "GIT_AUTHOR_DATE="+date, "GIT_COMMITTER_DATE="+date)
+	return cmd
+}
+
 func createGitRepo(t *testing.T) string {
 	testDir := "test_gitbrag_" + t.Name()
 	t.Cleanup(func() {
 		os.RemoveAll(testDir)
 	})
 
+	initGitRepo(t, testDir)
+	return testDir
+}
+
+// initGitRepo creates the default test repository in the given directory
+func initGitRepo(t *testing.T, testDir string) {
 	err := os.MkdirAll(testDir, 0755)
 	if err != nil {
 		t.Fatal(err)
@@ -54,7 +98,7 @@ console.log("Hello, World!");
 		t.Fatal(err)
 	}
 
-	cmd = exec.Command("git", "-c", "user.name=Test User", "-c", "user.email=test@example.com", "commit", "-m", "initial commit")
+	cmd = withCommitDate(exec.Command("git", "-c", "user.name=Test User", "-c", "user.email=test@example.com", "commit", "-m", "initial commit"), "2025-03-10T10:00:00Z")
 	cmd.Dir = testDir
 	if out, err := cmd.CombinedOutput(); err != nil {
 		t.Log(string(out))
@@ -81,7 +125,7 @@ console.log("Hello, World!");
 		t.Fatal(err)
 	}
 
-	cmd = exec.Command("git", "-c", "user.name=Test User", "-c", "user.email=test@example.com", "commit", "-m", "second commit", "--author", "John Doe <john.doe@example.com>")
+	cmd = withCommitDate(exec.Command("git", "-c", "user.name=Test User", "-c", "user.email=test@example.com", "commit", "-m", "second commit", "--author", "John Doe <john.doe@example.com>"), "2025-03-12T09:30:00Z")
 	cmd.Dir = testDir
 	if out, err := cmd.CombinedOutput(); err != nil {
 		t.Log(string(out))
@@ -94,5 +138,96 @@ console.log("Hello, World!");
 		t.Log(string(out))
 		t.Fatal(err)
 	}
-	return testDir
+}
+
+// runGit runs a git command in the given directory with a fixed committer identity and date
+func runGit(t *testing.T, dir string, args ...string) {
+	args = append([]string{"-c", "user.name=Test User", "-c", "user.email=test@example.com"}, args...)
+	cmd := withCommitDate(exec.Command("git", args...), "2025-03-11T12:00:00Z")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Log(string(out))
+		t.Fatal(err)
+	}
+}
+
+// createSmallGitRepo creates a repository with a single commit adding one line
+func createSmallGitRepo(t *testing.T, testDir string) {
+	if err := os.MkdirAll(testDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, testDir, "init", "-b", "main")
+	if err := os.WriteFile(filepath.Join(testDir, "README.md"), []byte("# Small\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, testDir, "add", ".")
+	runGit(t, testDir, "commit", "-m", "initial commit")
+}
+
+// runGitbrag runs the command with the arguments, without the program name,
+// and returns what it printed
+func runGitbrag(t *testing.T, time utils.Time, args ...string) (string, error) {
+	t.Helper()
+	out := new(bytes.Buffer)
+	printer := internal.NewPrinter(nil, out, out)
+	core := internal.NewCore(time, printer)
+	root, err := NewRoot("0.1.0", time, printer, core)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Args = append([]string{"gitbrag"}, args...)
+	err = root.Cmd.Execute()
+	return out.String(), err
+}
+
+// mustRunGitbrag runs the command like runGitbrag and fails the test on errors
+func mustRunGitbrag(t *testing.T, time utils.Time, args ...string) string {
+	t.Helper()
+	out, err := runGitbrag(t, time, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+// pngTolerance is the largest difference of a color channel between the
+// expected and the actual images. Text is antialiased with floating point
+// math, which rounds a few edge pixels differently on CPUs that fuse
+// multiplications and additions, e.g. on Apple silicon.
+const pngTolerance = 2
+
+// assertPNGClose decodes both images and checks that they have the same size
+// and pixels, within pngTolerance
+func assertPNGClose(t *testing.T, expected, actual []byte) {
+	t.Helper()
+	expectedImg, err := png.Decode(bytes.NewReader(expected))
+	if err != nil {
+		t.Fatalf("could not decode the expected image: %v", err)
+	}
+	actualImg, err := png.Decode(bytes.NewReader(actual))
+	if err != nil {
+		t.Fatalf("could not decode the actual image: %v", err)
+	}
+	if !assert.Equal(t, expectedImg.Bounds(), actualImg.Bounds()) {
+		return
+	}
+
+	diff := func(a, b uint32) uint32 {
+		a, b = a>>8, b>>8
+		if a > b {
+			return a - b
+		}
+		return b - a
+	}
+	bounds := expectedImg.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r1, g1, b1, a1 := expectedImg.At(x, y).RGBA()
+			r2, g2, b2, a2 := actualImg.At(x, y).RGBA()
+			if max(diff(r1, r2), diff(g1, g2), diff(b1, b2), diff(a1, a2)) > pngTolerance {
+				t.Errorf("pixel (%d, %d) is %v, want %v", x, y, actualImg.At(x, y), expectedImg.At(x, y))
+				return
+			}
+		}
+	}
 }
@@ -0,0 +1,183 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Attributes of GitHub Linguist that mark files as not written by hand.
+// Files with any of them set are not counted.
+const (
+	attrGenerated     = "linguist-generated"
+	attrVendored      = "linguist-vendored"
+	attrDocumentation = "linguist-documentation"
+)
+
+// generatedPatterns are files that are generated unless linguist-generated is
+// unset for them: protobuf and mock code, minified assets and lockfiles
+var generatedPatterns = parsePathPatterns([]string{
+	"*.pb.go", "*.pb.gw.go", "*_pb2.py", "*_pb2_grpc.py", "*.pb.cc", "*.pb.h",
+	"*_mock.go", "mock_*.go", "zz_generated.*.go",
+	"*.min.js", "*.min.css", "*.js.map", "*.css.map",
+	"package-lock.json", "npm-shrinkwrap.json", "yarn.lock", "pnpm-lock.yaml",
+	"go.sum", "Cargo.lock", "composer.lock", "Gemfile.lock", "poetry.lock", "Pipfile.lock",
+})
+
+func parsePathPatterns(patterns []string) []pathPattern {
+	result := make([]pathPattern, 0, len(patterns))
+	for _, pattern := range patterns {
+		result = append(result, parsePathPattern(pattern, ""))
+	}
+	return result
+}
+
+// attributeRule is a line of a .gitattributes file
+type attributeRule struct {
+	pattern pathPattern
+	values  map[string]string // "true" when set, "false" when unset, empty when unspecified with !
+}
+
+// gitAttributes reads the attributes of the files of a repository from the
+// .gitattributes files of its working tree and $GIT_DIR/info/attributes
+type gitAttributes struct {
+	dir   string
+	info  []attributeRule
+	files map[string][]attributeRule // rules of the .gitattributes of each directory, read when first needed
+}
+
+func newGitAttributes(dir string) *gitAttributes {
+	a := &gitAttributes{dir: dir, files: make(map[string][]attributeRule)}
+	if data, err := os.ReadFile(filepath.Join(dir, ".git", "info", "attributes")); err == nil {
+		a.info = parseAttributes(data, "")
+	}
+	return a
+}
+
+// parseAttributes parses a .gitattributes file of the base directory. Macros
+// and negative patterns, which git ignores, are skipped.
+func parseAttributes(data []byte, base string) []attributeRule {
+	var rules []attributeRule
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || strings.HasPrefix(line, "[attr]") {
+			continue
+		}
+		var pattern string
+		if line[0] == '"' {
+			// Quoted patterns may contain spaces
+			end := 1
+			for end < len(line) && line[end] != '"' {
+				if line[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(line) {
+				continue
+			}
+			pattern, line = unquotePath(line[:end+1]), line[end+1:]
+		} else if i := strings.IndexAny(line, " \t"); i >= 0 {
+			pattern, line = line[:i], line[i+1:]
+		} else {
+			pattern, line = line, ""
+		}
+		if strings.HasPrefix(pattern, "!") {
+			continue
+		}
+
+		rule := attributeRule{pattern: parsePathPattern(pattern, base), values: make(map[string]string)}
+		for _, attr := range strings.Fields(line) {
+			switch {
+			case attr[0] == '-':
+				rule.values[attr[1:]] = "false"
+			case attr[0] == '!':
+				rule.values[attr[1:]] = ""
+			default:
+				name, value, ok := strings.Cut(attr, "=")
+				if !ok {
+					value = "true"
+				}
+				rule.values[name] = value
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// value returns the value of an attribute for a file and whether it is
+// specified. $GIT_DIR/info/attributes comes first, then the .gitattributes
+// of the closest directory, and the last matching line of a file wins.
+func (a *gitAttributes) value(file, name string) (string, bool) {
+	if value, ok := matchAttribute(a.info, file, name); ok {
+		return value, value != ""
+	}
+	dir := path.Dir(file)
+	for {
+		if dir == "." {
+			dir = ""
+		}
+		if value, ok := matchAttribute(a.rules(dir), file, name); ok {
+			return value, value != ""
+		}
+		if dir == "" {
+			return "", false
+		}
+		dir = path.Dir(dir)
+	}
+}
+
+// rules returns the rules of the .gitattributes of a directory
+func (a *gitAttributes) rules(dir string) []attributeRule {
+	rules, ok := a.files[dir]
+	if !ok {
+		data, err := os.ReadFile(filepath.Join(a.dir, filepath.FromSlash(dir), ".gitattributes"))
+		if err == nil {
+			rules = parseAttributes(data, dir)
+		}
+		a.files[dir] = rules
+	}
+	return rules
+}
+
+// matchAttribute returns the value of the last rule matching the file that
+// mentions the attribute
+func matchAttribute(rules []attributeRule, file, name string) (string, bool) {
+	for i := len(rules) - 1; i >= 0; i-- {
+		value, ok := rules[i].values[name]
+		if ok && rules[i].pattern.match(file, false) {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// flag returns whether a boolean attribute is set for a file and whether it
+// is specified, any value other than false sets it
+func (a *gitAttributes) flag(file, name string) (bool, bool) {
+	value, ok := a.value(file, name)
+	return ok && value != "false", ok
+}
+
+// generated reports whether a file was generated, vendored or is
+// documentation. Files matching generatedPatterns are generated unless
+// linguist-generated is unset for them.
+func (a *gitAttributes) generated(file string) bool {
+	generated, ok := a.flag(file, attrGenerated)
+	if !ok {
+		for i := range generatedPatterns {
+			if generatedPatterns[i].match(file, false) {
+				generated = true
+				break
+			}
+		}
+	}
+	vendored, _ := a.flag(file, attrVendored)
+	documentation, _ := a.flag(file, attrDocumentation)
+	return generated || vendored || documentation
+}
@@ -0,0 +1,65 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAttributes(t *testing.T) {
+	rules := parseAttributes([]byte(`# comment
+[attr]generated linguist-generated
+*.pb.go	linguist-generated
+"docs/my notes/*" linguist-documentation -diff
+!negated.go linguist-vendored
+go.sum -linguist-generated !text eol=lf
+`), "api")
+
+	assert.Len(t, rules, 3)
+	assert.Equal(t, parsePathPattern("*.pb.go", "api"), rules[0].pattern)
+	assert.Equal(t, map[string]string{"linguist-generated": "true"}, rules[0].values)
+	assert.Equal(t, parsePathPattern("docs/my notes/*", "api"), rules[1].pattern)
+	assert.Equal(t, map[string]string{"linguist-documentation": "true", "diff": "false"}, rules[1].values)
+	assert.Equal(t, map[string]string{"linguist-generated": "false", "text": "", "eol": "lf"}, rules[2].values)
+}
+
+func TestGitAttributesGenerated(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(".gitattributes", "third_party/** linguist-vendored\n*.snap linguist-generated\ngo.sum -linguist-generated\n")
+	write("web/.gitattributes", "*.snap -linguist-generated\ndocs/** linguist-documentation\n")
+	write(".git/info/attributes", "local.go linguist-generated\n")
+
+	attributes := newGitAttributes(dir)
+	tests := []struct {
+		path      string
+		generated bool
+	}{
+		{"main.go", false},
+		{"api/user.pb.go", true},
+		{"web/dist/app.min.js", true},
+		{"web/package-lock.json", true},
+		{"go.sum", false},
+		{"third_party/lib/lib.c", true},
+		{"api/third_party/lib.c", false},
+		{"api/ui.snap", true},
+		{"web/ui.snap", false},
+		{"web/docs/guide.md", true},
+		{"docs/guide.md", false},
+		{"cmd/local.go", true},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.generated, attributes.generated(tt.path), tt.path)
+	}
+}
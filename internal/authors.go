@@ -0,0 +1,196 @@
+package internal
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// authorFilter selects the commits that are counted by their author. Both
+// backends read every commit and the filter is applied to the commits, cached
+// or not, so that authors are matched the same way whatever reads them.
+type authorFilter struct {
+	include    []*regexp.Regexp // --author patterns
+	identities []authorIdentity // identities of the --authors-file
+	exclude    []*regexp.Regexp // --exclude-author patterns
+}
+
+// authorIdentity is a line of an authors file, an email, a name or both
+type authorIdentity struct {
+	name  string
+	email string
+}
+
+// newAuthorFilter compiles the author patterns, basic regular expressions
+// like git log --author takes, matched against "Name <email>" ignoring case.
+// It returns nil when every author is counted.
+func newAuthorFilter(patterns, excludes []string, identities []authorIdentity) (*authorFilter, error) {
+	if len(patterns) == 0 && len(excludes) == 0 && len(identities) == 0 {
+		return nil, nil
+	}
+	include, err := compileAuthorPatterns(patterns)
+	if err != nil {
+		return nil, err
+	}
+	exclude, err := compileAuthorPatterns(excludes)
+	if err != nil {
+		return nil, err
+	}
+	return &authorFilter{include: include, identities: identities, exclude: exclude}, nil
+}
+
+func compileAuthorPatterns(patterns []string) ([]*regexp.Regexp, error) {
+	regexps := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := compileBasicRegexp("(?i)", pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid author pattern %q: %w", pattern, err)
+		}
+		regexps = append(regexps, re)
+	}
+	return regexps, nil
+}
+
+// included reports whether an author matches one of the patterns or
+// identities, every author does when there are none
+func (f *authorFilter) included(name, email string) bool {
+	if f == nil || len(f.include) == 0 && len(f.identities) == 0 {
+		return true
+	}
+	identity := name + " <" + email + ">"
+	for _, re := range f.include {
+		if re.MatchString(identity) {
+			return true
+		}
+	}
+	for _, id := range f.identities {
+		if id.match(name, email) {
+			return true
+		}
+	}
+	return false
+}
+
+// excluded reports whether an author matches one of the excluded patterns
+func (f *authorFilter) excluded(name, email string) bool {
+	if f == nil {
+		return false
+	}
+	identity := name + " <" + email + ">"
+	for _, re := range f.exclude {
+		if re.MatchString(identity) {
+			return true
+		}
+	}
+	return false
+}
+
+// match reports whether an author has the identity, by email when it has one
+// and by name otherwise, ignoring case
+func (id authorIdentity) match(name, email string) bool {
+	if id.email != "" {
+		return strings.EqualFold(id.email, email)
+	}
+	return strings.EqualFold(id.name, name)
+}
+
+// String formats the identity as "Name <email>"
+func (id authorIdentity) String() string {
+	switch {
+	case id.email == "":
+		return id.name
+	case id.name == "":
+		return "<" + id.email + ">"
+	}
+	return id.name + " <" + id.email + ">"
+}
+
+// parseAuthorIdentity parses "Name <email>", "email" or "Name"
+func parseAuthorIdentity(s string) authorIdentity {
+	s = strings.TrimSpace(s)
+	if start := strings.LastIndexByte(s, '<'); start >= 0 && strings.HasSuffix(s, ">") {
+		return authorIdentity{name: strings.TrimSpace(s[:start]), email: strings.TrimSpace(s[start+1 : len(s)-1])}
+	}
+	if strings.Contains(s, "@") && !strings.ContainsAny(s, " \t") {
+		return authorIdentity{email: s}
+	}
+	return authorIdentity{name: s}
+}
+
+// readAuthorsFile reads the identities of a file with one identity per line,
+// skipping blank lines and # comments
+func readAuthorsFile(path string) ([]authorIdentity, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var identities []authorIdentity
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		identities = append(identities, parseAuthorIdentity(line))
+	}
+	return identities, scanner.Err()
+}
+
+// compileBasicRegexp compiles a POSIX basic regular expression, the syntax of
+// git log --author, where ( ) { } | + ? are literal unless escaped. The flags,
+// such as (?i), are prepended to the compiled expression.
+func compileBasicRegexp(flags, pattern string) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	sb.WriteString(flags)
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '\\':
+			if i+1 == len(pattern) {
+				return nil, errors.New("trailing backslash")
+			}
+			i++
+			if strings.IndexByte("(){}|+?", pattern[i]) >= 0 {
+				sb.WriteByte(pattern[i])
+			} else {
+				sb.WriteByte('\\')
+				sb.WriteByte(pattern[i])
+			}
+		case strings.IndexByte("(){}|+?", c) >= 0:
+			sb.WriteByte('\\')
+			sb.WriteByte(c)
+		case c == '[':
+			// Bracket expressions are copied, backslashes are literal in them
+			end := i + 1
+			if end < len(pattern) && pattern[end] == '^' {
+				end++
+			}
+			if end < len(pattern) && pattern[end] == ']' {
+				end++
+			}
+			for end < len(pattern) && pattern[end] != ']' {
+				// Skip character classes such as [:alpha:]
+				if pattern[end] == '[' && end+1 < len(pattern) && pattern[end+1] == ':' {
+					if close := strings.Index(pattern[end+2:], ":]"); close >= 0 {
+						end += close + 4
+						continue
+					}
+				}
+				end++
+			}
+			if end == len(pattern) {
+				return nil, errors.New("unterminated bracket expression")
+			}
+			sb.WriteString(strings.ReplaceAll(pattern[i:end+1], `\`, `\\`))
+			i = end
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return regexp.Compile(sb.String())
+}
@@ -0,0 +1,67 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthorFilter(t *testing.T) {
+	type author struct{ name, email string }
+	jane := author{"Jane Roe", "Jane.Roe@Example.com"}
+	john := author{"John Doe", "john@example.com"}
+	bot := author{"dependabot[bot]", "49699333+dependabot[bot]@users.noreply.github.com"}
+	renovate := author{"Renovate Bot", "bot@renovateapp.com"}
+
+	tests := []struct {
+		name       string
+		patterns   []string
+		excludes   []string
+		identities []authorIdentity
+		counted    []author
+	}{
+		{"all", nil, nil, nil, []author{jane, john, bot, renovate}},
+		{"patterns", []string{"jane", "^John"}, nil, nil, []author{jane, john}},
+		{"email ignores case", []string{`jane\.roe@example\.com`}, nil, nil, []author{jane}},
+		{"basic regexp", []string{`Doe|Roe`, `\(Jane\|John\) `}, nil, nil, []author{jane, john}},
+		{"excludes", nil, []string{`\[bot\]`, "renovate"}, nil, []author{jane, john}},
+		{"identities", nil, nil, []authorIdentity{{email: "JOHN@example.com"}, {name: "jane roe"}}, []author{jane, john}},
+		{"identities and patterns", []string{"dependabot"}, []string{"john"}, []authorIdentity{{email: "john@example.com"}}, []author{bot}},
+	}
+	for _, tt := range tests {
+		f, err := newAuthorFilter(tt.patterns, tt.excludes, tt.identities)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var counted []author
+		for _, a := range []author{jane, john, bot, renovate} {
+			if f.included(a.name, a.email) && !f.excluded(a.name, a.email) {
+				counted = append(counted, a)
+			}
+		}
+		assert.Equal(t, tt.counted, counted, tt.name)
+	}
+
+	_, err := newAuthorFilter([]string{"[a-"}, nil, nil)
+	assert.EqualError(t, err, "invalid author pattern \"[a-\": unterminated bracket expression")
+}
+
+func TestReadAuthorsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "team.txt")
+	content := "# backend\nJane Roe <jane@example.com>\n\njohn@example.com\n  Alex Smith  \n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	identities, err := readAuthorsFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []authorIdentity{
+		{name: "Jane Roe", email: "jane@example.com"},
+		{email: "john@example.com"},
+		{name: "Alex Smith"},
+	}, identities)
+}
@@ -0,0 +1,501 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fixtureRepo builds the history of a test repository with git plumbing, so
+// that symlinks, file modes and unusual names work on every platform
+type fixtureRepo struct {
+	t     *testing.T
+	dir   string
+	dates int // commits made so far, each commit is an hour after the previous one
+}
+
+func newFixtureRepo(t *testing.T) *fixtureRepo {
+	f := &fixtureRepo{t: t, dir: t.TempDir()}
+	f.git("init", "-q", "-b", "main")
+	f.git("config", "user.name", "Test User")
+	f.git("config", "user.email", "test@example.com")
+	return f
+}
+
+func (f *fixtureRepo) git(args ...string) string {
+	return gitOutput(f.t, f.dir, nil, args...)
+}
+
+func gitOutput(t *testing.T, dir string, env []string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// stage adds a file with the given mode to the index
+func (f *fixtureRepo) stage(mode, path, content string) {
+	cmd := exec.Command("git", "hash-object", "-w", "--stdin")
+	cmd.Dir = f.dir
+	cmd.Stdin = strings.NewReader(content)
+	out, err := cmd.Output()
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	f.git("update-index", "--add", "--cacheinfo", mode+","+strings.TrimSpace(string(out))+","+path)
+}
+
+func (f *fixtureRepo) write(path, content string) {
+	f.stage("100644", path, content)
+}
+
+func (f *fixtureRepo) remove(path string) {
+	f.git("update-index", "--force-remove", path)
+}
+
+// move renames an entry of the index, keeping its mode and object
+func (f *fixtureRepo) move(oldPath, newPath string) {
+	mode, rest, _ := strings.Cut(f.git("ls-files", "-s", "--", oldPath), " ")
+	hash, _, _ := strings.Cut(rest, " ")
+	f.git("update-index", "--add", "--cacheinfo", mode+","+hash+","+newPath)
+	f.remove(oldPath)
+}
+
+// commit commits the index on a branch, by "Name <email>", with the given
+// parents or with the tip of the branch as parent when there are none
+func (f *fixtureRepo) commit(branch, author string, parents ...string) string {
+	f.dates++
+	date := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(f.dates) * time.Hour)
+	return f.commitAt(branch, author, date, parents...)
+}
+
+func (f *fixtureRepo) commitAt(branch, author string, date time.Time, parents ...string) string {
+	return f.commitMessage(branch, author, date, "commit "+fmt.Sprint(f.dates), parents...)
+}
+
+// commitMessage commits the index like commitAt, with the given message
+func (f *fixtureRepo) commitMessage(branch, author string, date time.Time, message string, parents ...string) string {
+	return f.commitRebased(branch, author, date, date, message, parents...)
+}
+
+// commitRebased commits the index like commitMessage, with an author date
+// different from the committer date like rebased and cherry-picked commits
+func (f *fixtureRepo) commitRebased(branch, author string, authored, committed time.Time, message string, parents ...string) string {
+	name, email, _ := strings.Cut(strings.TrimSuffix(author, ">"), " <")
+	env := []string{
+		"GIT_AUTHOR_NAME=" + name, "GIT_AUTHOR_EMAIL=" + email,
+		"GIT_COMMITTER_NAME=" + name, "GIT_COMMITTER_EMAIL=" + email,
+		"GIT_AUTHOR_DATE=" + authored.Format(time.RFC3339), "GIT_COMMITTER_DATE=" + committed.Format(time.RFC3339),
+	}
+	if len(parents) == 0 {
+		if tip, err := exec.Command("git", "-C", f.dir, "rev-parse", "-q", "--verify", "refs/heads/"+branch).Output(); err == nil {
+			parents = []string{strings.TrimSpace(string(tip))}
+		}
+	}
+	args := []string{"commit-tree", f.git("write-tree"), "-m", message}
+	for _, parent := range parents {
+		args = append(args, "-p", parent)
+	}
+	hash := gitOutput(f.t, f.dir, env, args...)
+	f.git("update-ref", "refs/heads/"+branch, hash)
+	return hash
+}
+
+// checkout switches the index to the tree of a commit, leaving the working tree alone
+func (f *fixtureRepo) checkout(rev string) {
+	f.git("read-tree", rev)
+}
+
+const (
+	testUser = "Test User <test@example.com>"
+	johnDoe  = "John Doe <john.doe@example.com>"
+)
+
+// numberedLines returns lines "line 0" to "line n-1", with replaced lines
+func numberedLines(n int, replace map[int]string) string {
+	var sb strings.Builder
+	for i := range n {
+		if line, ok := replace[i]; ok {
+			sb.WriteString(line)
+		} else {
+			fmt.Fprintf(&sb, "line %d\n", i)
+		}
+	}
+	return sb.String()
+}
+
+// buildHistory commits files of all kinds on several branches
+func buildHistory(f *fixtureRepo) {
+	f.write("README.md", "# Fixture\n")
+	f.write("src/app/main.go", "package main\n\nfunc main() {\n}\n")
+	f.write("src/app/util.go", "package main\n")
+	f.write("no-newline.txt", "first\nlast")
+	f.write("crlf.txt", "one\r\ntwo\r\n")
+	f.write("empty.txt", "")
+	f.write("big.txt", numberedLines(2000, nil))
+	f.stage("100644", "logo.png", "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	f.write("space name.txt", "spaces\n")
+	f.write("naïve.md", "unicode\n")
+	f.write("tab\tname.txt", "tab\n")
+	f.write(`quote".txt`, "quote\n")
+	root := f.commit("main", testUser)
+
+	// Edits, including a missing newline at the end being added
+	f.write("src/app/main.go", "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"hi\")\n}\n")
+	f.write("no-newline.txt", "first\nlast\n")
+	f.write("crlf.txt", "one\r\n2\r\n")
+	f.commit("main", testUser)
+
+	// Scattered edits, insertions and deletions in a large file
+	replaced := map[int]string{}
+	for i := 0; i < 2000; i += 97 {
+		replaced[i] = fmt.Sprintf("changed %d\n", i)
+	}
+	replaced[500] = "inserted a\ninserted b\nline 500\n"
+	replaced[1200] = ""
+	replaced[1201] = ""
+	f.write("big.txt", numberedLines(2000, replaced))
+	f.stage("100644", "logo.png", "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x10")
+	f.commit("main", testUser)
+
+	// Mode changes, a symlink, a submodule and a file replaced by a directory
+	f.stage("100755", "src/app/util.go", "package main\n")
+	f.stage("120000", "link", "src/app/main.go")
+	f.git("update-index", "--add", "--cacheinfo", "160000,"+root+",vendor/sub")
+	f.remove("empty.txt")
+	f.write("empty.txt/inside.txt", "now a directory\n")
+	f.commit("main", testUser)
+
+	// A rename and a deleted directory
+	f.remove("space name.txt")
+	f.write("moved/space name.txt", "spaces\n")
+	f.remove("src/app/main.go")
+	f.remove("src/app/util.go")
+	f.commit("main", testUser)
+
+	// Renames with and without edits, of symlinks, submodules and quoted
+	// paths, and a copy of a modified file
+	f.remove("big.txt")
+	f.write("docs/big.txt", numberedLines(2000, map[int]string{5: "renamed and edited\n"}))
+	f.remove("naïve.md")
+	f.write("docs/naïve.md", "unicode\n")
+	f.remove("tab\tname.txt")
+	f.write("tabs/tab\tname.txt", "tab\n")
+	f.move("link", "link2")
+	f.move("vendor/sub", "vendor/lib")
+	f.write("README.md", "# Fixture\n\nWith a copy\n")
+	f.write("docs/README.md", "# Fixture\ndocs\n")
+	f.commit("main", testUser)
+
+	// A feature branch by another author, merged with a change in the merge itself
+	base := f.commit("main", testUser)
+	f.write("feature.go", "package feature\n\nvar Enabled = true\n")
+	f.commit("feature", johnDoe, base)
+	f.write("feature.go", "package feature\n\nvar Enabled = false\n")
+	feature := f.commit("feature", johnDoe)
+	f.checkout(base)
+	f.write("main-only.txt", "main\n")
+	mainTip := f.commit("main", testUser)
+	f.checkout(feature)
+	f.write("main-only.txt", "main\n")
+	f.write("merge.txt", "resolved in the merge\n")
+	f.commit("main", testUser, mainTip, feature)
+
+	// A backport of the first feature commit on an older commit
+	f.checkout(root)
+	f.write("feature.go", "package feature\n\nvar Enabled = true\n")
+	f.commit("backport", testUser, root)
+	f.checkout(feature)
+
+	// A branch that is never merged, and an unrelated history
+	f.write("wip.txt", "work in progress\n")
+	f.commit("wip", johnDoe, feature)
+
+	// Pair programming, with trailers git finds and ones it doesn't
+	messages := []string{
+		"Pair\n\nCo-authored-by: Jane Roe <jane@example.com>\nCo-Authored-By: " + testUser,
+		"Folded\n\nBody\n\nco-authored-by:  Jane\n  Roe <jane@example.com>\nReviewed-by: Bob <bob@example.com>",
+		"Mixed\n\nSigned-off-by: " + johnDoe + "\nnot a trailer\nCo-authored-by: Bob <bob@example.com>",
+		"Prose\n\nCo-authored-by: Jane Roe <jane@example.com>\n\nThanks to everyone.",
+		"Co-authored-by: Jane Roe <jane@example.com>",
+		"Patch\n\nCo-authored-by: Bob <bob@example.com>\n---\n a.txt | 1 +",
+		"Self\n\nCo-authored-by: " + johnDoe + "\nCo-authored-by: jane roe <JANE@example.com>\nCo-authored-by: Jane Roe <jane@example.com>",
+	}
+	for i, message := range messages {
+		f.write("wip.txt", fmt.Sprintf("work in progress %d\n", i))
+		f.dates++
+		f.commitMessage("wip", johnDoe, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(f.dates)*time.Hour), message)
+	}
+
+	// Committed from another time zone
+	f.write("wip.txt", "work from abroad\n")
+	f.dates++
+	f.commitAt("wip", johnDoe, time.Date(2025, 3, 1, 0, 0, 0, 0, time.FixedZone("", -(3*60+30)*60)).Add(time.Duration(f.dates)*time.Hour))
+
+	// Written weeks before it was rebased
+	f.write("wip.txt", "rebased work\n")
+	f.dates++
+	f.commitRebased("wip", johnDoe, time.Date(2025, 2, 20, 15, 0, 0, 0, time.UTC), time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(f.dates)*time.Hour), "rebased")
+	f.git("read-tree", "--empty")
+	f.write("orphan.txt", "orphan\n")
+	f.commit("orphan", johnDoe, []string{}...)
+
+	// A commit dated before its parent
+	f.write("skew.txt", "skewed\n")
+	skewed := f.commitAt("skew", testUser, time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC), mainTip)
+	f.write("skew.txt", "after the skew\n")
+	f.commit("skew", testUser, skewed)
+
+	// Remote-tracking branches and tags, including tags of tags and of trees
+	f.git("update-ref", "refs/remotes/origin/main", mainTip)
+	f.git("update-ref", "refs/remotes/origin/release/1.0", feature)
+	f.git("update-ref", "refs/remotes/origin/release/1.1/hotfix", skewed)
+	f.git("symbolic-ref", "refs/remotes/origin/HEAD", "refs/remotes/origin/main")
+	f.git("tag", "v1.0", root)
+	f.git("tag", "-a", "-m", "release", "v2.0", feature)
+	f.git("tag", "-a", "-m", "nested", "v2.0-signed", "v2.0")
+	f.git("tag", "-a", "-m", "tree", "tree-tag", root+"^{tree}")
+
+	f.git("symbolic-ref", "HEAD", "refs/heads/main")
+	f.checkout("main")
+}
+
+// backendOptions are the filters compared between the backends
+var backendOptions = map[string]GitStatsOptions{
+	"default":      {},
+	"since":        {Since: "2025-03-01T06:00:00Z"},
+	"until":        {Until: "2025-03-01T08:00:00Z"},
+	"period":       {Since: "2025-03-01T03:00:00Z", Until: "2025-03-01T09:30:00+01:00"},
+	"author since": {DateField: DateAuthor, Since: "2025-03-01T06:00:00Z"},
+	"author until": {DateField: DateAuthor, Until: "2025-02-28T00:00:00Z"},
+	"author range": {DateField: DateAuthor, Since: "2025-02-15T00:00:00Z", Until: "2025-03-01T08:00:00Z"},
+	"author":       {Authors: mustAuthorFilter("John")},
+	"author email": {Authors: mustAuthorFilter(`doe@example\.com`)},
+	"author start": {Authors: mustAuthorFilter("^Test User")},
+	"author group": {Authors: mustAuthorFilter(`John \(Doe\)`)},
+	"author none":  {Authors: mustAuthorFilter("nobody")},
+	"head":         {Refs: []string{RefsHead}},
+	"remotes":      {Refs: []string{RefsRemotes}},
+	"tags":         {Refs: []string{RefsTags}},
+	"all":          {Refs: []string{RefsAll}},
+	"glob":         {Refs: []string{"origin/release/*", "refs/heads/w*"}},
+	"dedupe":       {Refs: []string{RefsAll}, Dedupe: true},
+	"merges":       {Merges: MergesInclude},
+	"no merges":    {Merges: MergesExclude},
+	"first parent": {Merges: MergesFirstParent},
+	"first since":  {Merges: MergesFirstParent, Since: "2025-03-01T06:00:00Z"},
+	"merge dedupe": {Refs: []string{RefsAll}, Merges: MergesInclude, Dedupe: true},
+	"moved":        {Refs: []string{RefsAll}, Moved: true},
+	"moved dedupe": {Moved: true, Dedupe: true},
+	"binary bytes": {Refs: []string{RefsAll}, BinaryBytes: true},
+	"credit":       {Refs: []string{RefsAll}, Coauthors: CoauthorsCredit, Authors: mustAuthorFilter("jane")},
+	"split":        {Refs: []string{RefsAll}, Coauthors: CoauthorsSplit},
+}
+
+func mustAuthorFilter(patterns ...string) *authorFilter {
+	f, err := newAuthorFilter(patterns, nil, nil)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+func mustExcludeAuthorFilter(patterns ...string) *authorFilter {
+	f, err := newAuthorFilter(nil, patterns, nil)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+func sortedCommits(commits []commit) []commit {
+	sort.Slice(commits, func(i, j int) bool {
+		return commits[i].Hash < commits[j].Hash
+	})
+	for _, c := range commits {
+		sort.Slice(c.Files, func(i, j int) bool {
+			return c.Files[i].Path < c.Files[j].Path
+		})
+	}
+	return commits
+}
+
+// assertBackendsAgree checks that both backends read the same commits and statistics
+func assertBackendsAgree(t *testing.T, dir string) {
+	t.Helper()
+	for name, opts := range backendOptions {
+		opts.ByAuthor = true
+		execOpts, nativeOpts := opts, opts
+		execOpts.Backend = BackendExec
+		nativeOpts.Backend = BackendNative
+
+		execRefs, err := resolveRefs(context.Background(), dir, &execOpts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nativeRefs, err := resolveRefs(context.Background(), dir, &nativeOpts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, execRefs, nativeRefs, name)
+
+		// The period bounds the history like it does without the cache
+		since, _, err := parsePeriod(&opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		execCommits, err := readCommits(context.Background(), dir, &execOpts, execRefs, uncachedBounds(&execOpts, since))
+		if err != nil {
+			t.Fatal(err)
+		}
+		nativeCommits, err := readCommits(context.Background(), dir, &nativeOpts, nativeRefs, uncachedBounds(&nativeOpts, since))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if execCommits, err = filterDates(execCommits, &execOpts); err != nil {
+			t.Fatal(err)
+		}
+		if nativeCommits, err = filterDates(nativeCommits, &nativeOpts); err != nil {
+			t.Fatal(err)
+		}
+		if len(execCommits) == 0 && len(nativeCommits) == 0 {
+			continue
+		}
+		assert.Equal(t, sortedCommits(execCommits), sortedCommits(nativeCommits), name)
+		assert.Equal(t, aggregateCommits(execCommits, &execOpts, nil), aggregateCommits(nativeCommits, &nativeOpts, nil), name)
+	}
+}
+
+func TestBackendConformance(t *testing.T) {
+	// Keep the configuration of the machine out of the fixtures and of git log
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	t.Run("loose objects", func(t *testing.T) {
+		f := newFixtureRepo(t)
+		buildHistory(f)
+		assertBackendsAgree(t, f.dir)
+	})
+
+	t.Run("packed with offset deltas", func(t *testing.T) {
+		f := newFixtureRepo(t)
+		buildHistory(f)
+		f.git("gc", "-q", "--aggressive")
+		assertBackendsAgree(t, f.dir)
+	})
+
+	t.Run("packed with ref deltas", func(t *testing.T) {
+		f := newFixtureRepo(t)
+		buildHistory(f)
+		f.git("-c", "repack.useDeltaBaseOffset=false", "repack", "-q", "-a", "-d", "-f")
+		f.git("pack-refs", "--all")
+		assertBackendsAgree(t, f.dir)
+	})
+
+	t.Run("loose objects and refs over a pack", func(t *testing.T) {
+		f := newFixtureRepo(t)
+		buildHistory(f)
+		f.git("gc", "-q")
+		f.write("big.txt", numberedLines(2100, map[int]string{7: "after gc\n"}))
+		f.commit("main", johnDoe)
+		f.write("wip.txt", "more work\n")
+		f.commit("wip", johnDoe)
+		assertBackendsAgree(t, f.dir)
+	})
+
+	t.Run("shared objects", func(t *testing.T) {
+		f := newFixtureRepo(t)
+		buildHistory(f)
+		f.git("gc", "-q")
+		clone := filepath.Join(t.TempDir(), "clone")
+		gitOutput(t, f.dir, nil, "clone", "-q", "--shared", "--no-checkout", f.dir, clone)
+		assertBackendsAgree(t, clone)
+	})
+
+	t.Run("shallow clone", func(t *testing.T) {
+		f := newFixtureRepo(t)
+		buildHistory(f)
+		source := filepath.ToSlash(f.dir)
+		if !strings.HasPrefix(source, "/") {
+			source = "/" + source
+		}
+		clone := filepath.Join(t.TempDir(), "clone")
+		gitOutput(t, f.dir, nil, "clone", "-q", "--depth", "2", "--no-checkout", "--no-single-branch", "file://"+source, clone)
+		gitOutput(t, clone, nil, "branch", "-q", "feature", "origin/feature")
+		assertBackendsAgree(t, clone)
+	})
+
+	t.Run("large edits", func(t *testing.T) {
+		// Beyond the edit cost after which the diff settles for a good enough
+		// edit script
+		f := newFixtureRepo(t)
+		var functions, shuffled strings.Builder
+		for i := range 500 {
+			fmt.Fprintf(&functions, "func f%d() {\n\treturn %d\n}\n\n", i, i%7)
+			fmt.Fprintf(&shuffled, "func f%d() {\n\treturn %d\n}\n\n", (i*137)%500, (i*137)%500%7)
+		}
+		f.write("functions.go", functions.String())
+		f.commit("main", testUser)
+		f.write("functions.go", shuffled.String())
+		f.commit("main", testUser)
+
+		// Many edits of a file with few distinct lines, so that many edit
+		// scripts have the same length
+		rng := rand.New(rand.NewPCG(1, 2))
+		var lines []string
+		for range 3000 {
+			lines = append(lines, fmt.Sprintf("value %d\n", rng.IntN(8)))
+		}
+		f.write("values.txt", strings.Join(lines, ""))
+		f.commit("main", testUser)
+		var edited []string
+		for _, line := range lines {
+			switch rng.IntN(10) {
+			case 0:
+			case 1:
+				edited = append(edited, fmt.Sprintf("value %d\n", rng.IntN(8)))
+			case 2:
+				edited = append(edited, line, fmt.Sprintf("value %d\n", rng.IntN(8)))
+			default:
+				edited = append(edited, line)
+			}
+		}
+		f.write("values.txt", strings.Join(edited, ""))
+		f.commit("main", testUser)
+		assertBackendsAgree(t, f.dir)
+	})
+
+	t.Run("real history", func(t *testing.T) {
+		// Revisions of a source file of this repository, where the heuristics
+		// of git diff give other counts than the shortest edit script
+		f := newFixtureRepo(t)
+		for i := 1; i <= 3; i++ {
+			content, err := os.ReadFile(filepath.Join("test_data", fmt.Sprintf("diff.go.%d.txt", i)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			f.write("diff.go", string(content))
+			f.commit("main", testUser)
+		}
+		assertBackendsAgree(t, f.dir)
+	})
+
+	t.Run("empty repository", func(t *testing.T) {
+		f := newFixtureRepo(t)
+		assertBackendsAgree(t, f.dir)
+	})
+}
@@ -0,0 +1,200 @@
+package internal
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// cacheVersion is part of every cache key, bump it whenever the commit records
+// change so that older entries are ignored
+const cacheVersion = 13
+
+// cacheMaxAge is how long an entry is kept without being used, entries of
+// relative dates such as --since 7d are only used until the dates move
+const cacheMaxAge = 30 * 24 * time.Hour
+
+// DefaultCacheDir returns the directory of the commit cache, gitbrag under
+// $XDG_CACHE_HOME or under the user cache directory of the platform
+func DefaultCacheDir() (string, error) {
+	dir := os.Getenv("XDG_CACHE_HOME")
+	if dir == "" {
+		var err error
+		dir, err = os.UserCacheDir()
+		if err != nil {
+			return "", err
+		}
+	}
+	return filepath.Join(dir, "gitbrag"), nil
+}
+
+// ClearCache removes all cached commits
+func ClearCache(dir string) error {
+	return os.RemoveAll(dir)
+}
+
+// commitCache stores the commits read from each repository on disk, so that
+// repositories whose branches haven't moved don't need git log again, and
+// repositories whose branches moved forward only read their new commits.
+// The whole history is cached and the statistics are aggregated from it on
+// every run, so that the period, excluded files, authors and days in the local
+// time zone are always up to date, and relative periods such as --since 7d
+// reuse the same entry.
+type commitCache struct {
+	dir string
+}
+
+// cacheKey holds everything the commits read from a repository depend on,
+// other than its refs. Every option passed to git log must be part of it.
+type cacheKey struct {
+	Version int    `json:"version"`
+	Path    string `json:"path"`
+	Backend string `json:"backend"`
+	Dedupe  bool   `json:"dedupe"` // commits have their patch identity
+	Merges  string `json:"merges"`
+	Moved   bool   `json:"moved"`  // files have the lines kept by renames
+	Binary  bool   `json:"binary"` // binary files have their size change
+}
+
+// cacheEntry is the history of a repository, a single entry per key that is
+// overwritten when the refs move
+type cacheEntry struct {
+	Key     cacheKey `json:"key"`
+	Tips    []gitRef `json:"tips"` // refs the commits were read from, with their commits
+	Commits []commit `json:"commits"`
+}
+
+// key returns the cache key of a repository
+func (c *commitCache) key(dir string, opts *GitStatsOptions) cacheKey {
+	return cacheKey{
+		Version: cacheVersion,
+		Path:    dir,
+		Backend: opts.Backend,
+		Dedupe:  opts.Dedupe,
+		Merges:  opts.Merges,
+		Moved:   opts.Moved,
+		Binary:  opts.BinaryBytes,
+	}
+}
+
+func (c *commitCache) path(key cacheKey) string {
+	data, _ := json.Marshal(key)
+	sum := sha256.Sum256(data)
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// get returns the cached entry, a missing or unreadable entry is a miss
+func (c *commitCache) get(key cacheKey) (*cacheEntry, bool) {
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Key != key {
+		return nil, false
+	}
+	// Dates are grouped by day in the local time zone, like the dates read from git log
+	for i := range entry.Commits {
+		entry.Commits[i].Date = entry.Commits[i].Date.Local()
+		entry.Commits[i].AuthorDate = entry.Commits[i].AuthorDate.Local()
+	}
+	// Keep the entry from being pruned while it is in use
+	now := time.Now()
+	os.Chtimes(c.path(key), now, now)
+	return &entry, true
+}
+
+// put stores the commits read from the refs, replacing the previous entry.
+// It writes to a temporary file first so that concurrent runs never read a
+// partial entry.
+func (c *commitCache) put(key cacheKey, tips []gitRef, commits []commit) error {
+	data, err := json.Marshal(cacheEntry{Key: key, Tips: tips, Commits: commits})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(c.dir, "entry-*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Rename(f.Name(), c.path(key)); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return nil
+}
+
+// prune removes the entries that haven't been used for maxAge
+func (c *commitCache) prune(maxAge time.Duration) {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || time.Since(info.ModTime()) < maxAge {
+			continue
+		}
+		os.Remove(filepath.Join(c.dir, entry.Name()))
+	}
+}
+
+// commits returns the cached commits of the repository. When the refs moved,
+// only the commits added since the entry was written are read, unless a ref
+// was rewound, e.g. by a force push, and the whole history is read again.
+// Caching is best effort, a cache that can't be read or written only makes
+// the run slower.
+func (c *commitCache) commits(ctx context.Context, dir string, opts *GitStatsOptions, refs []gitRef) ([]commit, error) {
+	key := c.key(dir, opts)
+	entry, ok := c.get(key)
+	if ok && slices.Equal(entry.Tips, refs) {
+		return entry.Commits, nil
+	}
+
+	// The first-parent history of a ref isn't the one of the refs it was
+	// moved to, it is always read again
+	if ok && opts.Merges != MergesFirstParent {
+		added, err := readCommits(ctx, dir, opts, refs, &logBounds{from: entry.Tips, known: commitHashes(entry.Commits)})
+		if err == nil {
+			commits := append(added, entry.Commits...)
+			c.put(key, refs, commits)
+			return commits, nil
+		}
+		if !errors.Is(err, errRefsRewound) {
+			return nil, err
+		}
+	}
+
+	commits, err := readCommits(ctx, dir, opts, refs, nil)
+	if err != nil {
+		return nil, err
+	}
+	c.put(key, refs, commits)
+	return commits, nil
+}
+
+// commitHashes returns the set of hashes of the commits
+func commitHashes(commits []commit) map[string]bool {
+	hashes := make(map[string]bool, len(commits))
+	for _, c := range commits {
+		hashes[c.Hash] = true
+	}
+	return hashes
+}
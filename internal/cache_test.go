@@ -0,0 +1,129 @@
+package internal
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommitCache(t *testing.T) {
+	cache := &commitCache{dir: filepath.Join(t.TempDir(), "gitbrag")}
+	key := cacheKey{Version: cacheVersion, Path: "/work/app"}
+	tips := []gitRef{{Name: "refs/heads/main", Hash: "abc"}}
+
+	_, ok := cache.get(key)
+	assert.False(t, ok)
+
+	commits := []commit{{
+		Hash:        "abc",
+		AuthorName:  "John Doe",
+		AuthorEmail: "john.doe@example.com",
+		Date:        time.Unix(1741600800, 0),
+		AuthorDate:  time.Unix(1741500000, 0),
+		Files:       []fileChange{{Path: "main.go", Insertions: 8}, {Path: "logo.png", Binary: true}},
+	}}
+	if err := cache.put(key, tips, commits); err != nil {
+		t.Fatal(err)
+	}
+
+	entry, ok := cache.get(key)
+	assert.True(t, ok)
+	assert.Equal(t, tips, entry.Tips)
+	assert.Equal(t, commits, entry.Commits)
+
+	// Any change of the key is a miss
+	other := key
+	other.Backend = BackendNative
+	_, ok = cache.get(other)
+	assert.False(t, ok)
+
+	// The entry of a key is replaced when its refs move
+	moved := []gitRef{{Name: "refs/heads/main", Hash: "def"}}
+	if err := cache.put(key, moved, nil); err != nil {
+		t.Fatal(err)
+	}
+	entry, ok = cache.get(key)
+	assert.True(t, ok)
+	assert.Equal(t, moved, entry.Tips)
+	assert.Empty(t, entry.Commits)
+	files, err := os.ReadDir(cache.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, files, 1)
+
+	// Entries that haven't been used for a while are removed
+	old := time.Now().Add(-2 * cacheMaxAge)
+	if err := os.Chtimes(cache.path(key), old, old); err != nil {
+		t.Fatal(err)
+	}
+	cache.prune(cacheMaxAge)
+	_, ok = cache.get(key)
+	assert.False(t, ok)
+}
+
+func TestCommitCache_Refs(t *testing.T) {
+	// Keep the configuration of the machine out of the fixtures and of git log
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	for _, backend := range Backends() {
+		for _, merges := range []string{"", MergesExclude, MergesFirstParent} {
+			name := backend + " " + merges
+			opts := &GitStatsOptions{Backend: backend, Merges: merges, Dedupe: true}
+			cache := &commitCache{dir: filepath.Join(t.TempDir(), "gitbrag")}
+			f := newFixtureRepo(t)
+
+			// The commits read from the cache are the ones read from the refs
+			assertCached := func(msg string) {
+				t.Helper()
+				refs, err := resolveRefs(context.Background(), f.dir, opts)
+				if err != nil {
+					t.Fatal(err)
+				}
+				cached, err := cache.commits(context.Background(), f.dir, opts, refs)
+				if err != nil {
+					t.Fatal(err)
+				}
+				read, err := readCommits(context.Background(), f.dir, opts, refs, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				assert.Equal(t, sortedCommits(read), sortedCommits(cached), name+": "+msg)
+			}
+
+			f.write("a.txt", "a\n")
+			base := f.commit("main", testUser)
+			assertCached("first read")
+
+			// New commits and branches
+			f.write("a.txt", "a\nb\n")
+			f.commit("main", testUser)
+			f.write("b.txt", "b\n")
+			f.commit("feature", johnDoe, base)
+			assertCached("moved forward")
+
+			// The branch is merged and deleted
+			f.write("a.txt", "a\nb\nc\n")
+			f.commit("main", testUser, f.git("rev-parse", "main"), f.git("rev-parse", "feature"))
+			f.git("update-ref", "-d", "refs/heads/feature")
+			assertCached("merged")
+
+			// A force push drops commits
+			f.checkout(base)
+			f.write("c.txt", "c\n")
+			f.commit("main", johnDoe, base)
+			assertCached("rewound")
+
+			files, err := os.ReadDir(cache.dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Len(t, files, 1, name)
+		}
+	}
+}
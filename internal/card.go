@@ -0,0 +1,113 @@
+package internal
+
+import (
+	"image/color"
+
+	"golang.org/x/image/font"
+)
+
+// cardLayout is the statistics card shared by the PNG and SVG renderers,
+// laid out once so that both only draw the positioned result
+type cardLayout struct {
+	Width    int
+	Height   int
+	Lines    []cardLine    // centered text lines, top to bottom
+	Sections []cardSection // charts and tables below the stats, top to bottom
+}
+
+// cardLine is a line of text with the position of its baseline start
+type cardLine struct {
+	Text  string
+	X     int
+	Y     int
+	Color color.Color
+}
+
+type cardSectionKind int
+
+const (
+	sectionLanguages cardSectionKind = iota
+	sectionHeatmap
+	sectionRows
+)
+
+// cardSection is a chart or table of the card starting at Y, Heatmap and Rows
+// are only set for the sections that draw them
+type cardSection struct {
+	Kind    cardSectionKind
+	Y       int
+	Heatmap *heatmap
+	Rows    []pngRow
+}
+
+// layoutCard positions the lines and sections of the card for a report,
+// measuring text with face and the heatmap labels with smallFace
+func layoutCard(face, smallFace font.Face, width, height int, fg color.Color, report *Report, opts *RunOptions) *cardLayout {
+	stats := &report.Total
+	showLang := opts.Lang && len(stats.Languages) > 0
+	if showLang {
+		height = 950 // Add extra space for language bar and labels
+	}
+
+	l := &cardLayout{Width: width}
+	addLine := func(text string, y int, col color.Color) {
+		x := (width - font.MeasureString(face, text).Ceil()) / 2
+		l.Lines = append(l.Lines, cardLine{Text: text, X: x, Y: y, Color: col})
+	}
+
+	filesStr, insertionsStr, deletionsStr, binaryStr := statLines(stats, opts.BinaryBytes)
+
+	greenColor := color.RGBA{26, 127, 55, 255} // Green for insertions
+	redColor := color.RGBA{209, 36, 47, 255}   // Red for deletions
+
+	// Date range if available, otherwise the dates of the first and last commits
+	yOffset := 280
+	if dateRange := cardDateRange(report); dateRange != "" {
+		addLine(dateRange, yOffset, fg)
+	}
+
+	addLine(filesStr, yOffset+100, fg)
+	addLine(insertionsStr, yOffset+150, greenColor)
+	addLine(deletionsStr, yOffset+200, redColor)
+
+	// Binary files have no lines, they get their own line when there are any
+	statsY := yOffset + 200
+	if binaryStr != "" {
+		statsY += 50
+		height += 50
+		addLine(binaryStr, statsY, fg)
+	}
+
+	// The number of commits and active days below the stats
+	addLine(commitsLine(stats), statsY+50, fg)
+
+	sectionY := statsY + 130
+	if showLang {
+		l.Sections = append(l.Sections, cardSection{Kind: sectionLanguages, Y: sectionY})
+		sectionY += 140
+	}
+
+	// The other sections make the card taller
+	addSection := func(s cardSection, sectionHeight int) {
+		s.Y = sectionY
+		l.Sections = append(l.Sections, s)
+		sectionY += sectionHeight
+		height += sectionHeight
+	}
+
+	if opts.Heatmap != "" && !report.From.IsZero() {
+		hm := newHeatmap(stats, report.From, report.To, opts.Heatmap)
+		addSection(cardSection{Kind: sectionHeatmap, Heatmap: hm}, heatmapHeight(smallFace, hm))
+	}
+
+	repoRows, authorRows := breakdownRows(report)
+	if opts.ByRepo {
+		addSection(cardSection{Kind: sectionRows, Rows: repoRows}, rowsSectionHeight(len(repoRows)))
+	}
+	if opts.ByAuthor {
+		addSection(cardSection{Kind: sectionRows, Rows: authorRows}, rowsSectionHeight(len(authorRows)))
+	}
+
+	l.Height = height
+	return l
+}
@@ -0,0 +1,49 @@
+package internal
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/image/font/basicfont"
+)
+
+func TestLayoutCard(t *testing.T) {
+	report := NewReport([]RepoStats{
+		NewRepoStats("/work/app", GitStats{FilesChanged: 3, Insertions: 11, Deletions: 1, BinaryFiles: 1, Commits: 2, Languages: map[string]int{"Go": 12}}),
+		NewRepoStats("/work/api", GitStats{FilesChanged: 1, Insertions: 4}),
+	})
+	report.DateRange = "Since Jan 1, 2025"
+	fg := color.RGBA{0, 0, 0, 255}
+	face := basicfont.Face7x13
+
+	l := layoutCard(face, face, 800, 800, fg, report, &RunOptions{Lang: true, ByRepo: true})
+
+	var texts []string
+	var ys []int
+	for _, line := range l.Lines {
+		texts = append(texts, line.Text)
+		ys = append(ys, line.Y)
+		assert.Equal(t, (800-7*len(line.Text))/2, line.X, "line %q is centered", line.Text)
+	}
+	assert.Equal(t, []string{
+		"Since Jan 1, 2025",
+		" 4 files changed",
+		"15 insertions(+)",
+		" 1 deletions(-) ",
+		" 1 binary file  ",
+		"2 commits on 0 days",
+	}, texts)
+	assert.Equal(t, []int{280, 380, 430, 480, 530, 580}, ys)
+
+	// The language bar uses the space of the taller card, the repositories
+	// are added below it
+	assert.Equal(t, []cardSection{
+		{Kind: sectionLanguages, Y: 660},
+		{Kind: sectionRows, Y: 800, Rows: []pngRow{
+			{Label: "app", Insertions: 11, Deletions: 1},
+			{Label: "api", Insertions: 4},
+		}},
+	}, l.Sections)
+	assert.Equal(t, 950+50+rowsSectionHeight(2), l.Height)
+}
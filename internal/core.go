@@ -1,10 +1,15 @@
 package internal
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/radulucut/gitbrag/internal/utils"
@@ -23,29 +28,153 @@ func NewCore(time utils.Time, printer *Printer) *Core {
 }
 
 type RunOptions struct {
-	Dirs         []string
-	Since        time.Time
-	Until        time.Time
-	DateRange    string
-	Author       string
-	Output       string
-	Background   string
-	Color        string
-	Lang         bool
-	ExcludeFiles *regexp.Regexp
-	ExcludeDirs  *regexp.Regexp
+	Dirs           []string
+	Format         string // registered renderer, chosen by the output file extension when empty
+	Since          time.Time
+	Until          time.Time
+	PeriodLabel    string         // label of the period of Since and Until, such as "Q3 2025", the dates when empty
+	DateField      string         // DateCommitter or DateAuthor, the date of the commits filtered and grouped by, DateCommitter when empty
+	Location       *time.Location // time zone of the period label and of the days commits are grouped in, the one of the dates and time.Local when nil
+	AuthorTime     bool           // group commits into days in the time zone of their author instead
+	Authors        []string       // author patterns, the commits of any of them are counted
+	Output         string
+	Background     string
+	Color          string
+	Lang           bool
+	Heatmap        string // HeatmapCommits or HeatmapLines, empty to disable
+	ByRepo         bool
+	ByAuthor       bool
+	ExcludeFiles   *regexp.Regexp
+	ExcludeDirs    *regexp.Regexp
+	ExcludeAuthors []string // author patterns whose commits are not counted
+	AuthorsFile    string   // file with one author identity per line whose commits are counted
+	Coauthors      string   // CoauthorsCredit, CoauthorsSplit or CoauthorsIgnore, co-authors are ignored when empty
+	Include        []string // gitignore-style patterns of the files to count, all files when empty
+	Exclude        []string // gitignore-style patterns of the files not to count, after .gitbragignore
+	CacheDir       string   // directory of the commit cache, empty to disable caching
+	Jobs           int      // repositories scanned in parallel, the number of CPUs when not set
+	Backend        string   // BackendExec or BackendNative, BackendExec when empty
+	Refs           []string // ref selections such as RefsBranches or globs, RefsBranches when empty
+	Dedupe         bool     // count cherry-picked and rebased copies of a change once
+	Merges         string   // MergesInclude, MergesExclude or MergesFirstParent, merges are counted without their changes when empty
+	Moved          bool     // count the lines kept by renamed and copied files separately
+	BinaryBytes    bool     // count the size change of binary files
+	Generated      bool     // also count generated, vendored and documentation files
+	MailmapFile    string   // mailmap applied to every repository after their own .mailmap, empty for none
+	NoMailmap      bool     // ignore the .mailmap of the repositories
 }
 
-func (c *Core) Run(opts *RunOptions) error {
-	if len(opts.Dirs) == 0 {
-		return utils.NewInternalError("no directories specified")
+func (c *Core) Run(ctx context.Context, opts *RunOptions) error {
+	format := opts.Format
+	switch {
+	case format == "" && opts.Output != "":
+		format = formatForFile(opts.Output)
+	case format == "":
+		format = FormatText
+	case opts.Output != "":
+		// An explicit format wins over the extension, as long as they agree
+		if fileFormat, ok := rendererExtensions[outputExtension(opts.Output)]; ok && fileFormat != format {
+			return fmt.Errorf("output file %s has the extension of the %s format, not %s", opts.Output, fileFormat, format)
+		}
+	}
+	renderer, err := NewRenderer(format, opts)
+	if err != nil {
+		return err
+	}
+
+	report, err := c.Collect(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	// Write to the standard output unless an output file is requested
+	if opts.Output == "" {
+		if err := renderer.Render(c.printer.OutWriter, report, opts); err != nil {
+			return fmt.Errorf("failed to write %s: %w", strings.ToUpper(format), err)
+		}
+		return nil
 	}
 
-	totalStats := &GitStats{}
+	// Images of nothing are not exported, JSON documents always are
+	if report.Total.Repositories == 0 && format != FormatJSON {
+		c.printer.Println("No git repositories found in the specified directories.")
+		return nil
+	}
+
+	f, err := os.Create(opts.Output)
+	if err != nil {
+		return fmt.Errorf("failed to export %s: failed to create file: %w", strings.ToUpper(format), err)
+	}
+	if err := renderer.Render(f, report, opts); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to export %s: %w", strings.ToUpper(format), err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to export %s: %w", strings.ToUpper(format), err)
+	}
+	c.printer.Printf("Statistics exported to %s\n", opts.Output)
+	return nil
+}
+
+// Collect scans the directories for git repositories and builds the report
+// of the requested period
+func (c *Core) Collect(ctx context.Context, opts *RunOptions) (*Report, error) {
+	if len(opts.Dirs) == 0 {
+		return nil, utils.NewInternalError("no directories specified")
+	}
+	if opts.Backend != "" && !slices.Contains(Backends(), opts.Backend) {
+		return nil, fmt.Errorf("unsupported backend: %s", opts.Backend)
+	}
+	if opts.Merges != "" && !slices.Contains(MergePolicies(), opts.Merges) {
+		return nil, fmt.Errorf("invalid merges: %s, must be one of %s", opts.Merges, strings.Join(MergePolicies(), ", "))
+	}
+	if opts.DateField != "" && !slices.Contains(DateFields(), opts.DateField) {
+		return nil, fmt.Errorf("invalid date-field: %s, must be one of %s", opts.DateField, strings.Join(DateFields(), ", "))
+	}
+	if opts.Coauthors != "" && !slices.Contains(CoauthorsModes(), opts.Coauthors) {
+		return nil, fmt.Errorf("invalid coauthors: %s, must be one of %s", opts.Coauthors, strings.Join(CoauthorsModes(), ", "))
+	}
+	if err := ValidateRefs(opts.Refs); err != nil {
+		return nil, err
+	}
+	var identities []authorIdentity
+	if opts.AuthorsFile != "" {
+		var err error
+		if identities, err = readAuthorsFile(opts.AuthorsFile); err != nil {
+			return nil, fmt.Errorf("failed to read authors file: %w", err)
+		}
+	}
+	authors, err := newAuthorFilter(opts.Authors, opts.ExcludeAuthors, identities)
+	if err != nil {
+		return nil, err
+	}
+	var globalMailmap *mailmap
+	if opts.MailmapFile != "" {
+		if globalMailmap, err = readMailmap(opts.MailmapFile); err != nil {
+			return nil, fmt.Errorf("failed to read mailmap: %w", err)
+		}
+	}
 
 	gitOpts := &GitStatsOptions{
-		Author:       opts.Author,
+		DateField:    opts.DateField,
+		Location:     opts.Location,
+		AuthorTime:   opts.AuthorTime,
+		Authors:      authors,
+		Coauthors:    opts.Coauthors,
+		Mailmap:      globalMailmap,
+		NoMailmap:    opts.NoMailmap,
 		ExcludeFiles: opts.ExcludeFiles,
+		Include:      opts.Include,
+		Exclude:      opts.Exclude,
+		ByAuthor:     opts.ByAuthor,
+		CacheDir:     opts.CacheDir,
+		Backend:      opts.Backend,
+		Refs:         opts.Refs,
+		Dedupe:       opts.Dedupe,
+		Merges:       opts.Merges,
+		Moved:        opts.Moved,
+		BinaryBytes:  opts.BinaryBytes,
+		Generated:    opts.Generated,
 	}
 	if !opts.Since.IsZero() {
 		gitOpts.Since = opts.Since.Format(time.RFC3339)
@@ -54,68 +183,150 @@ func (c *Core) Run(opts *RunOptions) error {
 		gitOpts.Until = opts.Until.Format(time.RFC3339)
 	}
 
-	// Process each directory
+	// Find the repositories first so that they can be scanned in parallel
+	search := &repoSearch{excludeDirs: opts.ExcludeDirs}
 	for _, dir := range opts.Dirs {
-		c.processDirectory(dir, gitOpts, totalStats, opts.ExcludeDirs)
+		c.processDirectory(ctx, dir, search)
 	}
-
-	// Output results
-	if totalStats.Repositories == 0 {
-		c.printer.Println("No git repositories found in the specified directories.")
-		return nil
+	repos := c.collectRepos(ctx, search, gitOpts, opts.Jobs)
+	if opts.CacheDir != "" {
+		cache := &commitCache{dir: opts.CacheDir}
+		cache.prune(cacheMaxAge)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	report := NewReport(repos)
+	since, until, now := opts.Since, opts.Until, c.time.Now()
+	if loc := opts.Location; loc != nil {
+		since, until, now = inLocation(since, loc), inLocation(until, loc), now.In(loc)
+	}
+	report.SetPeriod(since, until, now)
+	if opts.PeriodLabel != "" {
+		report.DateRange = opts.PeriodLabel
 	}
+	report.RefSelection = opts.Refs
+	if len(report.RefSelection) == 0 {
+		report.RefSelection = []string{RefsBranches}
+	}
+
+	return report, nil
+}
+
+// repoPath is a git repository found while walking the directories
+type repoPath struct {
+	Path string // absolute path
+	Name string // path shown in warnings, as given or as found
+}
 
-	if !opts.Since.IsZero() && !opts.Until.IsZero() {
-		opts.DateRange = fmt.Sprintf("%s - %s", formatOutputDate(opts.Since), formatOutputDate(opts.Until))
-	} else if !opts.Since.IsZero() {
-		opts.DateRange = fmt.Sprintf("Since %s", formatOutputDate(opts.Since))
-	} else if !opts.Until.IsZero() {
-		opts.DateRange = fmt.Sprintf("Until %s", formatOutputDate(opts.Until))
+// repoSearch holds the repositories found while walking the directories
+type repoSearch struct {
+	paths       []repoPath
+	pending     []pendingDirs
+	excludeDirs *regexp.Regexp
+}
+
+// pendingDirs are the subdirectories of a directory with repositories, they
+// are only searched when none of the repositories can be read
+type pendingDirs struct {
+	repos []int // indexes of the repositories in repoSearch.paths
+	dirs  []string
+}
+
+// collectRepos runs git log on the repositories with at most jobs workers.
+// The results and warnings are in the order the repositories were found,
+// whatever order the workers finish in.
+func (c *Core) collectRepos(ctx context.Context, search *repoSearch, gitOpts *GitStatsOptions, jobs int) []RepoStats {
+	if jobs <= 0 {
+		jobs = runtime.NumCPU()
 	}
 
-	// Check if PNG output is requested
-	if opts.Output != "" {
-		pngRenderer := NewPNGRenderer()
-		if opts.Background != "" {
-			if err := pngRenderer.SetBackgroundFromHex(opts.Background); err != nil {
-				return fmt.Errorf("invalid background color: %w", err)
+	var commits [][]commit
+	var refs [][]string
+	var errs []error
+	for read := 0; read < len(search.paths); {
+		commits = append(commits, make([][]commit, len(search.paths)-read)...)
+		refs = append(refs, make([][]string, len(search.paths)-read)...)
+		errs = append(errs, make([]error, len(search.paths)-read)...)
+		c.readRepos(ctx, search.paths[read:], gitOpts, jobs, commits[read:], refs[read:], errs[read:])
+		read = len(search.paths)
+
+		// Search the subdirectories next to repositories that all failed, which
+		// may find more repositories to read
+		pending := search.pending
+		search.pending = nil
+		for _, p := range pending {
+			if ctx.Err() != nil {
+				break
 			}
-		}
-		if opts.Color != "" {
-			if err := pngRenderer.SetForegroundFromHex(opts.Color); err != nil {
-				return fmt.Errorf("invalid text color: %w", err)
+			if slices.ContainsFunc(p.repos, func(i int) bool { return errs[i] == nil }) {
+				continue
+			}
+			for _, dir := range p.dirs {
+				c.processSubdirectories(ctx, dir, search)
 			}
 		}
-		if err := pngRenderer.RenderToFile(totalStats, opts); err != nil {
-			return fmt.Errorf("failed to export PNG: %w", err)
-		}
-		c.printer.Printf("Statistics exported to %s\n", opts.Output)
-		return nil
 	}
+	paths := search.paths
 
-	// Print date range if available
-	if opts.DateRange != "" {
-		c.printer.Printf("%s\n\n", opts.DateRange)
+	// Copies of a patch are dropped once all repositories are read, so that the
+	// kept copy doesn't depend on the order the workers finish in
+	if gitOpts.Dedupe {
+		commits = dropDuplicatePatches(commits)
 	}
 
-	filesStr := fmt.Sprint(totalStats.FilesChanged)
-	insertionsStr := fmt.Sprint(totalStats.Insertions)
-	deletionsStr := fmt.Sprint(totalStats.Deletions)
-
-	maxLen := max(len(filesStr), len(insertionsStr), len(deletionsStr))
-
-	filesStr = fmt.Sprintf("%*s", maxLen, filesStr)
-	insertionsStr = fmt.Sprintf("%*s", maxLen, insertionsStr)
-	deletionsStr = fmt.Sprintf("%*s", maxLen, deletionsStr)
-	c.printer.Printf(`%s files changed
-%s insertions(+)
-%s deletions(-)
-`, filesStr, insertionsStr, deletionsStr)
+	repos := make([]RepoStats, 0, len(paths))
+	for i, path := range paths {
+		if errs[i] != nil {
+			c.printer.ErrPrintf("Warning: could not get git stats for '%s': %v\n", path.Name, errs[i])
+			continue
+		}
+		// Authors are mapped to their canonical identity before they are matched and grouped
+		var repoMailmap *mailmap
+		if !gitOpts.NoMailmap {
+			var err error
+			if repoMailmap, err = readRepoMailmap(path.Path); err != nil {
+				c.printer.ErrPrintf("Warning: could not read the .mailmap of '%s': %v\n", path.Name, err)
+			}
+		}
+		mapAuthors(commits[i], repoMailmap, gitOpts.Mailmap)
+		repo := NewRepoStats(path.Path, aggregateCommits(commits[i], gitOpts, newFileFilter(path.Path, gitOpts)))
+		repo.Refs = refs[i]
+		repos = append(repos, repo)
+	}
+	return repos
+}
 
-	return nil
+// readRepos reads the commits of the repositories with at most jobs workers,
+// into the slices at the same indexes
+func (c *Core) readRepos(ctx context.Context, paths []repoPath, gitOpts *GitStatsOptions, jobs int, commits [][]commit, refs [][]string, errs []error) {
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range min(jobs, len(paths)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				commits[i], refs[i], errs[i] = getGitCommits(ctx, paths[i].Path, gitOpts)
+			}
+		}()
+	}
+	for i := range paths {
+		if ctx.Err() != nil {
+			break
+		}
+		next <- i
+	}
+	close(next)
+	wg.Wait()
 }
 
-func (c *Core) processDirectory(dir string, gitOpts *GitStatsOptions, totalStats *GitStats, excludeDirs *regexp.Regexp) {
+func (c *Core) processDirectory(ctx context.Context, dir string, search *repoSearch) {
+	// Stop scanning once the run is canceled
+	if ctx.Err() != nil {
+		return
+	}
+
 	// Convert to absolute path
 	absDir, err := filepath.Abs(dir)
 	if err != nil {
@@ -137,19 +348,13 @@ func (c *Core) processDirectory(dir string, gitOpts *GitStatsOptions, totalStats
 
 	// Check if it's a git repository
 	if isGitRepo(absDir) {
-		stats, err := getGitStats(absDir, gitOpts)
-		if err != nil {
-			c.printer.ErrPrintf("Warning: could not get git stats for '%s': %v\n", dir, err)
-			return
-		}
-		totalStats.Add(stats)
-		totalStats.Repositories++
+		search.paths = append(search.paths, repoPath{Path: absDir, Name: dir})
 	} else {
-		c.processSubdirectories(absDir, gitOpts, totalStats, excludeDirs)
+		c.processSubdirectories(ctx, absDir, search)
 	}
 }
 
-func (c *Core) processSubdirectories(dir string, opts *GitStatsOptions, totalStats *GitStats, excludeDirs *regexp.Regexp) {
+func (c *Core) processSubdirectories(ctx context.Context, dir string, search *repoSearch) {
 	entries, err := os.ReadDir(dir)
 	if err != nil {
 		c.printer.ErrPrintf("Warning: could not read directory '%s': %v\n", dir, err)
@@ -157,8 +362,11 @@ func (c *Core) processSubdirectories(dir string, opts *GitStatsOptions, totalSta
 	}
 
 	var nextDirs []string
-	gitDirFound := false
+	var repos []int
 	for _, entry := range entries {
+		if ctx.Err() != nil {
+			return
+		}
 		if !entry.IsDir() {
 			continue
 		}
@@ -169,32 +377,37 @@ func (c *Core) processSubdirectories(dir string, opts *GitStatsOptions, totalSta
 		}
 
 		// Skip directories matching exclude pattern
-		if excludeDirs != nil && excludeDirs.MatchString(entry.Name()) {
+		if search.excludeDirs != nil && search.excludeDirs.MatchString(entry.Name()) {
 			continue
 		}
 
 		subDir := filepath.Join(dir, entry.Name())
 
 		if isGitRepo(subDir) {
-			stats, err := getGitStats(subDir, opts)
-			if err != nil {
-				c.printer.ErrPrintf("Warning: could not get git stats for '%s': %v\n", subDir, err)
-				continue
-			}
-			totalStats.Add(stats)
-			totalStats.Repositories++
-			gitDirFound = true
+			repos = append(repos, len(search.paths))
+			search.paths = append(search.paths, repoPath{Path: subDir, Name: subDir})
 		} else {
 			nextDirs = append(nextDirs, subDir)
 		}
 	}
 
-	// if no git directory found, process subdirectories
-	if !gitDirFound {
+	// if no git directory found, process subdirectories, otherwise only once
+	// none of the repositories found can be read
+	if len(repos) == 0 {
 		for _, subDir := range nextDirs {
-			c.processSubdirectories(subDir, opts, totalStats, excludeDirs)
+			c.processSubdirectories(ctx, subDir, search)
 		}
+	} else if len(nextDirs) > 0 {
+		search.pending = append(search.pending, pendingDirs{repos: repos, dirs: nextDirs})
+	}
+}
+
+// inLocation returns t in the time zone, zero times stay zero
+func inLocation(t time.Time, loc *time.Location) time.Time {
+	if t.IsZero() {
+		return t
 	}
+	return t.In(loc)
 }
 
 func formatOutputDate(t time.Time) string {
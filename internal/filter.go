@@ -0,0 +1,60 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// ignoreFile is the file at the root of a repository with the patterns of the
+// files not to count, in the syntax of .gitignore
+const ignoreFile = ".gitbragignore"
+
+// fileFilter decides which changed files of a repository are counted
+type fileFilter struct {
+	include    pathPatterns   // GitStatsOptions.Include, all files when empty
+	exclude    pathPatterns   // the ignoreFile of the repository, then GitStatsOptions.Exclude
+	excludeRe  *regexp.Regexp // GitStatsOptions.ExcludeFiles
+	attributes *gitAttributes // nil when generated files are counted
+}
+
+// newFileFilter returns the filter of the repository in dir
+func newFileFilter(dir string, opts *GitStatsOptions) *fileFilter {
+	f := &fileFilter{excludeRe: opts.ExcludeFiles}
+	for _, pattern := range opts.Include {
+		f.include = append(f.include, parsePathPattern(pattern, ""))
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, ignoreFile)); err == nil {
+		f.exclude = parseIgnore(data)
+	}
+	for _, pattern := range opts.Exclude {
+		f.exclude = append(f.exclude, parsePathPattern(pattern, ""))
+	}
+	if !opts.Generated {
+		f.attributes = newGitAttributes(dir)
+	}
+	return f
+}
+
+// counted reports whether the changes of a file are counted, all files are
+// counted without a filter. A file is counted when it matches the include
+// patterns, if there are any, and none of the exclusions: the exclude
+// patterns, the exclude regexp and the generated files.
+func (f *fileFilter) counted(path string) bool {
+	if f == nil {
+		return true
+	}
+	if len(f.include) > 0 && !f.include.match(path) {
+		return false
+	}
+	if f.exclude.match(path) {
+		return false
+	}
+	if f.excludeRe != nil && f.excludeRe.MatchString(path) {
+		return false
+	}
+	if f.attributes != nil && f.attributes.generated(path) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,45 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileFilter(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ignoreFile), []byte("*.md\nscripts/\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	filter := newFileFilter(dir, &GitStatsOptions{
+		Include:      []string{"src/**", "*.md", "!src/legacy/"},
+		Exclude:      []string{"!README.md", "**/*_test.go"},
+		ExcludeFiles: regexp.MustCompile(`\.tmp$`),
+	})
+	tests := []struct {
+		path    string
+		counted bool
+	}{
+		{"src/main.go", true},
+		{"src/main_test.go", false},
+		{"src/legacy/old.go", false},
+		{"src/data.tmp", false},
+		{"src/package-lock.json", false},
+		{"docs/guide.md", false},
+		{"README.md", true},
+		{"scripts/build.sh", false},
+		{"main.go", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.counted, filter.counted(tt.path), tt.path)
+	}
+
+	// Generated files are counted on request, the patterns still apply
+	filter = newFileFilter(dir, &GitStatsOptions{Generated: true})
+	assert.True(t, filter.counted("package-lock.json"))
+	assert.False(t, filter.counted("docs/guide.md"))
+}
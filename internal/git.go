@@ -1,28 +1,82 @@
 package internal
 
 import (
+	"context"
+	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/radulucut/gitbrag/internal/gitobj"
 	"github.com/radulucut/gitbrag/internal/utils"
 )
 
 type GitStats struct {
 	Repositories int
-	FilesChanged int
+	Commits      int
+	FilesChanged int // a file renamed in the period counts once
 	Insertions   int
 	Deletions    int
-	Languages    map[string]int // Lines of code per language
+	MovedLines   int // lines kept by renamed and copied files, only counted with GitStatsOptions.Moved
+	BinaryFiles  int // files changed that git considers binary, they have no line counts
+	BinaryBytes  int // size change of the binary files, only counted with GitStatsOptions.BinaryBytes
+	FirstCommit  time.Time
+	LastCommit   time.Time
+	Days         map[string]DayStats     // Activity per day (YYYY-MM-DD)
+	Languages    map[string]int          // Lines of code per language
+	Authors      map[string]*AuthorStats // Keyed by author identity, only set when grouping by author
+}
+
+// DayStats holds the activity of a single day
+type DayStats struct {
+	Commits int
+	Lines   int // inserted and deleted lines
+}
+
+// AuthorStats holds the statistics of a single author
+type AuthorStats struct {
+	Name  string
+	Email string
+	Stats GitStats
+}
+
+// Identity returns the author formatted as "Name <email>"
+func (a *AuthorStats) Identity() string {
+	if a.Email == "" {
+		return a.Name
+	}
+	return fmt.Sprintf("%s <%s>", a.Name, a.Email)
 }
 
 func (g *GitStats) Add(other GitStats) {
+	g.Commits += other.Commits
 	g.FilesChanged += other.FilesChanged
 	g.Insertions += other.Insertions
 	g.Deletions += other.Deletions
+	g.MovedLines += other.MovedLines
+	g.BinaryFiles += other.BinaryFiles
+	g.BinaryBytes += other.BinaryBytes
+
+	if !other.FirstCommit.IsZero() && (g.FirstCommit.IsZero() || other.FirstCommit.Before(g.FirstCommit)) {
+		g.FirstCommit = other.FirstCommit
+	}
+	if other.LastCommit.After(g.LastCommit) {
+		g.LastCommit = other.LastCommit
+	}
+
+	if g.Days == nil {
+		g.Days = make(map[string]DayStats)
+	}
+	for key, day := range other.Days {
+		existing := g.Days[key]
+		existing.Commits += day.Commits
+		existing.Lines += day.Lines
+		g.Days[key] = existing
+	}
 
 	if g.Languages == nil {
 		g.Languages = make(map[string]int)
@@ -30,6 +84,67 @@ func (g *GitStats) Add(other GitStats) {
 	for lang, lines := range other.Languages {
 		g.Languages[lang] += lines
 	}
+
+	for key, author := range other.Authors {
+		if g.Authors == nil {
+			g.Authors = make(map[string]*AuthorStats)
+		}
+		existing, ok := g.Authors[key]
+		if !ok {
+			existing = &AuthorStats{
+				Name:  author.Name,
+				Email: author.Email,
+			}
+			g.Authors[key] = existing
+		}
+		existing.Stats.Add(author.Stats)
+	}
+}
+
+// ActiveDays returns the number of distinct days with at least one commit
+func (g *GitStats) ActiveDays() int {
+	return len(g.Days)
+}
+
+// addCommit counts a commit made at the given time
+func (g *GitStats) addCommit(date time.Time) {
+	g.Commits++
+
+	if g.FirstCommit.IsZero() || date.Before(g.FirstCommit) {
+		g.FirstCommit = date
+	}
+	if date.After(g.LastCommit) {
+		g.LastCommit = date
+	}
+
+	if g.Days == nil {
+		g.Days = make(map[string]DayStats)
+	}
+	key := dayKey(date)
+	day := g.Days[key]
+	day.Commits++
+	g.Days[key] = day
+}
+
+// addDayLines counts lines changed on the day of the given time
+func (g *GitStats) addDayLines(date time.Time, lines int) {
+	if g.Days == nil {
+		g.Days = make(map[string]DayStats)
+	}
+	key := dayKey(date)
+	day := g.Days[key]
+	day.Lines += lines
+	g.Days[key] = day
+}
+
+// dayKey returns the key of the day of the given time in GitStats.Days
+func dayKey(date time.Time) string {
+	return date.Format(time.DateOnly)
+}
+
+// LinesChanged returns the number of inserted and deleted lines
+func (g *GitStats) LinesChanged() int {
+	return g.Insertions + g.Deletions
 }
 
 // isGitRepo checks if a directory is a git repository
@@ -45,39 +160,251 @@ func isGitRepo(dir string) bool {
 type GitStatsOptions struct {
 	Since        string
 	Until        string
-	Author       string
+	DateField    string         // DateCommitter or DateAuthor, the date Since, Until and the days of the commits are read from, DateCommitter when empty
+	Location     *time.Location // time zone commits are grouped into days in, time.Local when nil
+	AuthorTime   bool           // group commits into days in the time zone of their author instead
+	Authors      *authorFilter  // commits counted by their author, all commits when nil
+	Coauthors    string         // CoauthorsCredit, CoauthorsSplit or CoauthorsIgnore, CoauthorsIgnore when empty
+	Mailmap      *mailmap       // identities of the authors in every repository, applied after the .mailmap of the repository
+	NoMailmap    bool           // ignore the .mailmap of the repositories
 	ExcludeFiles *regexp.Regexp
+	Include      []string // gitignore-style patterns of the files to count, see fileFilter
+	Exclude      []string // gitignore-style patterns of the files not to count, see fileFilter
+	ByAuthor     bool
+	CacheDir     string   // directory of the commit cache, empty to always read the history
+	Backend      string   // BackendExec or BackendNative, BackendExec when empty
+	Refs         []string // ref selections such as RefsBranches or globs, RefsBranches when empty
+	Dedupe       bool     // count cherry-picked and rebased copies of a change once, see patchID
+	Merges       string   // MergesInclude, MergesExclude or MergesFirstParent, see the constants for the default
+	Moved        bool     // count the lines kept by renamed and copied files in GitStats.MovedLines
+	BinaryBytes  bool     // count the size change of binary files in GitStats.BinaryBytes
+	Generated    bool     // also count the generated, vendored and documentation files, see gitAttributes.generated
 }
 
-func getGitStats(dir string, opts *GitStatsOptions) (GitStats, error) {
-	stats := GitStats{
-		Languages: make(map[string]int),
-	}
+// Dates of a commit. Rebasing and cherry-picking a commit change its committer
+// date, the date git log --since and --until filter on, but keep its author date.
+const (
+	DateCommitter = "committer" // when the commit was last committed, rebased or cherry-picked
+	DateAuthor    = "author"    // when the change was first written
+)
+
+// DateFields returns the supported dates of commits
+func DateFields() []string {
+	return []string{DateCommitter, DateAuthor}
+}
+
+// Merge policies. By default merges are counted as commits without their
+// changes, like git log shows them, so the lines merged from other branches
+// are only counted once, in the commits of those branches.
+const (
+	MergesInclude     = "include"      // also count the changes of merges against their first parent
+	MergesExclude     = "exclude"      // skip merges, like git log --no-merges
+	MergesFirstParent = "first-parent" // follow only the first parent of merges, what landed on the mainline
+)
+
+// MergePolicies returns the supported merge policies
+func MergePolicies() []string {
+	return []string{MergesInclude, MergesExclude, MergesFirstParent}
+}
+
+// Attribution modes of the co-authors of commits, recorded in their
+// Co-authored-by trailers. The mode applies to author filters and grouping,
+// the totals of all authors are the same with every mode.
+const (
+	CoauthorsCredit = "credit" // co-authors are credited with the whole commit, like its author
+	CoauthorsSplit  = "split"  // the lines of the commit are split evenly between its author and co-authors
+	CoauthorsIgnore = "ignore" // commits are attributed to their author only
+)
+
+// CoauthorsModes returns the supported attribution modes of co-authors
+func CoauthorsModes() []string {
+	return []string{CoauthorsCredit, CoauthorsSplit, CoauthorsIgnore}
+}
+
+// commit is a single commit parsed from the git log output
+type commit struct {
+	Hash        string
+	AuthorName  string
+	AuthorEmail string
+	Date        time.Time // committer date, the same date used by --since and --until
+	AuthorDate  time.Time // when the change was written, kept by rebases and cherry-picks
+	Files       []fileChange
+	PatchID     string   `json:",omitempty"` // identity of the change, only set with GitStatsOptions.Dedupe
+	AuthorZone  int      `json:",omitempty"` // offset of the time zone of the author from UTC in seconds
+	CoAuthors   []string `json:",omitempty"` // "Name <email>" of the Co-authored-by trailers
+
+	raw   []rawChange // objects of the changed files, only read with GitStatsOptions.Dedupe
+	merge bool        // has several parents, merges have no patch identity
+}
+
+// fileChange is a single numstat entry of a commit
+type fileChange struct {
+	Path       string
+	OldPath    string `json:",omitempty"` // path the file was renamed or copied from
+	Insertions int
+	Deletions  int
+	Moved      int `json:",omitempty"` // lines kept from OldPath, only set with GitStatsOptions.Moved
+	Binary     bool
+	SizeDelta  int `json:",omitempty"` // size change of a binary file in bytes, only set with GitStatsOptions.BinaryBytes
+}
 
+// Each commit starts with a header line prefixed by the record separator,
+// with its fields separated by the unit separator. The last field holds the
+// co-authors, separated by the group separator.
+const (
+	logRecordSeparator  = "\x1e"
+	logFieldSeparator   = "\x1f"
+	logTrailerSeparator = "\x1d"
+	logFormat           = "--pretty=tformat:%x1e%H%x1f%an%x1f%ae%x1f%ct%x1f%P%x1f%ai%x1f%(trailers:key=" + coauthorTrailer + ",valueonly,unfold,separator=%x1d)"
+)
+
+// getGitCommits returns the commits of a repository and the names of the
+// refs they were read from
+func getGitCommits(ctx context.Context, dir string, opts *GitStatsOptions) ([]commit, []string, error) {
 	// Check if directory exists
 	if _, err := os.Stat(dir); err != nil {
-		return stats, utils.NewInternalError("directory does not exist: " + dir)
+		return nil, nil, utils.NewInternalError("directory does not exist: " + dir)
 	}
 
 	// Check if it's a git repo
 	if !isGitRepo(dir) {
-		return stats, utils.NewInternalError("not a git repository: " + dir)
+		return nil, nil, utils.NewInternalError("not a git repository: " + dir)
 	}
 
-	// Build git log command with shortstat
-	args := []string{"log", "--pretty=", "--numstat", "--branches"}
+	refs, err := resolveRefs(ctx, dir, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	since, _, err := parsePeriod(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var commits []commit
+	if opts.CacheDir != "" {
+		cache := &commitCache{dir: opts.CacheDir}
+		commits, err = cache.commits(ctx, dir, opts, refs)
+	} else {
+		commits, err = readCommits(ctx, dir, opts, refs, uncachedBounds(opts, since))
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	commits, err = filterDates(commits, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return commits, refNames(refs), nil
+}
+
+// uncachedBounds limits the history read without the cache to the period,
+// when it starts at a committer date: the history is walked from the most
+// recently committed commits, like git log --since does
+func uncachedBounds(opts *GitStatsOptions, since time.Time) *logBounds {
+	if opts.DateField == DateAuthor {
+		return nil
+	}
+	return &logBounds{since: since}
+}
+
+// parsePeriod parses GitStatsOptions.Since and Until, zero when not set
+func parsePeriod(opts *GitStatsOptions) (time.Time, time.Time, error) {
+	var since, until time.Time
+	var err error
 	if opts.Since != "" {
-		args = append(args, "--since="+opts.Since)
+		if since, err = time.Parse(time.RFC3339, opts.Since); err != nil {
+			return since, until, utils.NewInternalError("invalid since date: " + opts.Since)
+		}
 	}
 	if opts.Until != "" {
-		args = append(args, "--until="+opts.Until)
+		if until, err = time.Parse(time.RFC3339, opts.Until); err != nil {
+			return since, until, utils.NewInternalError("invalid until date: " + opts.Until)
+		}
+	}
+	return since, until, nil
+}
+
+// filterDates keeps the commits whose date of GitStatsOptions.DateField is in
+// the period. The cached history serves any period and the history read
+// without it is only bounded by the committer date, so the dates are always
+// filtered after the commits are read, the same way for both backends.
+func filterDates(commits []commit, opts *GitStatsOptions) ([]commit, error) {
+	since, until, err := parsePeriod(opts)
+	if err != nil {
+		return nil, err
+	}
+	if since.IsZero() && until.IsZero() {
+		return commits, nil
+	}
+	var kept []commit
+	for _, c := range commits {
+		date := c.Date
+		if opts.DateField == DateAuthor {
+			date = c.AuthorDate
+		}
+		if !since.IsZero() && date.Before(since) || !until.IsZero() && date.After(until) {
+			continue
+		}
+		kept = append(kept, c)
+	}
+	return kept, nil
+}
+
+// readGitLog runs git log from the refs and parses the commits
+func readGitLog(ctx context.Context, dir string, opts *GitStatsOptions, refs []gitRef, bounds *logBounds) ([]commit, error) {
+	if err := checkNotRewound(ctx, dir, refs, bounds.from); err != nil {
+		return nil, err
 	}
-	if opts.Author != "" {
-		args = append(args, "--author="+opts.Author)
+	// Without refs git log would read HEAD
+	if len(refs) == 0 {
+		return nil, nil
 	}
 
-	cmd := exec.Command("git", args...)
+	// Build git log command with numstat and a header per commit. The options
+	// that change paths or line counts are pinned, so that the user configuration
+	// doesn't change the statistics and the native backend gives the same results.
+	// Renames and copies are detected like gitobj.Repository.DiffTrees does, and
+	// lines are counted with the algorithm gitobj.CountLines reproduces.
+	args := []string{
+		"-c", "core.quotepath=off",
+		"-c", "log.showRoot=true",
+		"-c", "trailer.separators=:",
+		"-c", "core.commentChar=#",
+		"log", logFormat, "--numstat", "--stdin",
+		"--find-copies", "-l1000", "--no-textconv", "--no-show-signature",
+		"--diff-algorithm=myers",
+	}
+	switch opts.Merges {
+	case MergesInclude:
+		args = append(args, "--diff-merges=first-parent")
+	case MergesExclude:
+		args = append(args, "--no-merges")
+	case MergesFirstParent:
+		args = append(args, "--first-parent", "--diff-merges=first-parent")
+	}
+	if opts.Dedupe || opts.Moved || opts.BinaryBytes {
+		// The objects of the changed files, to compute the identity of each
+		// patch, the lines kept by renamed files and the size of binary files
+		args = append(args, "--raw", "--no-abbrev")
+	}
+	if !bounds.since.IsZero() {
+		args = append(args, "--max-age="+strconv.FormatInt(bounds.since.Unix(), 10))
+	}
+
+	// The commits of the refs are passed on the standard input, there may be
+	// too many for the command line, followed by the commits already read
+	var stdin strings.Builder
+	for _, ref := range refs {
+		stdin.WriteString(ref.Hash + "\n")
+	}
+	for _, ref := range bounds.from {
+		stdin.WriteString("^" + ref.Hash + "\n")
+	}
+
+	cmd := exec.CommandContext(ctx, "git", args...)
 	cmd.Dir = dir
+	cmd.Stdin = strings.NewReader(stdin.String())
 
 	output, err := cmd.Output()
 	if err != nil {
@@ -86,67 +413,519 @@ func getGitStats(dir string, opts *GitStatsOptions) (GitStats, error) {
 			stderr := string(exitErr.Stderr)
 			if strings.Contains(stderr, "does not have any commits yet") ||
 				strings.Contains(stderr, "bad default revision") {
-				// Empty repository - return zero stats
-				return stats, nil
+				// Empty repository - no commits
+				return nil, nil
 			}
 		}
-		return stats, utils.NewInternalError("failed to execute git command: " + err.Error())
+		return nil, utils.NewInternalError("failed to execute git command: " + err.Error())
 	}
 
-	// Parse the output
-	lines := strings.Split(string(output), "\n")
-	filesMap := make(map[string]bool)
+	commits := parseGitLog(string(output))
+	if opts.Dedupe || opts.Moved {
+		if err := readGitFiles(ctx, dir, commits, opts); err != nil {
+			return nil, utils.NewInternalError("failed to read changed files: " + err.Error())
+		}
+	}
+	if opts.BinaryBytes {
+		if err := setGitSizeDeltas(ctx, dir, commits); err != nil {
+			return nil, utils.NewInternalError("failed to read binary files: " + err.Error())
+		}
+	}
+	for i := range commits {
+		commits[i].raw = nil
+	}
+	return commits, nil
+}
+
+// checkNotRewound returns errRefsRewound unless the history of the refs
+// contains the commits of from, also when some of them no longer exist
+func checkNotRewound(ctx context.Context, dir string, refs, from []gitRef) error {
+	if len(from) == 0 {
+		return nil
+	}
+	if len(refs) == 0 {
+		return errRefsRewound
+	}
+	var stdin strings.Builder
+	for _, ref := range from {
+		stdin.WriteString(ref.Hash + "\n")
+	}
+	for _, ref := range refs {
+		stdin.WriteString("^" + ref.Hash + "\n")
+	}
+	cmd := exec.CommandContext(ctx, "git", "rev-list", "--count", "--stdin")
+	cmd.Dir = dir
+	cmd.Stdin = strings.NewReader(stdin.String())
+	output, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return errRefsRewound
+	}
+	if strings.TrimSpace(string(output)) != "0" {
+		return errRefsRewound
+	}
+	return nil
+}
+
+// readGitFiles computes what needs the content of the changed files from the
+// objects printed by git log --raw, reading them with git cat-file: the
+// identity of the patch of each commit and the lines kept by renamed files
+func readGitFiles(ctx context.Context, dir string, commits []commit, opts *GitStatsOptions) error {
+	objects, err := newCatFile(ctx, dir, "--batch")
+	if err != nil {
+		return err
+	}
+	defer objects.Close()
+	for i := range commits {
+		c := &commits[i]
+		if opts.Dedupe && !c.merge {
+			c.PatchID, err = patchID(c.raw, objects.read)
+			if err != nil {
+				return err
+			}
+		}
+		if opts.Moved {
+			for j := range c.Files {
+				if err := setMovedLines(&c.Files[j], c.raw, objects.read); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// setGitSizeDeltas sets the size change of the binary files from the size of
+// their objects, without reading them
+func setGitSizeDeltas(ctx context.Context, dir string, commits []commit) error {
+	var objects *catFile
+	size := func(hash string) (int, error) {
+		if strings.Trim(hash, "0") == "" {
+			return 0, nil // the missing side of an added or deleted file
+		}
+		if objects == nil {
+			var err error
+			if objects, err = newCatFile(ctx, dir, "--batch-check"); err != nil {
+				return 0, err
+			}
+		}
+		return objects.size(hash)
+	}
+	defer func() {
+		if objects != nil {
+			objects.Close()
+		}
+	}()
+
+	for i := range commits {
+		c := &commits[i]
+		for j := range c.Files {
+			file := &c.Files[j]
+			if !file.Binary {
+				continue
+			}
+			for _, change := range c.raw {
+				if change.Path != file.Path || change.OldPath != file.OldPath {
+					continue
+				}
+				oldSize, err := size(change.OldHash)
+				if err != nil {
+					return err
+				}
+				newSize, err := size(change.NewHash)
+				if err != nil {
+					return err
+				}
+				file.SizeDelta = newSize - oldSize
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// setMovedLines counts the lines a renamed or copied file kept from its source
+func setMovedLines(file *fileChange, raw []rawChange, readBlob func(hash string) ([]byte, error)) error {
+	if file.OldPath == "" || file.Binary {
+		return nil
+	}
+	for _, change := range raw {
+		if change.Path != file.Path || change.OldPath != file.OldPath {
+			continue
+		}
+		data, err := readRawObject(change.OldMode, change.OldHash, readBlob)
+		if err != nil {
+			return err
+		}
+		file.Moved = gitobj.LineCount(data) - file.Deletions
+		return nil
+	}
+	return nil
+}
+
+// parseGitLog parses the output of git log with logFormat and --numstat
+func parseGitLog(output string) []commit {
+	var commits []commit
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasPrefix(line, logRecordSeparator) {
+			fields := strings.Split(strings.TrimPrefix(line, logRecordSeparator), logFieldSeparator)
+			if len(fields) < 5 {
+				continue
+			}
+			timestamp, _ := strconv.ParseInt(fields[3], 10, 64)
+			c := commit{
+				Hash:        fields[0],
+				AuthorName:  fields[1],
+				AuthorEmail: fields[2],
+				Date:        time.Unix(timestamp, 0),
+				merge:       len(strings.Fields(fields[4])) > 1,
+			}
+			if len(fields) > 5 {
+				// The author date ends with the time zone, such as +0200
+				date := fields[5]
+				zone := date[strings.LastIndexByte(date, ' ')+1:]
+				c.AuthorZone, _ = gitobj.ZoneOffset(zone)
+				if t, err := time.ParseInLocation(time.DateTime, strings.TrimSuffix(date, " "+zone), time.FixedZone("", c.AuthorZone)); err == nil {
+					c.AuthorDate = time.Unix(t.Unix(), 0)
+				}
+			}
+			if len(fields) > 6 {
+				for _, coauthor := range strings.Split(fields[6], logTrailerSeparator) {
+					if coauthor = strings.TrimSpace(coauthor); coauthor != "" {
+						c.CoAuthors = append(c.CoAuthors, coauthor)
+					}
+				}
+			}
+			commits = append(commits, c)
+			continue
+		}
+
+		if strings.HasPrefix(line, ":") && len(commits) > 0 {
+			if change, ok := parseRawChange(line); ok {
+				c := &commits[len(commits)-1]
+				c.raw = append(c.raw, change)
+			}
+			continue
+		}
 
-	for _, line := range lines {
 		line = strings.TrimSpace(line)
-		if line == "" {
+		if line == "" || len(commits) == 0 {
 			continue
 		}
 
-		parts := strings.Fields(line)
+		parts := strings.SplitN(line, "\t", 3)
 		if len(parts) < 3 {
 			continue
 		}
 
-		insertions := parts[0]
-		deletions := parts[1]
-		filename := strings.Join(parts[2:], " ")
+		change := fileChange{}
+		change.OldPath, change.Path = parseNumstatPath(parts[2])
 
-		// Exclude files matching the regex pattern
-		if opts.ExcludeFiles != nil && opts.ExcludeFiles.MatchString(filename) {
-			continue
+		// Binary files are reported as "-" for both insertions and deletions
+		if parts[0] == "-" || parts[1] == "-" {
+			change.Binary = true
+		} else {
+			change.Insertions, _ = strconv.Atoi(parts[0])
+			change.Deletions, _ = strconv.Atoi(parts[1])
+		}
+
+		c := &commits[len(commits)-1]
+		c.Files = append(c.Files, change)
+	}
+	return commits
+}
+
+// parseNumstatPath returns the paths before and after a rename or copy, as
+// printed by git log --numstat: "old => new", or "dir/{old => new}/file" when
+// the paths have a common prefix or suffix. The old path is empty for other
+// files. Paths with control characters, quotes or backslashes are quoted like
+// Go strings, each side of a rename separately.
+func parseNumstatPath(path string) (string, string) {
+	if strings.HasPrefix(path, `"`) {
+		if quoted, err := strconv.QuotedPrefix(path); err == nil {
+			oldPath, _ := strconv.Unquote(quoted)
+			if newPath, ok := strings.CutPrefix(path[len(quoted):], " => "); ok {
+				return oldPath, unquotePath(newPath)
+			}
+			return "", oldPath
+		}
+	}
+
+	oldPath, newPath, ok := strings.Cut(path, " => ")
+	if !ok {
+		return "", path
+	}
+	start := strings.LastIndexByte(oldPath, '{')
+	end := strings.IndexByte(newPath, '}')
+	if start < 0 || end < 0 {
+		return oldPath, unquotePath(newPath)
+	}
+	prefix, suffix := oldPath[:start], newPath[end+1:]
+	return joinRenamePath(prefix, oldPath[start+1:], suffix), joinRenamePath(prefix, newPath[:end], suffix)
+}
+
+// joinRenamePath rebuilds a path from the parts of "prefix{middle => ...}suffix",
+// the slash is printed on both sides of an empty middle, e.g. "dir/{ => sub}/file"
+func joinRenamePath(prefix, middle, suffix string) string {
+	if middle == "" && strings.HasSuffix(prefix, "/") {
+		return prefix + strings.TrimPrefix(suffix, "/")
+	}
+	return prefix + middle + suffix
+}
+
+// unquotePath unquotes a path quoted like a Go string
+func unquotePath(path string) string {
+	if strings.HasPrefix(path, `"`) {
+		if unquoted, err := strconv.Unquote(path); err == nil {
+			return unquoted
 		}
+	}
+	return path
+}
 
-		// Track unique files
-		filesMap[filename] = true
+// aggregateCommits sums up the commits of a single repository, counting the
+// files the filter keeps. A commit is counted when one of the authors it is
+// attributed to passes the author filter, and only their share of it is.
+func aggregateCommits(commits []commit, opts *GitStatsOptions, filter *fileFilter) GitStats {
+	stats := GitStats{
+		Languages: make(map[string]int),
+	}
+	filesMap := make(map[string]bool)
+	binaryFiles := make(map[string]bool)
+	renames := renamedFiles(commits)
 
-		// Detect language from file extension
-		lang := detectLanguage(filename)
-		var totalLines int
+	var authorFiles, authorBinaryFiles map[string]map[string]bool
+	if opts.ByAuthor {
+		stats.Authors = make(map[string]*AuthorStats)
+		authorFiles = make(map[string]map[string]bool)
+		authorBinaryFiles = make(map[string]map[string]bool)
+	}
 
-		// Parse insertions
-		if insertions != "-" {
-			if n, err := strconv.Atoi(insertions); err == nil {
-				stats.Insertions += n
-				totalLines += n
+	for _, c := range commits {
+		authors := commitAuthors(c, opts.Coauthors, opts.Authors)
+		var counted []int // indexes of the authors that pass the filter
+		for i, author := range authors {
+			if opts.Authors.included(author.name, author.email) {
+				counted = append(counted, i)
 			}
 		}
+		if len(counted) == 0 {
+			continue
+		}
+		date := commitDate(c, opts)
+		stats.addCommit(date)
 
-		// Parse deletions
-		if deletions != "-" {
-			if n, err := strconv.Atoi(deletions); err == nil {
-				stats.Deletions += n
-				totalLines += n
+		var keys []string
+		if opts.ByAuthor {
+			for _, i := range counted {
+				key := authorKey(authors[i].name, authors[i].email)
+				author := stats.Authors[key]
+				if author == nil {
+					author = &AuthorStats{
+						Name:  authors[i].name,
+						Email: authors[i].email,
+						Stats: GitStats{
+							Languages: make(map[string]int),
+						},
+					}
+					stats.Authors[key] = author
+					authorFiles[key] = make(map[string]bool)
+					authorBinaryFiles[key] = make(map[string]bool)
+				}
+				author.Stats.addCommit(date)
+				keys = append(keys, key)
 			}
 		}
 
-		// Track language statistics
-		if lang != "" && totalLines > 0 {
-			stats.Languages[lang] += totalLines
+		split := opts.Coauthors == CoauthorsSplit && len(authors) > 1
+		for _, file := range c.Files {
+			if !filter.counted(file.Path) {
+				continue
+			}
+
+			// Track unique files, following renames
+			identity := renames.identity(file.Path)
+			filesMap[identity] = true
+			if file.Binary {
+				binaryFiles[identity] = true
+			}
+
+			shares := []fileChange{file}
+			if split {
+				shares = splitFileChange(file, len(authors))
+				total := fileChange{Path: file.Path}
+				for _, i := range counted {
+					total = addShare(total, shares[i])
+				}
+				addFileChange(&stats, total, date)
+			} else {
+				addFileChange(&stats, file, date)
+			}
+			for k, key := range keys {
+				authorFiles[key][identity] = true
+				if file.Binary {
+					authorBinaryFiles[key][identity] = true
+				}
+				share := file
+				if split {
+					share = shares[counted[k]]
+				}
+				addFileChange(&stats.Authors[key].Stats, share, date)
+			}
 		}
 	}
 
 	stats.FilesChanged = len(filesMap)
+	stats.BinaryFiles = len(binaryFiles)
+	for key, author := range stats.Authors {
+		author.Stats.FilesChanged = len(authorFiles[key])
+		author.Stats.BinaryFiles = len(authorBinaryFiles[key])
+	}
+
+	return stats
+}
+
+// commitDate returns the date of a commit selected by GitStatsOptions.DateField,
+// in the time zone its day is counted in, the one of its author with
+// GitStatsOptions.AuthorTime
+func commitDate(c commit, opts *GitStatsOptions) time.Time {
+	date := c.Date
+	if opts.DateField == DateAuthor {
+		date = c.AuthorDate
+	}
+	if opts.AuthorTime {
+		return date.In(time.FixedZone("", c.AuthorZone))
+	}
+	if opts.Location != nil {
+		return date.In(opts.Location)
+	}
+	return date
+}
+
+// commitAuthors returns the authors a commit is attributed to, its author
+// then its co-authors unless they are ignored, each once. Excluded authors
+// are left out, they neither get nor take a share of the commit.
+func commitAuthors(c commit, mode string, filter *authorFilter) []authorIdentity {
+	var authors []authorIdentity
+	seen := make(map[string]bool)
+	add := func(id authorIdentity) {
+		key := authorKey(id.name, id.email)
+		if seen[key] || filter.excluded(id.name, id.email) {
+			return
+		}
+		seen[key] = true
+		authors = append(authors, id)
+	}
+	add(authorIdentity{name: c.AuthorName, email: c.AuthorEmail})
+	if mode == CoauthorsCredit || mode == CoauthorsSplit {
+		for _, coauthor := range c.CoAuthors {
+			add(parseAuthorIdentity(coauthor))
+		}
+	}
+	return authors
+}
 
-	return stats, nil
+// splitFileChange splits the line counts of a file change evenly between n
+// authors. The shares are whole lines and add up to the change, the first
+// authors get the remainder.
+func splitFileChange(file fileChange, n int) []fileChange {
+	shares := make([]fileChange, n)
+	for i := range shares {
+		shares[i] = file
+		shares[i].Insertions = splitShare(file.Insertions, i, n)
+		shares[i].Deletions = splitShare(file.Deletions, i, n)
+		shares[i].Moved = splitShare(file.Moved, i, n)
+		shares[i].SizeDelta = splitShare(file.SizeDelta, i, n)
+	}
+	return shares
+}
+
+// splitShare returns the share of the i-th of n authors of a count
+func splitShare(count, i, n int) int {
+	if count < 0 {
+		return -splitShare(-count, i, n)
+	}
+	share := count / n
+	if i < count%n {
+		share++
+	}
+	return share
+}
+
+// addShare adds the line counts of a share of a file change to another
+func addShare(total, share fileChange) fileChange {
+	total.Insertions += share.Insertions
+	total.Deletions += share.Deletions
+	total.Moved += share.Moved
+	total.SizeDelta += share.SizeDelta
+	return total
+}
+
+// addFileChange adds the line counts of a file change committed at the given time to the stats
+func addFileChange(stats *GitStats, file fileChange, date time.Time) {
+	stats.Insertions += file.Insertions
+	stats.Deletions += file.Deletions
+	stats.MovedLines += file.Moved
+	stats.BinaryBytes += file.SizeDelta
+
+	// Track daily and language statistics
+	totalLines := file.Insertions + file.Deletions
+	if totalLines > 0 {
+		stats.addDayLines(date, totalLines)
+	}
+	if lang := detectLanguage(file.Path); lang != "" && totalLines > 0 {
+		stats.Languages[lang] += totalLines
+	}
+}
+
+// fileRenames groups the paths a file had, so that a renamed file counts once
+type fileRenames map[string]string
+
+// renamedFiles returns the renames of the commits. Copies are separate files,
+// the source of a copy is still there after the commit.
+func renamedFiles(commits []commit) fileRenames {
+	renames := make(fileRenames)
+	for _, c := range commits {
+		var paths map[string]bool
+		for _, file := range c.Files {
+			if file.OldPath == "" {
+				continue
+			}
+			if paths == nil {
+				paths = make(map[string]bool, len(c.Files))
+				for _, f := range c.Files {
+					paths[f.Path] = true
+				}
+			}
+			if paths[file.OldPath] {
+				continue
+			}
+			oldIdentity, newIdentity := renames.identity(file.OldPath), renames.identity(file.Path)
+			if oldIdentity != newIdentity {
+				renames[oldIdentity] = newIdentity
+			}
+		}
+	}
+	return renames
+}
+
+// identity returns the path that stands for all the paths of a file
+func (r fileRenames) identity(path string) string {
+	for {
+		next, ok := r[path]
+		if !ok {
+			return path
+		}
+		path = next
+	}
+}
+
+// authorKey identifies an author by email, falling back to the name
+func authorKey(name, email string) string {
+	if email != "" {
+		return strings.ToLower(email)
+	}
+	return name
 }
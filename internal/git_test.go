@@ -0,0 +1,235 @@
+package internal
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseNumstatPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		oldPath string
+		newPath string
+	}{
+		{"main.go", "", "main.go"},
+		{`"tab\tname.txt"`, "", "tab\tname.txt"},
+		{"old.go => new.go", "old.go", "new.go"},
+		{"{src/app => lib}/a.go", "src/app/a.go", "lib/a.go"},
+		{"src/{a.go => b.go}", "src/a.go", "src/b.go"},
+		{"x/{ => y}/f", "x/f", "x/y/f"},
+		{"x/{y => }/f", "x/y/f", "x/f"},
+		{`"tab\tname.txt" => "tabs/tab\tname.txt"`, "tab\tname.txt", "tabs/tab\tname.txt"},
+		{`a.txt => "quote\".txt"`, "a.txt", `quote".txt`},
+		{"docs/{naïve.md => naïve.txt}", "docs/naïve.md", "docs/naïve.txt"},
+	}
+	for _, tt := range tests {
+		oldPath, newPath := parseNumstatPath(tt.path)
+		assert.Equal(t, tt.oldPath, oldPath, tt.path)
+		assert.Equal(t, tt.newPath, newPath, tt.path)
+	}
+}
+
+func TestAggregateCommits_Renames(t *testing.T) {
+	date := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	commits := []commit{
+		{Hash: "4", Date: date, Files: []fileChange{
+			{Path: "c.go", Insertions: 1},
+		}},
+		// A copy is a new file, its source is still there
+		{Hash: "3", Date: date, Files: []fileChange{
+			{Path: "b.go", Insertions: 1},
+			{Path: "copy.go", OldPath: "b.go", Insertions: 1, Moved: 9},
+		}},
+		{Hash: "2", Date: date, Files: []fileChange{
+			{Path: "b.go", OldPath: "a.go", Moved: 10},
+		}},
+		{Hash: "1", Date: date, Files: []fileChange{
+			{Path: "a.go", Insertions: 10},
+		}},
+	}
+
+	stats := aggregateCommits(commits, &GitStatsOptions{}, nil)
+	assert.Equal(t, 3, stats.FilesChanged)
+	assert.Equal(t, 13, stats.Insertions)
+	assert.Equal(t, 19, stats.MovedLines)
+}
+
+func TestAggregateCommits_Coauthors(t *testing.T) {
+	date := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	commits := []commit{
+		{Hash: "2", AuthorName: "Jane Roe", AuthorEmail: "jane@example.com", Date: date, Files: []fileChange{
+			{Path: "b.go", Insertions: 4},
+		}},
+		{Hash: "1", AuthorName: "John Doe", AuthorEmail: "john@example.com", Date: date,
+			CoAuthors: []string{"Jane Roe <JANE@example.com>", "Bob <bob@example.com>", "John <john@example.com>"},
+			Files: []fileChange{
+				{Path: "a.go", Insertions: 10, Deletions: 5},
+			}},
+	}
+
+	tests := []struct {
+		name       string
+		coauthors  string
+		authors    *authorFilter
+		commits    int
+		insertions int
+		deletions  int
+		byAuthor   map[string][3]int // commits, insertions and deletions by author
+	}{
+		{"ignore", CoauthorsIgnore, nil, 2, 14, 5, map[string][3]int{
+			"jane@example.com": {1, 4, 0},
+			"john@example.com": {1, 10, 5},
+		}},
+		{"credit", CoauthorsCredit, nil, 2, 14, 5, map[string][3]int{
+			"jane@example.com": {2, 14, 5},
+			"john@example.com": {1, 10, 5},
+			"bob@example.com":  {1, 10, 5},
+		}},
+		{"split", CoauthorsSplit, nil, 2, 14, 5, map[string][3]int{
+			"jane@example.com": {2, 7, 2},
+			"john@example.com": {1, 4, 2},
+			"bob@example.com":  {1, 3, 1},
+		}},
+		{"ignore author", CoauthorsIgnore, mustAuthorFilter("bob"), 0, 0, 0, map[string][3]int{}},
+		{"credit author", CoauthorsCredit, mustAuthorFilter("bob"), 1, 10, 5, map[string][3]int{
+			"bob@example.com": {1, 10, 5},
+		}},
+		{"split author", CoauthorsSplit, mustAuthorFilter("bob"), 1, 3, 1, map[string][3]int{
+			"bob@example.com": {1, 3, 1},
+		}},
+		{"split authors", CoauthorsSplit, mustAuthorFilter("bob", "john"), 1, 7, 3, map[string][3]int{
+			"john@example.com": {1, 4, 2},
+			"bob@example.com":  {1, 3, 1},
+		}},
+		{"split excluded", CoauthorsSplit, mustExcludeAuthorFilter("jane"), 1, 10, 5, map[string][3]int{
+			"john@example.com": {1, 5, 3},
+			"bob@example.com":  {1, 5, 2},
+		}},
+	}
+	for _, tt := range tests {
+		stats := aggregateCommits(commits, &GitStatsOptions{Coauthors: tt.coauthors, Authors: tt.authors, ByAuthor: true}, nil)
+		assert.Equal(t, tt.commits, stats.Commits, tt.name)
+		assert.Equal(t, tt.insertions, stats.Insertions, tt.name)
+		assert.Equal(t, tt.deletions, stats.Deletions, tt.name)
+		byAuthor := make(map[string][3]int)
+		for key, author := range stats.Authors {
+			byAuthor[key] = [3]int{author.Stats.Commits, author.Stats.Insertions, author.Stats.Deletions}
+		}
+		assert.Equal(t, tt.byAuthor, byAuthor, tt.name)
+	}
+}
+
+func TestSplitShare(t *testing.T) {
+	tests := []struct {
+		count  int
+		n      int
+		shares []int
+	}{
+		{9, 3, []int{3, 3, 3}},
+		{10, 3, []int{4, 3, 3}},
+		{2, 3, []int{1, 1, 0}},
+		{-5, 2, []int{-3, -2}},
+		{0, 2, []int{0, 0}},
+	}
+	for _, tt := range tests {
+		var shares []int
+		for i := range tt.n {
+			shares = append(shares, splitShare(tt.count, i, tt.n))
+		}
+		assert.Equal(t, tt.shares, shares, tt.count)
+	}
+}
+
+func TestAggregateCommits_TimeZones(t *testing.T) {
+	// Late in the evening in New York, early the next day in UTC and Bucharest
+	commits := []commit{
+		{Hash: "2", Date: time.Date(2025, 3, 11, 3, 30, 0, 0, time.UTC), AuthorZone: -4 * 60 * 60},
+		{Hash: "1", Date: time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC), AuthorZone: 2 * 60 * 60},
+	}
+	days := func(stats GitStats) []string {
+		var keys []string
+		for key := range stats.Days {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		return keys
+	}
+
+	stats := aggregateCommits(commits, &GitStatsOptions{}, nil)
+	assert.Equal(t, []string{"2025-03-10", "2025-03-11"}, days(stats))
+
+	stats = aggregateCommits(commits, &GitStatsOptions{Location: time.FixedZone("EET", 2*60*60)}, nil)
+	assert.Equal(t, []string{"2025-03-10", "2025-03-11"}, days(stats))
+	assert.Equal(t, "2025-03-11T05:30:00+02:00", stats.LastCommit.Format(time.RFC3339))
+
+	stats = aggregateCommits(commits, &GitStatsOptions{Location: time.FixedZone("PDT", -7*60*60)}, nil)
+	assert.Equal(t, []string{"2025-03-10"}, days(stats))
+
+	stats = aggregateCommits(commits, &GitStatsOptions{Location: time.UTC, AuthorTime: true}, nil)
+	assert.Equal(t, []string{"2025-03-10"}, days(stats))
+	assert.Equal(t, "2025-03-10T23:30:00-04:00", stats.LastCommit.Format(time.RFC3339))
+	assert.Equal(t, "2025-03-10T14:00:00+02:00", stats.FirstCommit.Format(time.RFC3339))
+}
+
+func TestAggregateCommits_DateField(t *testing.T) {
+	// Written on Friday and rebased on Monday
+	commits := []commit{
+		{Hash: "1", Date: time.Date(2025, 3, 17, 9, 0, 0, 0, time.UTC), AuthorDate: time.Date(2025, 3, 14, 16, 0, 0, 0, time.UTC), AuthorZone: 2 * 60 * 60},
+	}
+
+	stats := aggregateCommits(commits, &GitStatsOptions{Location: time.UTC}, nil)
+	assert.Equal(t, "2025-03-17T09:00:00Z", stats.LastCommit.Format(time.RFC3339))
+	assert.Contains(t, stats.Days, "2025-03-17")
+
+	stats = aggregateCommits(commits, &GitStatsOptions{Location: time.UTC, DateField: DateAuthor}, nil)
+	assert.Equal(t, "2025-03-14T16:00:00Z", stats.LastCommit.Format(time.RFC3339))
+	assert.Contains(t, stats.Days, "2025-03-14")
+
+	stats = aggregateCommits(commits, &GitStatsOptions{DateField: DateAuthor, AuthorTime: true}, nil)
+	assert.Equal(t, "2025-03-14T18:00:00+02:00", stats.LastCommit.Format(time.RFC3339))
+}
+
+func TestFilterDates(t *testing.T) {
+	commits := []commit{
+		{Hash: "written and committed last week", Date: time.Date(2025, 3, 12, 9, 0, 0, 0, time.UTC), AuthorDate: time.Date(2025, 3, 12, 9, 0, 0, 0, time.UTC)},
+		{Hash: "written last week, rebased this week", Date: time.Date(2025, 3, 17, 9, 0, 0, 0, time.UTC), AuthorDate: time.Date(2025, 3, 14, 16, 0, 0, 0, time.UTC)},
+		{Hash: "written before, rebased last week", Date: time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC), AuthorDate: time.Date(2025, 3, 7, 16, 0, 0, 0, time.UTC)},
+	}
+	hashes := func(commits []commit) []string {
+		var hashes []string
+		for _, c := range commits {
+			hashes = append(hashes, c.Hash)
+		}
+		return hashes
+	}
+	lastWeek := GitStatsOptions{Since: "2025-03-10T00:00:00Z", Until: "2025-03-16T23:59:59Z"}
+
+	tests := []struct {
+		name      string
+		dateField string
+		expected  []string
+	}{
+		{"default", "", []string{"written and committed last week", "written before, rebased last week"}},
+		{"committer", DateCommitter, []string{"written and committed last week", "written before, rebased last week"}},
+		{"author", DateAuthor, []string{"written and committed last week", "written last week, rebased this week"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := lastWeek
+			opts.DateField = tt.dateField
+			kept, err := filterDates(commits, &opts)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, hashes(kept))
+		})
+	}
+
+	kept, err := filterDates(commits, &GitStatsOptions{DateField: DateAuthor})
+	assert.NoError(t, err)
+	assert.Len(t, kept, 3)
+
+	_, err = filterDates(commits, &GitStatsOptions{Since: "last week"})
+	assert.EqualError(t, err, "invalid since date: last week")
+}
@@ -0,0 +1,659 @@
+package gitobj
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// FileChange is the line count of a changed file, as printed by git diff --numstat
+type FileChange struct {
+	Path       string
+	OldPath    string // path the file was renamed or copied from, empty otherwise
+	Insertions int
+	Deletions  int
+	Moved      int // lines of a renamed or copied file kept from its source
+	Binary     bool
+	OldSize    int    // size in bytes, zero for added files
+	NewSize    int    // size in bytes, zero for deleted files
+	OldMode    uint32 // zero for added files
+	NewMode    uint32 // zero for deleted files
+	OldHash    Hash   // zero for added files
+	NewHash    Hash   // zero for deleted files
+}
+
+// filePair is a file on both sides of a diff, either may be missing
+type filePair struct {
+	Path    string
+	OldPath string // set for renames and copies
+	Old     *TreeEntry
+	New     *TreeEntry
+}
+
+// DiffTrees compares two trees recursively and counts the lines changed in
+// each file. The zero hash is the empty tree, to diff the root commit.
+// Renames and copies are detected like git diff -C, see detectRenames.
+func (r *Repository) DiffTrees(oldTree, newTree Hash) ([]FileChange, error) {
+	var pairs []filePair
+	if err := r.diffTrees(oldTree, newTree, "", &pairs); err != nil {
+		return nil, err
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i].Path < pairs[j].Path
+	})
+
+	files := &fileContents{repo: r, data: make(map[Hash][]byte)}
+	pairs, err := detectRenames(pairs, files)
+	if err != nil {
+		return nil, err
+	}
+	changes := make([]FileChange, 0, len(pairs))
+	for _, p := range pairs {
+		change, err := countChange(p, files)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes, nil
+}
+
+func (r *Repository) diffTrees(oldTree, newTree Hash, prefix string, pairs *[]filePair) error {
+	if oldTree == newTree {
+		return nil
+	}
+	oldEntries, err := r.Tree(oldTree)
+	if err != nil {
+		return err
+	}
+	newEntries, err := r.Tree(newTree)
+	if err != nil {
+		return err
+	}
+
+	oldByName := make(map[string]TreeEntry, len(oldEntries))
+	for _, e := range oldEntries {
+		oldByName[e.Name] = e
+	}
+	for _, n := range newEntries {
+		o, ok := oldByName[n.Name]
+		if !ok {
+			if err := r.diffEntries(nil, &n, prefix+n.Name, pairs); err != nil {
+				return err
+			}
+			continue
+		}
+		delete(oldByName, n.Name)
+		if err := r.diffEntries(&o, &n, prefix+n.Name, pairs); err != nil {
+			return err
+		}
+	}
+	for _, o := range oldByName {
+		if err := r.diffEntries(&o, nil, prefix+o.Name, pairs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// diffEntries compares two entries of the same name, either may be missing
+func (r *Repository) diffEntries(o, n *TreeEntry, path string, pairs *[]filePair) error {
+	switch {
+	case o != nil && n != nil && o.Hash == n.Hash && o.Mode == n.Mode:
+		return nil
+	case o != nil && n != nil && o.IsTree() && n.IsTree():
+		return r.diffTrees(o.Hash, n.Hash, path+"/", pairs)
+	case o != nil && n != nil && o.IsTree() != n.IsTree():
+		// A file replaced by a directory or the reverse is a deletion and an addition
+		if err := r.diffEntries(o, nil, path, pairs); err != nil {
+			return err
+		}
+		return r.diffEntries(nil, n, path, pairs)
+	case o != nil && o.IsTree():
+		return r.diffTrees(o.Hash, ZeroHash, path+"/", pairs)
+	case n != nil && n.IsTree():
+		return r.diffTrees(ZeroHash, n.Hash, path+"/", pairs)
+	}
+	*pairs = append(*pairs, filePair{Path: path, Old: o, New: n})
+	return nil
+}
+
+// countChange counts the lines changed between the two sides of a pair
+func countChange(p filePair, files *fileContents) (FileChange, error) {
+	oldData, err := files.read(p.Old)
+	if err != nil {
+		return FileChange{}, err
+	}
+	newData, err := files.read(p.New)
+	if err != nil {
+		return FileChange{}, err
+	}
+	change := FileChange{Path: p.Path, OldPath: p.OldPath, OldSize: len(oldData), NewSize: len(newData)}
+	if p.Old != nil {
+		change.OldMode, change.OldHash = p.Old.Mode, p.Old.Hash
+	}
+	if p.New != nil {
+		change.NewMode, change.NewHash = p.New.Mode, p.New.Hash
+	}
+	if IsBinary(oldData) || IsBinary(newData) {
+		change.Binary = true
+		return change, nil
+	}
+	if p.Old == nil || p.New == nil || p.Old.Hash != p.New.Hash {
+		change.Insertions, change.Deletions = CountLines(oldData, newData)
+	}
+	if p.OldPath != "" {
+		change.Moved = LineCount(oldData) - change.Deletions
+	}
+	return change, nil
+}
+
+// fileContents reads the content of the files of a diff once
+type fileContents struct {
+	repo *Repository
+	data map[Hash][]byte
+}
+
+// read returns the content of a file or submodule, nil for a missing side
+func (f *fileContents) read(e *TreeEntry) ([]byte, error) {
+	if e == nil {
+		return nil, nil
+	}
+	if e.IsSubmodule() {
+		return SubmoduleContent(e.Hash), nil
+	}
+	if data, ok := f.data[e.Hash]; ok {
+		return data, nil
+	}
+	data, err := f.repo.Blob(e.Hash)
+	if err != nil {
+		return nil, err
+	}
+	f.data[e.Hash] = data
+	return data, nil
+}
+
+// SubmoduleContent is how git diffs a submodule entry, a single line with
+// the commit it points to
+func SubmoduleContent(h Hash) []byte {
+	return fmt.Appendf(nil, "Subproject commit %s\n", h)
+}
+
+// binaryCheckSize is how much of a file git looks at to decide it's binary
+const binaryCheckSize = 8000
+
+// IsBinary reports whether git considers a file binary, when it has a NUL byte
+// near the start
+func IsBinary(data []byte) bool {
+	if len(data) > binaryCheckSize {
+		data = data[:binaryCheckSize]
+	}
+	return bytes.IndexByte(data, 0) >= 0
+}
+
+// CountLines returns the number of lines inserted and deleted between two
+// versions of a file like git diff --numstat, see DiffLines. Lines include
+// their newline, so adding a newline at the end of a file changes its last
+// line.
+func CountLines(oldData, newData []byte) (int, int) {
+	insertions, deletions := 0, 0
+	DiffLines(oldData, newData, func(op byte, line []byte) {
+		if op == '+' {
+			insertions++
+		} else {
+			deletions++
+		}
+	})
+	return insertions, deletions
+}
+
+// DiffLines calls fn with the lines of the edit script turning oldData into
+// newData, '-' for the deleted lines and '+' for the inserted ones, in the
+// order of the file. The deleted lines of each change come before its
+// inserted lines, like in the hunks of git diff.
+//
+// The edit script is the one of the default algorithm of git diff, the Myers
+// algorithm with the heuristics of xdiff that bound its cost on files with
+// many changes, so the counts are the ones git log --numstat prints.
+func DiffLines(oldData, newData []byte, fn func(op byte, line []byte)) {
+	ids := make(map[string]int)
+	oldLines, oldIDs := splitLines(oldData, ids)
+	newLines, newIDs := splitLines(newData, ids)
+	oldChanged, newChanged := diffIDs(oldIDs, newIDs, len(ids))
+
+	// Unchanged lines are paired in order, the changes are between them
+	i, j := 0, 0
+	for i < len(oldLines) || j < len(newLines) {
+		if i < len(oldLines) && j < len(newLines) && !oldChanged[i] && !newChanged[j] {
+			i++
+			j++
+			continue
+		}
+		for ; i < len(oldLines) && oldChanged[i]; i++ {
+			fn('-', oldLines[i])
+		}
+		for ; j < len(newLines) && newChanged[j]; j++ {
+			fn('+', newLines[j])
+		}
+	}
+}
+
+// Tuning of the heuristics, the values of xdiff
+const (
+	diffMaxEqLimit     = 1024 // lines found more often on the other side are "multimatch" lines
+	diffSimscanWindow  = 100  // lines around a multimatch line looked at to discard it
+	diffKeepDiscardRun = 4    // ratio of discarded to multimatch lines to discard them
+	diffMaxCostMin     = 256  // minimum edit cost after which the search stops
+	diffHeurMinCost    = 256  // edit cost after which long snakes are taken as split points
+	diffSnakeCount     = 20   // length of a snake that is long enough
+	diffHeurK          = 4    // how much further than the cost a snake must have gone to be taken
+)
+
+// diffIDs marks the lines of two sequences of line IDs that are changed, the
+// way xdiff does: the lines common to the start and the end are unchanged, the
+// lines found only on the other side, or found too often and surrounded by
+// such lines, are changed, and the other lines are compared with the linear
+// space variant of the Myers algorithm, which splits the sequences at the
+// middle snake and recurses on both halves.
+func diffIDs(oldIDs, newIDs []int, classes int) ([]bool, []bool) {
+	d := &xdiff{
+		old: diffFile{ids: oldIDs, changed: make([]bool, len(oldIDs))},
+		new: diffFile{ids: newIDs, changed: make([]bool, len(newIDs))},
+	}
+	d.trimEnds()
+	d.cleanupRecords(classes)
+
+	ndiags := len(d.old.kept) + len(d.new.kept) + 3
+	d.vf = make([]int, ndiags)
+	d.vb = make([]int, ndiags)
+	d.base = len(d.new.kept) + 1
+	d.maxCost = max(bogosqrt(ndiags), diffMaxCostMin)
+	d.compare(0, len(d.old.kept), 0, len(d.new.kept), false)
+	return d.old.changed, d.new.changed
+}
+
+// diffFile is a side of a diff
+type diffFile struct {
+	ids     []int  // line IDs
+	changed []bool // lines that are not on the common subsequence
+	start   int    // first line after the lines common to the start of both sides
+	end     int    // last line before the lines common to the end of both sides
+	kept    []int  // IDs of the lines left to compare
+	index   []int  // index in ids of each kept line
+}
+
+type xdiff struct {
+	old, new diffFile
+	vf, vb   []int // furthest line of the old side reached on each diagonal, forward and backward
+	base     int   // index of diagonal 0 in vf and vb
+	maxCost  int   // edit cost after which the search takes the furthest reaching path
+}
+
+// bogosqrt is the integer square root approximation of xdiff
+func bogosqrt(n int) int {
+	i := 1
+	for ; n > 0; n >>= 2 {
+		i <<= 1
+	}
+	return i
+}
+
+// trimEnds skips the lines common to the start and the end of both sides
+func (d *xdiff) trimEnds() {
+	a, b := d.old.ids, d.new.ids
+	i, lim := 0, min(len(a), len(b))
+	for i < lim && a[i] == b[i] {
+		i++
+	}
+	d.old.start, d.new.start = i, i
+	lim -= i
+	i = 0
+	for i < lim && a[len(a)-1-i] == b[len(b)-1-i] {
+		i++
+	}
+	d.old.end = len(a) - i - 1
+	d.new.end = len(b) - i - 1
+}
+
+// cleanupRecords keeps the lines to compare. Lines found only on one side
+// are changed. Lines found many times on the other side are changed too when
+// they are surrounded by lines that are changed, so that blank lines and
+// braces don't make the search expensive.
+func (d *xdiff) cleanupRecords(classes int) {
+	oldCounts := make([]int, classes)
+	for _, id := range d.old.ids {
+		oldCounts[id]++
+	}
+	newCounts := make([]int, classes)
+	for _, id := range d.new.ids {
+		newCounts[id]++
+	}
+	d.old.cleanup(newCounts)
+	d.new.cleanup(oldCounts)
+}
+
+// cleanup keeps the lines of a side to compare, given how many times each
+// line is found on the other side
+func (f *diffFile) cleanup(otherCounts []int) {
+	// 0 for no match, 1 for a match and 2 for too many matches
+	dis := make([]byte, len(f.ids))
+	mlim := min(bogosqrt(len(f.ids)), diffMaxEqLimit)
+	for i := f.start; i <= f.end; i++ {
+		switch nm := otherCounts[f.ids[i]]; {
+		case nm == 0:
+			dis[i] = 0
+		case nm >= mlim:
+			dis[i] = 2
+		default:
+			dis[i] = 1
+		}
+	}
+	for i := f.start; i <= f.end; i++ {
+		if dis[i] == 1 || (dis[i] == 2 && !cleanMultimatch(dis, i, f.start, f.end)) {
+			f.kept = append(f.kept, f.ids[i])
+			f.index = append(f.index, i)
+		} else {
+			f.changed[i] = true
+		}
+	}
+}
+
+// cleanMultimatch reports whether the multimatch line i is in the middle of
+// a run of lines without match, looking at most diffSimscanWindow lines away
+func cleanMultimatch(dis []byte, i, s, e int) bool {
+	if i-s > diffSimscanWindow {
+		s = i - diffSimscanWindow
+	}
+	if e-i > diffSimscanWindow {
+		e = i + diffSimscanWindow
+	}
+
+	// The runs before and after the line must have lines without match
+	rdis0, rpdis0 := 0, 1
+	for r := 1; i-r >= s; r++ {
+		if dis[i-r] == 0 {
+			rdis0++
+		} else if dis[i-r] == 2 {
+			rpdis0++
+		} else {
+			break
+		}
+	}
+	if rdis0 == 0 {
+		return false
+	}
+	rdis1, rpdis1 := 0, 1
+	for r := 1; i+r <= e; r++ {
+		if dis[i+r] == 0 {
+			rdis1++
+		} else if dis[i+r] == 2 {
+			rpdis1++
+		} else {
+			break
+		}
+	}
+	if rdis1 == 0 {
+		return false
+	}
+	rdis1 += rdis0
+	rpdis1 += rpdis0
+	return rpdis1*diffKeepDiscardRun < rpdis1+rdis1
+}
+
+// compare marks the kept lines of old.kept[off1:lim1] and new.kept[off2:lim2]
+// that are changed, needMin disables the heuristics
+func (d *xdiff) compare(off1, lim1, off2, lim2 int, needMin bool) {
+	a, b := d.old.kept, d.new.kept
+	for off1 < lim1 && off2 < lim2 && a[off1] == b[off2] {
+		off1++
+		off2++
+	}
+	for off1 < lim1 && off2 < lim2 && a[lim1-1] == b[lim2-1] {
+		lim1--
+		lim2--
+	}
+	switch {
+	case off1 == lim1:
+		for ; off2 < lim2; off2++ {
+			d.new.changed[d.new.index[off2]] = true
+		}
+	case off2 == lim2:
+		for ; off1 < lim1; off1++ {
+			d.old.changed[d.old.index[off1]] = true
+		}
+	default:
+		s := d.split(off1, lim1, off2, lim2, needMin)
+		d.compare(off1, s.i1, off2, s.i2, s.minLo)
+		d.compare(s.i1, lim1, s.i2, lim2, s.minHi)
+	}
+}
+
+// diffSplit is where a box is split, and whether each half needs a minimal diff
+type diffSplit struct {
+	i1, i2       int
+	minLo, minHi bool
+}
+
+// split finds the middle snake of the box by searching from both corners. When
+// the cost gets high it settles for a long snake or the furthest reaching path.
+func (d *xdiff) split(off1, lim1, off2, lim2 int, needMin bool) diffSplit {
+	a, b := d.old.kept, d.new.kept
+	kvdf, kvdb := d.vf, d.vb
+	base := d.base
+	dmin, dmax := off1-lim2, lim1-off2
+	fmid, bmid := off1-off2, lim1-lim2
+	odd := (fmid-bmid)&1 != 0
+	fmin, fmax := fmid, fmid
+	bmin, bmax := bmid, bmid
+
+	kvdf[base+fmid] = off1
+	kvdb[base+bmid] = lim1
+
+	for ec := 1; ; ec++ {
+		gotSnake := false
+
+		// Extend the forward diagonals by one, or shrink them at the box edges
+		if fmin > dmin {
+			fmin--
+			kvdf[base+fmin-1] = -1
+		} else {
+			fmin++
+		}
+		if fmax < dmax {
+			fmax++
+			kvdf[base+fmax+1] = -1
+		} else {
+			fmax--
+		}
+
+		for k := fmax; k >= fmin; k -= 2 {
+			var i1 int
+			if kvdf[base+k-1] >= kvdf[base+k+1] {
+				i1 = kvdf[base+k-1] + 1
+			} else {
+				i1 = kvdf[base+k+1]
+			}
+			prev1 := i1
+			i2 := i1 - k
+			for i1 < lim1 && i2 < lim2 && a[i1] == b[i2] {
+				i1++
+				i2++
+			}
+			if i1-prev1 > diffSnakeCount {
+				gotSnake = true
+			}
+			kvdf[base+k] = i1
+			if odd && bmin <= k && k <= bmax && kvdb[base+k] <= i1 {
+				return diffSplit{i1: i1, i2: i2, minLo: true, minHi: true}
+			}
+		}
+
+		// Same for the backward diagonals
+		if bmin > dmin {
+			bmin--
+			kvdb[base+bmin-1] = math.MaxInt
+		} else {
+			bmin++
+		}
+		if bmax < dmax {
+			bmax++
+			kvdb[base+bmax+1] = math.MaxInt
+		} else {
+			bmax--
+		}
+
+		for k := bmax; k >= bmin; k -= 2 {
+			var i1 int
+			if kvdb[base+k-1] < kvdb[base+k+1] {
+				i1 = kvdb[base+k-1]
+			} else {
+				i1 = kvdb[base+k+1] - 1
+			}
+			prev1 := i1
+			i2 := i1 - k
+			for i1 > off1 && i2 > off2 && a[i1-1] == b[i2-1] {
+				i1--
+				i2--
+			}
+			if prev1-i1 > diffSnakeCount {
+				gotSnake = true
+			}
+			kvdb[base+k] = i1
+			if !odd && fmin <= k && k <= fmax && i1 <= kvdf[base+k] {
+				return diffSplit{i1: i1, i2: i2, minLo: true, minHi: true}
+			}
+		}
+
+		if needMin {
+			continue
+		}
+
+		// Past the heuristic trigger, a diagonal that went far from the
+		// corner without going far from the middle diagonal, and ends with a
+		// long snake, is a good enough split
+		if gotSnake && ec > diffHeurMinCost {
+			best := 0
+			var spl diffSplit
+			for k := fmax; k >= fmin; k -= 2 {
+				dd := k - fmid
+				if dd < 0 {
+					dd = -dd
+				}
+				i1 := kvdf[base+k]
+				i2 := i1 - k
+				v := (i1 - off1) + (i2 - off2) - dd
+				if v > diffHeurK*ec && v > best &&
+					off1+diffSnakeCount <= i1 && i1 < lim1 &&
+					off2+diffSnakeCount <= i2 && i2 < lim2 {
+					for n := 1; a[i1-n] == b[i2-n]; n++ {
+						if n == diffSnakeCount {
+							best = v
+							spl.i1, spl.i2 = i1, i2
+							break
+						}
+					}
+				}
+			}
+			if best > 0 {
+				spl.minLo = true
+				return spl
+			}
+
+			for k := bmax; k >= bmin; k -= 2 {
+				dd := k - bmid
+				if dd < 0 {
+					dd = -dd
+				}
+				i1 := kvdb[base+k]
+				i2 := i1 - k
+				v := (lim1 - i1) + (lim2 - i2) - dd
+				if v > diffHeurK*ec && v > best &&
+					off1 < i1 && i1 <= lim1-diffSnakeCount &&
+					off2 < i2 && i2 <= lim2-diffSnakeCount {
+					for n := 0; a[i1+n] == b[i2+n]; n++ {
+						if n == diffSnakeCount-1 {
+							best = v
+							spl.i1, spl.i2 = i1, i2
+							break
+						}
+					}
+				}
+			}
+			if best > 0 {
+				spl.minHi = true
+				return spl
+			}
+		}
+
+		// Enough is enough, take the furthest reaching path
+		if ec >= d.maxCost {
+			fbest, fbest1 := -1, -1
+			for k := fmax; k >= fmin; k -= 2 {
+				i1 := min(kvdf[base+k], lim1)
+				i2 := i1 - k
+				if lim2 < i2 {
+					i1 = lim2 + k
+					i2 = lim2
+				}
+				if fbest < i1+i2 {
+					fbest = i1 + i2
+					fbest1 = i1
+				}
+			}
+
+			bbest, bbest1 := math.MaxInt, math.MaxInt
+			for k := bmax; k >= bmin; k -= 2 {
+				i1 := max(off1, kvdb[base+k])
+				i2 := i1 - k
+				if i2 < off2 {
+					i1 = off2 + k
+					i2 = off2
+				}
+				if i1+i2 < bbest {
+					bbest = i1 + i2
+					bbest1 = i1
+				}
+			}
+
+			if (lim1+lim2)-bbest < fbest-(off1+off2) {
+				return diffSplit{i1: fbest1, i2: fbest - fbest1, minLo: true}
+			}
+			return diffSplit{i1: bbest1, i2: bbest - bbest1, minHi: true}
+		}
+	}
+}
+
+// splitLines splits data in lines, keeping the newlines, and numbers each distinct line
+func splitLines(data []byte, ids map[string]int) ([][]byte, []int) {
+	var lines [][]byte
+	var lineIDs []int
+	for len(data) > 0 {
+		end := bytes.IndexByte(data, '\n') + 1
+		if end == 0 {
+			end = len(data)
+		}
+		line := data[:end]
+		id, ok := ids[string(line)]
+		if !ok {
+			id = len(ids)
+			ids[string(line)] = id
+		}
+		lines = append(lines, line)
+		lineIDs = append(lineIDs, id)
+		data = data[end:]
+	}
+	return lines, lineIDs
+}
+
+// LineCount returns the number of lines of data, the last one may have no newline
+func LineCount(data []byte) int {
+	n := bytes.Count(data, []byte("\n"))
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,62 @@
+package gitobj
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountLines(t *testing.T) {
+	tests := []struct {
+		name       string
+		old, new   string
+		insertions int
+		deletions  int
+	}{
+		{"added file", "", "a\nb\n", 2, 0},
+		{"deleted file", "a\nb\n", "", 0, 2},
+		{"unchanged", "a\nb\n", "a\nb\n", 0, 0},
+		{"edited line", "a\nb\nc\n", "a\nB\nc\n", 1, 1},
+		{"missing newline added", "a\nb", "a\nb\n", 1, 1},
+		{"moved line", "a\nb\nc\nd\n", "b\nc\nd\na\n", 1, 1},
+		{"repeated lines", "}\n}\nx\n}\n", "}\nx\n}\n}\n}\n", 2, 1},
+		{"no newline at all", "", "a", 1, 0},
+		// A line found often on the other side is discarded when it is
+		// surrounded by changed lines, like git does
+		{"multimatch line", "a\nb\nc\nd\n}\ne\nf\ng\nh\n", "}\n}\n}\n}\n", 4, 9},
+	}
+	for _, tt := range tests {
+		insertions, deletions := CountLines([]byte(tt.old), []byte(tt.new))
+		assert.Equal(t, tt.insertions, insertions, tt.name)
+		assert.Equal(t, tt.deletions, deletions, tt.name)
+	}
+}
+
+func TestCountLines_ManyChanges(t *testing.T) {
+	// Shuffled blocks of lines, far beyond the cost after which the search
+	// settles for a good enough edit script, the counts are the ones of git
+	var old, new strings.Builder
+	for i := range 3000 {
+		fmt.Fprintf(&old, "func f%d() {\n\treturn\n}\n", i)
+		fmt.Fprintf(&new, "func f%d() {\n\treturn\n}\n", (i*1237)%3000)
+	}
+	insertions, deletions := CountLines([]byte(old.String()), []byte(new.String()))
+	assert.Equal(t, 2988, insertions)
+	assert.Equal(t, 2988, deletions)
+}
+
+func TestDiffLines(t *testing.T) {
+	var script []string
+	DiffLines([]byte("a\nb\nc\nd\ne\n"), []byte("a\nB\nc\ne\nf\n"), func(op byte, line []byte) {
+		script = append(script, string(op)+strings.TrimSuffix(string(line), "\n"))
+	})
+	assert.Equal(t, []string{"-b", "+B", "-d", "+f"}, script)
+
+	script = nil
+	DiffLines([]byte("x\ny\nz\n"), []byte("z\ny\nx\n"), func(op byte, line []byte) {
+		script = append(script, string(op)+strings.TrimSuffix(string(line), "\n"))
+	})
+	assert.Equal(t, []string{"-x", "-y", "+y", "+x"}, script)
+}
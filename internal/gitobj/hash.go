@@ -0,0 +1,34 @@
+// Package gitobj reads commits, trees and blobs straight from the object
+// database of a git repository, without the git binary.
+package gitobj
+
+import (
+	"encoding/hex"
+	"fmt"
+)
+
+// Hash is the SHA-1 name of an object
+type Hash [20]byte
+
+// ZeroHash names no object, e.g. the tree of a missing parent
+var ZeroHash Hash
+
+// NewHash parses a hash from its 40 hexadecimal digits
+func NewHash(s string) (Hash, error) {
+	var h Hash
+	if len(s) != 2*len(h) {
+		return h, fmt.Errorf("invalid object name: %q", s)
+	}
+	if _, err := hex.Decode(h[:], []byte(s)); err != nil {
+		return h, fmt.Errorf("invalid object name: %q", s)
+	}
+	return h, nil
+}
+
+func (h Hash) String() string {
+	return hex.EncodeToString(h[:])
+}
+
+func (h Hash) IsZero() bool {
+	return h == ZeroHash
+}
@@ -0,0 +1,53 @@
+package gitobj
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// readLooseObject reads an object stored in its own zlib compressed file
+// under objects/, with a "type size\0" header before the content
+func readLooseObject(objectsDir string, h Hash) (ObjectType, []byte, error) {
+	name := h.String()
+	f, err := os.Open(filepath.Join(objectsDir, name[:2], name[2:]))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil, ErrObjectNotFound
+		}
+		return 0, nil, err
+	}
+	defer f.Close()
+
+	zr, err := zlib.NewReader(f)
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid loose object %s: %w", h, err)
+	}
+	defer zr.Close()
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid loose object %s: %w", h, err)
+	}
+
+	nul := bytes.IndexByte(data, 0)
+	if nul < 0 {
+		return 0, nil, fmt.Errorf("invalid loose object %s: missing header", h)
+	}
+	typeName, sizeStr, ok := bytes.Cut(data[:nul], []byte(" "))
+	if !ok {
+		return 0, nil, fmt.Errorf("invalid loose object %s: invalid header", h)
+	}
+	t, err := parseObjectType(string(typeName))
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid loose object %s: %w", h, err)
+	}
+	size, err := strconv.Atoi(string(sizeStr))
+	if err != nil || size != len(data)-nul-1 {
+		return 0, nil, fmt.Errorf("invalid loose object %s: size mismatch", h)
+	}
+	return t, data[nul+1:], nil
+}
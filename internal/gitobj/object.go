@@ -0,0 +1,226 @@
+package gitobj
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type ObjectType int8
+
+const (
+	ObjectCommit ObjectType = 1
+	ObjectTree   ObjectType = 2
+	ObjectBlob   ObjectType = 3
+	ObjectTag    ObjectType = 4
+
+	// Types only found in packfiles, for objects stored as a delta of another object
+	objectOfsDelta ObjectType = 6
+	objectRefDelta ObjectType = 7
+)
+
+func (t ObjectType) String() string {
+	switch t {
+	case ObjectCommit:
+		return "commit"
+	case ObjectTree:
+		return "tree"
+	case ObjectBlob:
+		return "blob"
+	case ObjectTag:
+		return "tag"
+	}
+	return fmt.Sprintf("unknown(%d)", t)
+}
+
+func parseObjectType(s string) (ObjectType, error) {
+	switch s {
+	case "commit":
+		return ObjectCommit, nil
+	case "tree":
+		return ObjectTree, nil
+	case "blob":
+		return ObjectBlob, nil
+	case "tag":
+		return ObjectTag, nil
+	}
+	return 0, fmt.Errorf("unknown object type: %q", s)
+}
+
+// ErrObjectNotFound is returned for objects missing from the repository
+var ErrObjectNotFound = errors.New("object not found")
+
+// Signature is the author or committer of a commit
+type Signature struct {
+	Name  string
+	Email string
+	When  time.Time // in the time zone of the signature
+}
+
+type Commit struct {
+	Hash      Hash
+	Tree      Hash
+	Parents   []Hash
+	Author    Signature
+	Committer Signature
+	Message   string
+}
+
+// parseCommit parses the content of a commit object
+func parseCommit(h Hash, data []byte) (*Commit, error) {
+	c := &Commit{Hash: h}
+	header, message, _ := bytes.Cut(data, []byte("\n\n"))
+	c.Message = string(message)
+	for _, line := range strings.Split(string(header), "\n") {
+		// Continuation lines of multi-line headers such as gpgsig start with a space
+		key, value, ok := strings.Cut(line, " ")
+		if !ok || key == "" {
+			continue
+		}
+		var err error
+		switch key {
+		case "tree":
+			c.Tree, err = NewHash(value)
+		case "parent":
+			var parent Hash
+			parent, err = NewHash(value)
+			c.Parents = append(c.Parents, parent)
+		case "author":
+			c.Author = parseSignature(value)
+		case "committer":
+			c.Committer = parseSignature(value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid commit %s: %w", h, err)
+		}
+	}
+	return c, nil
+}
+
+// parseSignature parses "Name <email> timestamp timezone"
+func parseSignature(s string) Signature {
+	var sig Signature
+	open := strings.IndexByte(s, '<')
+	close := strings.LastIndexByte(s, '>')
+	if open < 0 || close < open {
+		sig.Name = strings.TrimSpace(s)
+		return sig
+	}
+	sig.Name = strings.TrimSpace(s[:open])
+	sig.Email = s[open+1 : close]
+
+	fields := strings.Fields(s[close+1:])
+	if len(fields) == 0 {
+		return sig
+	}
+	timestamp, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return sig
+	}
+	loc := time.UTC
+	if len(fields) > 1 {
+		if seconds, ok := ZoneOffset(fields[1]); ok {
+			loc = time.FixedZone(fields[1], seconds)
+		}
+	}
+	sig.When = time.Unix(timestamp, 0).In(loc)
+	return sig
+}
+
+// ZoneOffset parses the time zone of a signature, such as +0200, into its
+// offset from UTC in seconds
+func ZoneOffset(zone string) (int, bool) {
+	if len(zone) != 5 || zone[0] != '+' && zone[0] != '-' {
+		return 0, false
+	}
+	offset, err := strconv.Atoi(zone[1:])
+	if err != nil {
+		return 0, false
+	}
+	seconds := (offset/100*60 + offset%100) * 60
+	if zone[0] == '-' {
+		seconds = -seconds
+	}
+	return seconds, true
+}
+
+// Tag is an annotated tag
+type Tag struct {
+	Object Hash
+	Type   ObjectType
+	Name   string
+}
+
+func parseTag(h Hash, data []byte) (*Tag, error) {
+	t := &Tag{}
+	header, _, _ := bytes.Cut(data, []byte("\n\n"))
+	for _, line := range strings.Split(string(header), "\n") {
+		key, value, _ := strings.Cut(line, " ")
+		var err error
+		switch key {
+		case "object":
+			t.Object, err = NewHash(value)
+		case "type":
+			t.Type, err = parseObjectType(value)
+		case "tag":
+			t.Name = value
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid tag %s: %w", h, err)
+		}
+	}
+	return t, nil
+}
+
+// File modes of tree entries
+const (
+	ModeTree       = 0o040000
+	ModeFile       = 0o100644
+	ModeExecutable = 0o100755
+	ModeSymlink    = 0o120000
+	ModeSubmodule  = 0o160000
+)
+
+type TreeEntry struct {
+	Name string
+	Mode uint32
+	Hash Hash
+}
+
+const modeTypeMask = 0o170000
+
+func (e TreeEntry) IsTree() bool {
+	return e.Mode&modeTypeMask == ModeTree
+}
+
+func (e TreeEntry) IsSubmodule() bool {
+	return e.Mode&modeTypeMask == ModeSubmodule
+}
+
+// parseTree parses the entries of a tree object, "mode name\0hash" each
+func parseTree(h Hash, data []byte) ([]TreeEntry, error) {
+	var entries []TreeEntry
+	for len(data) > 0 {
+		space := bytes.IndexByte(data, ' ')
+		if space < 0 {
+			return nil, fmt.Errorf("invalid tree %s", h)
+		}
+		mode, err := strconv.ParseUint(string(data[:space]), 8, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tree %s: %w", h, err)
+		}
+		data = data[space+1:]
+		nul := bytes.IndexByte(data, 0)
+		if nul < 0 || len(data) < nul+1+len(Hash{}) {
+			return nil, fmt.Errorf("invalid tree %s", h)
+		}
+		entry := TreeEntry{Name: string(data[:nul]), Mode: uint32(mode)}
+		copy(entry.Hash[:], data[nul+1:])
+		entries = append(entries, entry)
+		data = data[nul+1+len(Hash{}):]
+	}
+	return entries, nil
+}
@@ -0,0 +1,404 @@
+package gitobj
+
+import (
+	"bytes"
+	"compress/zlib"
+	"container/list"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// packIndex is a version 2 pack index, the sorted names of the objects of a
+// packfile with their offsets
+type packIndex struct {
+	fanout       [256]uint32 // number of objects whose name starts with a byte up to the index
+	names        []byte      // sorted object names, 20 bytes each
+	offsets      []byte      // 4 bytes each, an index into largeOffsets when the MSB is set
+	largeOffsets []byte      // 8 bytes each, for packs larger than 2 GiB
+}
+
+var packIndexMagic = []byte{0xff, 't', 'O', 'c'}
+
+func readPackIndex(path string) (*packIndex, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 8+256*4 || !bytes.Equal(data[:4], packIndexMagic) {
+		return nil, fmt.Errorf("unsupported pack index %s, only version 2 is supported", path)
+	}
+	if version := binary.BigEndian.Uint32(data[4:8]); version != 2 {
+		return nil, fmt.Errorf("unsupported pack index version %d: %s", version, path)
+	}
+
+	idx := &packIndex{}
+	for i := range idx.fanout {
+		idx.fanout[i] = binary.BigEndian.Uint32(data[8+i*4:])
+	}
+	count := int(idx.fanout[255])
+	pos := 8 + 256*4
+	if len(data) < pos+count*(20+4+4) {
+		return nil, fmt.Errorf("truncated pack index: %s", path)
+	}
+	idx.names = data[pos : pos+count*20]
+	pos += count * 20
+	pos += count * 4 // CRC32 of each object
+	idx.offsets = data[pos : pos+count*4]
+	pos += count * 4
+	idx.largeOffsets = data[pos:]
+	return idx, nil
+}
+
+// find returns the offset of the object in the packfile
+func (idx *packIndex) find(h Hash) (int64, bool) {
+	lo := 0
+	if h[0] > 0 {
+		lo = int(idx.fanout[h[0]-1])
+	}
+	hi := int(idx.fanout[h[0]])
+	for lo < hi {
+		mid := (lo + hi) / 2
+		switch cmp := bytes.Compare(idx.names[mid*20:mid*20+20], h[:]); {
+		case cmp == 0:
+			return idx.offset(mid), true
+		case cmp < 0:
+			lo = mid + 1
+		default:
+			hi = mid
+		}
+	}
+	return 0, false
+}
+
+func (idx *packIndex) offset(i int) int64 {
+	offset := binary.BigEndian.Uint32(idx.offsets[i*4:])
+	if offset&0x80000000 == 0 {
+		return int64(offset)
+	}
+	large := int(offset&0x7fffffff) * 8
+	if large+8 > len(idx.largeOffsets) {
+		return -1
+	}
+	return int64(binary.BigEndian.Uint64(idx.largeOffsets[large:]))
+}
+
+// packFile reads objects from a packfile, resolving delta chains
+type packFile struct {
+	file  *os.File
+	size  int64
+	index *packIndex
+	store *objectStore // resolves the bases of ref deltas stored outside of the pack
+
+	mu    sync.Mutex
+	bases *baseCache // recently resolved objects, delta chains often share their bases
+}
+
+func openPackFile(indexPath string, store *objectStore) (*packFile, error) {
+	index, err := readPackIndex(indexPath)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(strings.TrimSuffix(indexPath, ".idx") + ".pack")
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	header := make([]byte, 12)
+	if _, err := f.ReadAt(header, 0); err != nil || string(header[:4]) != "PACK" {
+		f.Close()
+		return nil, fmt.Errorf("invalid packfile: %s", f.Name())
+	}
+	if version := binary.BigEndian.Uint32(header[4:8]); version != 2 && version != 3 {
+		f.Close()
+		return nil, fmt.Errorf("unsupported packfile version %d: %s", version, f.Name())
+	}
+	return &packFile{
+		file:  f,
+		size:  info.Size(),
+		index: index,
+		store: store,
+		bases: newBaseCache(32 << 20),
+	}, nil
+}
+
+func (p *packFile) Close() error {
+	return p.file.Close()
+}
+
+// read returns the object with the given name, or ErrObjectNotFound
+func (p *packFile) read(h Hash) (ObjectType, []byte, error) {
+	offset, ok := p.index.find(h)
+	if !ok {
+		return 0, nil, ErrObjectNotFound
+	}
+	return p.readAt(offset, 0)
+}
+
+// maxDeltaDepth guards against cycles in corrupt packfiles, git itself
+// never writes chains longer than 4095
+const maxDeltaDepth = 10000
+
+// readAt returns the object stored at offset, applying its delta chain
+func (p *packFile) readAt(offset int64, depth int) (ObjectType, []byte, error) {
+	if depth > maxDeltaDepth {
+		return 0, nil, errors.New("delta chain too long")
+	}
+	if t, data, ok := p.cached(offset); ok {
+		return t, data, nil
+	}
+
+	t, size, dataOffset, err := p.readHeader(offset)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var baseType ObjectType
+	var base []byte
+	switch t {
+	case ObjectCommit, ObjectTree, ObjectBlob, ObjectTag:
+		data, err := p.inflate(dataOffset, size)
+		if err != nil {
+			return 0, nil, err
+		}
+		p.cache(offset, t, data)
+		return t, data, nil
+	case objectOfsDelta:
+		// The base is stored at a negative offset from this object
+		var buf [10]byte
+		n, _ := p.file.ReadAt(buf[:], dataOffset)
+		c := buf[0]
+		distance := int64(c & 0x7f)
+		i := 1
+		for c&0x80 != 0 {
+			if i >= n {
+				return 0, nil, fmt.Errorf("invalid delta offset at %d", offset)
+			}
+			c = buf[i]
+			distance = ((distance + 1) << 7) | int64(c&0x7f)
+			i++
+		}
+		if distance <= 0 || distance > offset {
+			return 0, nil, fmt.Errorf("invalid delta offset at %d", offset)
+		}
+		dataOffset += int64(i)
+		baseType, base, err = p.readAt(offset-distance, depth+1)
+	case objectRefDelta:
+		// The base is named by its hash, in this pack or anywhere in the repository
+		var baseHash Hash
+		if _, err := p.file.ReadAt(baseHash[:], dataOffset); err != nil {
+			return 0, nil, err
+		}
+		dataOffset += int64(len(baseHash))
+		if baseOffset, ok := p.index.find(baseHash); ok {
+			baseType, base, err = p.readAt(baseOffset, depth+1)
+		} else {
+			baseType, base, err = p.store.read(baseHash)
+		}
+	default:
+		return 0, nil, fmt.Errorf("invalid object type %d at %d", t, offset)
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+
+	delta, err := p.inflate(dataOffset, size)
+	if err != nil {
+		return 0, nil, err
+	}
+	data, err := applyDelta(base, delta)
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid delta at %d: %w", offset, err)
+	}
+	p.cache(offset, baseType, data)
+	return baseType, data, nil
+}
+
+// readHeader reads the type and size of the object at offset, and returns
+// the offset of the data that follows
+func (p *packFile) readHeader(offset int64) (ObjectType, int64, int64, error) {
+	var buf [16]byte
+	n, err := p.file.ReadAt(buf[:], offset)
+	if n == 0 {
+		if err == nil {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, 0, 0, err
+	}
+	c := buf[0]
+	t := ObjectType((c >> 4) & 7)
+	size := int64(c & 0x0f)
+	shift := 4
+	i := 1
+	for c&0x80 != 0 {
+		if i >= n {
+			return 0, 0, 0, fmt.Errorf("invalid object header at %d", offset)
+		}
+		c = buf[i]
+		size |= int64(c&0x7f) << shift
+		shift += 7
+		i++
+	}
+	return t, size, offset + int64(i), nil
+}
+
+// inflate decompresses size bytes of zlib data stored at offset
+func (p *packFile) inflate(offset, size int64) ([]byte, error) {
+	zr, err := zlib.NewReader(io.NewSectionReader(p.file, offset, p.size-offset))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	data := make([]byte, size)
+	if _, err := io.ReadFull(zr, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (p *packFile) cached(offset int64) (ObjectType, []byte, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.bases.get(offset)
+}
+
+func (p *packFile) cache(offset int64, t ObjectType, data []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.bases.add(offset, t, data)
+}
+
+// applyDelta rebuilds an object from its base and a delta, which starts with
+// the sizes of both objects followed by instructions to copy ranges of the
+// base or to insert new data
+func applyDelta(base, delta []byte) ([]byte, error) {
+	baseSize, delta, err := readDeltaSize(delta)
+	if err != nil {
+		return nil, err
+	}
+	if baseSize != len(base) {
+		return nil, errors.New("base size mismatch")
+	}
+	resultSize, delta, err := readDeltaSize(delta)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]byte, 0, resultSize)
+	for len(delta) > 0 {
+		op := delta[0]
+		delta = delta[1:]
+		switch {
+		case op&0x80 != 0:
+			// Copy from the base, the bits of op tell which offset and size bytes follow
+			var offset, size int
+			for i := 0; i < 4; i++ {
+				if op&(1<<i) != 0 {
+					if len(delta) == 0 {
+						return nil, errors.New("truncated copy instruction")
+					}
+					offset |= int(delta[0]) << (8 * i)
+					delta = delta[1:]
+				}
+			}
+			for i := 0; i < 3; i++ {
+				if op&(0x10<<i) != 0 {
+					if len(delta) == 0 {
+						return nil, errors.New("truncated copy instruction")
+					}
+					size |= int(delta[0]) << (8 * i)
+					delta = delta[1:]
+				}
+			}
+			if size == 0 {
+				size = 0x10000
+			}
+			if offset+size > len(base) {
+				return nil, errors.New("copy out of bounds")
+			}
+			result = append(result, base[offset:offset+size]...)
+		case op != 0:
+			// Insert the next op bytes
+			if int(op) > len(delta) {
+				return nil, errors.New("truncated insert instruction")
+			}
+			result = append(result, delta[:op]...)
+			delta = delta[op:]
+		default:
+			return nil, errors.New("invalid delta instruction")
+		}
+	}
+	if len(result) != resultSize {
+		return nil, errors.New("result size mismatch")
+	}
+	return result, nil
+}
+
+// readDeltaSize reads a little-endian base-128 size
+func readDeltaSize(delta []byte) (int, []byte, error) {
+	size := 0
+	shift := 0
+	for i, c := range delta {
+		size |= int(c&0x7f) << shift
+		shift += 7
+		if c&0x80 == 0 {
+			return size, delta[i+1:], nil
+		}
+	}
+	return 0, nil, errors.New("truncated delta size")
+}
+
+// baseCache keeps the most recently used objects of a packfile up to a total size
+type baseCache struct {
+	maxSize int
+	size    int
+	order   *list.List // most recently used first
+	entries map[int64]*list.Element
+}
+
+type baseCacheEntry struct {
+	offset int64
+	t      ObjectType
+	data   []byte
+}
+
+func newBaseCache(maxSize int) *baseCache {
+	return &baseCache{
+		maxSize: maxSize,
+		order:   list.New(),
+		entries: make(map[int64]*list.Element),
+	}
+}
+
+func (c *baseCache) get(offset int64) (ObjectType, []byte, bool) {
+	el, ok := c.entries[offset]
+	if !ok {
+		return 0, nil, false
+	}
+	c.order.MoveToFront(el)
+	entry := el.Value.(*baseCacheEntry)
+	return entry.t, entry.data, true
+}
+
+func (c *baseCache) add(offset int64, t ObjectType, data []byte) {
+	if _, ok := c.entries[offset]; ok || len(data) > c.maxSize/4 {
+		return
+	}
+	c.entries[offset] = c.order.PushFront(&baseCacheEntry{offset: offset, t: t, data: data})
+	c.size += len(data)
+	for c.size > c.maxSize {
+		last := c.order.Back()
+		entry := last.Value.(*baseCacheEntry)
+		c.order.Remove(last)
+		delete(c.entries, entry.offset)
+		c.size -= len(entry.data)
+	}
+}
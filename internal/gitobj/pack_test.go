@@ -0,0 +1,47 @@
+package gitobj
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyDelta(t *testing.T) {
+	base := []byte("hello, world\n")
+
+	// Sizes 13 and 19, copy "hello, " then insert "brave " then copy "world\n"
+	delta := []byte{
+		13, 19,
+		0x80 | 0x10, 7,
+		6, 'b', 'r', 'a', 'v', 'e', ' ',
+		0x80 | 0x01 | 0x10, 7, 6,
+	}
+	result, err := applyDelta(base, delta)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello, brave world\n", string(result))
+
+	// A copy without a size copies 64 KiB
+	large := make([]byte, 0x10000)
+	large[0x10000-1] = 'x'
+	result, err = applyDelta(large, []byte{0x80, 0x80, 0x04, 0x80, 0x80, 0x04, 0x80})
+	assert.NoError(t, err)
+	assert.Equal(t, large, result)
+}
+
+func TestApplyDelta_Invalid(t *testing.T) {
+	base := []byte("hello")
+
+	tests := map[string][]byte{
+		"base size":        {4, 5, 0x80 | 0x10, 5},
+		"result size":      {5, 6, 0x80 | 0x10, 5},
+		"copy out of base": {5, 6, 0x80 | 0x01 | 0x10, 1, 6},
+		"truncated insert": {5, 5, 5, 'h', 'e'},
+		"truncated copy":   {5, 5, 0x80 | 0x01},
+		"reserved op":      {5, 5, 0},
+		"truncated size":   {0x85},
+	}
+	for name, delta := range tests {
+		_, err := applyDelta(base, delta)
+		assert.Error(t, err, name)
+	}
+}
@@ -0,0 +1,154 @@
+package gitobj
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Ref is a reference resolved to the object it points to
+type Ref struct {
+	Name string // full name, e.g. refs/heads/main
+	Hash Hash
+}
+
+// maxSymrefDepth matches the limit of git on chained symbolic references
+const maxSymrefDepth = 5
+
+// References returns the references whose full name starts with prefix,
+// e.g. "refs/heads/", sorted by name. Symbolic references are resolved and
+// references that don't resolve to an object are skipped, like git does.
+func (r *Repository) References(prefix string) ([]Ref, error) {
+	packed, err := r.packedRefs()
+	if err != nil {
+		return nil, err
+	}
+
+	// Loose references override packed references of the same name
+	refs := make(map[string]Hash)
+	for name, h := range packed {
+		if strings.HasPrefix(name, prefix) {
+			refs[name] = h
+		}
+	}
+
+	root := filepath.Join(r.commonDir, "refs")
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(r.commonDir, path)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		if !strings.HasPrefix(name, prefix) {
+			return nil
+		}
+		if h, ok, err := r.resolveRef(name, packed, 0); err != nil {
+			return err
+		} else if ok {
+			refs[name] = h
+		} else {
+			delete(refs, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Ref, 0, len(refs))
+	for name, h := range refs {
+		result = append(result, Ref{Name: name, Hash: h})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+// Head returns the commit checked out in the working tree, false when the
+// current branch has no commits yet
+func (r *Repository) Head() (Hash, bool, error) {
+	packed, err := r.packedRefs()
+	if err != nil {
+		return ZeroHash, false, err
+	}
+	return r.resolveRef("HEAD", packed, 0)
+}
+
+// resolveRef reads a loose reference, following symbolic references such as
+// "ref: refs/heads/main"
+func (r *Repository) resolveRef(name string, packed map[string]Hash, depth int) (Hash, bool, error) {
+	if depth > maxSymrefDepth {
+		return ZeroHash, false, fmt.Errorf("too many levels of symbolic references: %s", name)
+	}
+
+	// HEAD and other pseudo references belong to the worktree, refs/ are shared
+	dir := r.commonDir
+	if !strings.HasPrefix(name, "refs/") {
+		dir = r.gitDir
+	}
+	data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			h, ok := packed[name]
+			return h, ok, nil
+		}
+		return ZeroHash, false, err
+	}
+
+	content := strings.TrimSpace(string(data))
+	if target, ok := strings.CutPrefix(content, "ref:"); ok {
+		return r.resolveRef(strings.TrimSpace(target), packed, depth+1)
+	}
+	h, err := NewHash(content)
+	if err != nil {
+		return ZeroHash, false, fmt.Errorf("invalid reference %s: %w", name, err)
+	}
+	return h, true, nil
+}
+
+// packedRefs reads the packed-refs file written by git pack-refs and git gc
+func (r *Repository) packedRefs() (map[string]Hash, error) {
+	refs := make(map[string]Hash)
+	f, err := os.Open(filepath.Join(r.commonDir, "packed-refs"))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return refs, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		// Skip the header and the peeled objects of annotated tags
+		if line == "" || line[0] == '#' || line[0] == '^' {
+			continue
+		}
+		hash, name, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		h, err := NewHash(hash)
+		if err != nil {
+			return nil, fmt.Errorf("invalid packed-refs: %w", err)
+		}
+		refs[name] = h
+	}
+	return refs, scanner.Err()
+}
@@ -0,0 +1,84 @@
+package gitobj
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReferences(t *testing.T) {
+	dir := t.TempDir()
+	gitDir := filepath.Join(dir, ".git")
+	a := strings.Repeat("a", 40)
+	b := strings.Repeat("b", 40)
+	c := strings.Repeat("c", 40)
+
+	writeFile(t, filepath.Join(gitDir, "HEAD"), "ref: refs/heads/main\n")
+	writeFile(t, filepath.Join(gitDir, "packed-refs"), "# pack-refs with: peeled fully-peeled sorted \n"+
+		a+" refs/heads/main\n"+
+		a+" refs/heads/old\n"+
+		b+" refs/tags/v1\n"+
+		"^"+c+"\n")
+	// Loose references win over packed ones
+	writeFile(t, filepath.Join(gitDir, "refs", "heads", "main"), b+"\n")
+	writeFile(t, filepath.Join(gitDir, "refs", "heads", "feature", "x"), c+"\n")
+	writeFile(t, filepath.Join(gitDir, "refs", "heads", "alias"), "ref: refs/heads/old\n")
+	// Symbolic references to missing branches are skipped
+	writeFile(t, filepath.Join(gitDir, "refs", "heads", "dangling"), "ref: refs/heads/missing\n")
+	if err := os.MkdirAll(filepath.Join(gitDir, "objects"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	repo, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close()
+
+	refs, err := repo.References("refs/heads/")
+	assert.NoError(t, err)
+	hash := func(s string) Hash {
+		h, err := NewHash(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h
+	}
+	assert.Equal(t, []Ref{
+		{Name: "refs/heads/alias", Hash: hash(a)},
+		{Name: "refs/heads/feature/x", Hash: hash(c)},
+		{Name: "refs/heads/main", Hash: hash(b)},
+		{Name: "refs/heads/old", Hash: hash(a)},
+	}, refs)
+
+	head, ok, err := repo.Head()
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, hash(b), head)
+}
+
+func TestOpen_UnsupportedFormats(t *testing.T) {
+	for _, config := range []string{
+		"[core]\n\trepositoryformatversion = 99\n",
+		"[core]\n\trepositoryformatversion = 1\n[extensions]\n\tobjectFormat = sha256\n",
+		"[core]\n\trepositoryformatversion = 1\n[extensions]\n\trefStorage = reftable\n",
+	} {
+		dir := t.TempDir()
+		writeFile(t, filepath.Join(dir, ".git", "config"), config)
+		_, err := Open(dir)
+		assert.Error(t, err, config)
+	}
+}
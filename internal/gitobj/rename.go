@@ -0,0 +1,290 @@
+package gitobj
+
+import "sort"
+
+// Rename detection follows diffcore-rename of git with copies enabled, as
+// git diff -C -l1000 does, so that both backends pair the same files
+const (
+	maxScore         = 60000 // score of identical files
+	minScore         = 30000 // 50% similar, the default of -M and -C
+	renameLimit      = 1000  // beyond limit*limit pairs only exact renames are found
+	candidatesPerDst = 4     // best sources kept for each destination
+)
+
+// renameSource is a deleted or modified file that added files may come from
+type renameSource struct {
+	pair int // index in the pairs
+	used int // times it was paired, modified files start at 1 so they are only copied
+}
+
+// renameScore is the similarity of a source and a destination
+type renameScore struct {
+	dst, src  int // -1 dst for an unused slot
+	score     int
+	nameScore int // 1 when both have the same file name
+}
+
+// detectRenames pairs added files with the deleted or modified files they
+// were renamed or copied from. Deleted files that were renamed are dropped.
+func detectRenames(pairs []filePair, files *fileContents) ([]filePair, error) {
+	var dsts []int
+	var srcs []renameSource
+	for i, p := range pairs {
+		switch {
+		case p.Old == nil:
+			dsts = append(dsts, i)
+		case p.New == nil:
+			srcs = append(srcs, renameSource{pair: i})
+		default:
+			srcs = append(srcs, renameSource{pair: i, used: 1})
+		}
+	}
+	if len(dsts) == 0 {
+		return pairs, nil
+	}
+
+	sources := make(map[int]int, len(dsts)) // destination index to source index
+	record := func(dst, src int) {
+		sources[dst] = src
+		srcs[src].used++
+	}
+
+	// Exact renames first, preferring unused sources with the same file name
+	for d, dst := range dsts {
+		target := pairs[dst].New
+		best, bestScore, tries := -1, -1, 100
+		for s := range srcs {
+			source := pairs[srcs[s].pair].Old
+			if source.Hash != target.Hash {
+				continue
+			}
+			if (!isRegular(source.Mode) || !isRegular(target.Mode)) && source.Mode != target.Mode {
+				continue
+			}
+			score := 0
+			if srcs[s].used == 0 {
+				score = 1
+			}
+			if sameBaseName(pairs[srcs[s].pair].Path, pairs[dst].Path) {
+				score++
+			}
+			if score > bestScore {
+				best, bestScore = s, score
+				if score == 2 {
+					break
+				}
+			}
+			if tries--; tries == 0 {
+				break
+			}
+		}
+		if best >= 0 {
+			record(d, best)
+		}
+	}
+
+	// Then similar files, unless there are too many pairs to compare
+	remaining := len(dsts) - len(sources)
+	if remaining > 0 && len(srcs) > 0 && remaining*len(srcs) <= renameLimit*renameLimit {
+		hashes := make(map[Hash]map[uint32]int)
+		spans := func(e *TreeEntry) (map[uint32]int, error) {
+			if h, ok := hashes[e.Hash]; ok {
+				return h, nil
+			}
+			data, err := files.read(e)
+			if err != nil {
+				return nil, err
+			}
+			hashes[e.Hash] = spanHashes(data)
+			return hashes[e.Hash], nil
+		}
+
+		scores := make([]renameScore, 0, remaining*candidatesPerDst)
+		for d, dst := range dsts {
+			if _, ok := sources[d]; ok {
+				continue
+			}
+			m := make([]renameScore, candidatesPerDst)
+			for i := range m {
+				m[i].dst = -1
+			}
+			for s := range srcs {
+				score, err := similarity(pairs[srcs[s].pair].Old, pairs[dst].New, files, spans)
+				if err != nil {
+					return nil, err
+				}
+				candidate := renameScore{dst: d, src: s, score: score}
+				if sameBaseName(pairs[srcs[s].pair].Path, pairs[dst].Path) {
+					candidate.nameScore = 1
+				}
+				recordIfBetter(m, candidate)
+			}
+			scores = append(scores, m...)
+		}
+		sort.SliceStable(scores, func(i, j int) bool {
+			return compareScores(scores[i], scores[j]) < 0
+		})
+
+		// Renames of unused sources first, then copies
+		for _, copies := range []bool{false, true} {
+			for _, m := range scores {
+				if m.dst < 0 || m.score < minScore {
+					break
+				}
+				if _, ok := sources[m.dst]; ok {
+					continue
+				}
+				if !copies && srcs[m.src].used > 0 {
+					continue
+				}
+				record(m.dst, m.src)
+			}
+		}
+	}
+	if len(sources) == 0 {
+		return pairs, nil
+	}
+
+	renamedFrom := make(map[int]int, len(sources)) // pair of a destination to pair of its source
+	for d, s := range sources {
+		renamedFrom[dsts[d]] = srcs[s].pair
+	}
+	deleted := make(map[int]bool) // pairs of renamed deleted files
+	for _, s := range srcs {
+		if pairs[s.pair].New == nil && s.used > 0 {
+			deleted[s.pair] = true
+		}
+	}
+	result := make([]filePair, 0, len(pairs))
+	for i, p := range pairs {
+		if deleted[i] {
+			continue
+		}
+		if src, ok := renamedFrom[i]; ok {
+			p.OldPath = pairs[src].Path
+			p.Old = pairs[src].Old
+		}
+		result = append(result, p)
+	}
+	return result, nil
+}
+
+// recordIfBetter keeps the candidate when it is better than the worst one
+func recordIfBetter(m []renameScore, candidate renameScore) {
+	worst := 0
+	for i := 1; i < len(m); i++ {
+		if compareScores(m[i], m[worst]) > 0 {
+			worst = i
+		}
+	}
+	if compareScores(m[worst], candidate) > 0 {
+		m[worst] = candidate
+	}
+}
+
+// compareScores orders the most similar pairs first and unused slots last
+func compareScores(a, b renameScore) int {
+	if a.dst < 0 {
+		if b.dst >= 0 {
+			return 1
+		}
+		return 0
+	}
+	if b.dst < 0 {
+		return -1
+	}
+	if a.score == b.score {
+		return b.nameScore - a.nameScore
+	}
+	return b.score - a.score
+}
+
+// similarity estimates how much of the larger file comes from the other one,
+// from 0 to maxScore. Only regular files can be renamed with changes.
+func similarity(src, dst *TreeEntry, files *fileContents, spans func(*TreeEntry) (map[uint32]int, error)) (int, error) {
+	if !isRegular(src.Mode) || !isRegular(dst.Mode) {
+		return 0, nil
+	}
+	srcData, err := files.read(src)
+	if err != nil {
+		return 0, err
+	}
+	dstData, err := files.read(dst)
+	if err != nil {
+		return 0, err
+	}
+	maxSize := max(len(srcData), len(dstData))
+	delta := maxSize - min(len(srcData), len(dstData))
+	// Files whose size changes too much are not compared
+	if maxSize*(maxScore-minScore) < delta*maxScore {
+		return 0, nil
+	}
+	if len(dstData) == 0 {
+		return 0, nil
+	}
+
+	srcSpans, err := spans(src)
+	if err != nil {
+		return 0, err
+	}
+	dstSpans, err := spans(dst)
+	if err != nil {
+		return 0, err
+	}
+	copied := 0
+	for h, n := range srcSpans {
+		copied += min(n, dstSpans[h])
+	}
+	return int(uint64(copied) * maxScore / uint64(maxSize)), nil
+}
+
+// spanHashBase is the modulus of the span hashes
+const spanHashBase = 107927
+
+// spanHashes splits data in lines of at most 64 bytes and returns the number
+// of bytes of the spans with each hash. Carriage returns before newlines are
+// ignored in text files.
+func spanHashes(data []byte) map[uint32]int {
+	text := !IsBinary(data)
+	hashes := make(map[uint32]int)
+	var accum1, accum2 uint32
+	n := 0
+	for i := 0; i < len(data); i++ {
+		c := uint32(data[i])
+		if text && c == '\r' && i+1 < len(data) && data[i+1] == '\n' {
+			continue
+		}
+		old1 := accum1
+		accum1 = (accum1 << 7) ^ (accum2 >> 25)
+		accum2 = (accum2 << 7) ^ (old1 >> 25)
+		accum1 += c
+		n++
+		if n < 64 && c != '\n' {
+			continue
+		}
+		hashes[(accum1+accum2*0x61)%spanHashBase] += n
+		n, accum1, accum2 = 0, 0, 0
+	}
+	// Like git, a last line without a newline is not hashed
+	return hashes
+}
+
+// sameBaseName reports whether two paths have the same file name
+func sameBaseName(a, b string) bool {
+	i, j := len(a), len(b)
+	for i > 0 && j > 0 {
+		i--
+		j--
+		if a[i] != b[j] {
+			return false
+		}
+		if a[i] == '/' {
+			return true
+		}
+	}
+	return (i == 0 || a[i-1] == '/') && (j == 0 || b[j-1] == '/')
+}
+
+func isRegular(mode uint32) bool {
+	return mode&modeTypeMask == 0o100000
+}
@@ -0,0 +1,31 @@
+package gitobj
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSameBaseName(t *testing.T) {
+	assert.True(t, sameBaseName("a.go", "a.go"))
+	assert.True(t, sameBaseName("src/a.go", "lib/a.go"))
+	assert.True(t, sameBaseName("a.go", "lib/a.go"))
+	assert.False(t, sameBaseName("src/a.go", "src/b.go"))
+	assert.False(t, sameBaseName("src/a.go", "src/ba.go"))
+	assert.False(t, sameBaseName("ba.go", "a.go"))
+}
+
+func TestSpanHashes(t *testing.T) {
+	// Carriage returns before newlines are ignored in text files only
+	assert.Equal(t, spanHashes([]byte("a\nb\n")), spanHashes([]byte("a\r\nb\r\n")))
+	assert.NotEqual(t, spanHashes([]byte("a\n\x00\n")), spanHashes([]byte("a\r\n\x00\n")))
+
+	// Lines are split every 64 bytes, a last line without a newline is not hashed
+	hashes := spanHashes([]byte("0123456789012345678901234567890123456789012345678901234567890123456789\nend"))
+	total := 0
+	for _, n := range hashes {
+		total += n
+	}
+	assert.Len(t, hashes, 2)
+	assert.Equal(t, 71, total)
+}
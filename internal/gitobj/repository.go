@@ -0,0 +1,206 @@
+package gitobj
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Repository reads the objects and references of a git repository
+type Repository struct {
+	gitDir    string // the .git directory, with HEAD
+	commonDir string // the directory with objects and refs, different from gitDir in linked worktrees
+	objects   *objectStore
+	shallow   map[Hash]bool // commits whose parents are missing in a shallow clone
+}
+
+// Open opens the repository of a working tree, with a .git directory or a
+// .git file pointing to the git directory as written for worktrees and submodules
+func Open(dir string) (*Repository, error) {
+	gitDir := filepath.Join(dir, ".git")
+	info, err := os.Stat(gitDir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		data, err := os.ReadFile(gitDir)
+		if err != nil {
+			return nil, err
+		}
+		target, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:")
+		if !ok {
+			return nil, fmt.Errorf("invalid .git file: %s", gitDir)
+		}
+		gitDir = strings.TrimSpace(target)
+		if !filepath.IsAbs(gitDir) {
+			gitDir = filepath.Join(dir, gitDir)
+		}
+	}
+	return OpenGitDir(gitDir)
+}
+
+// OpenGitDir opens a repository from its git directory, e.g. a bare repository
+func OpenGitDir(gitDir string) (*Repository, error) {
+	r := &Repository{
+		gitDir:    gitDir,
+		commonDir: gitDir,
+	}
+	if data, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+		r.commonDir = strings.TrimSpace(string(data))
+		if !filepath.IsAbs(r.commonDir) {
+			r.commonDir = filepath.Join(gitDir, r.commonDir)
+		}
+	}
+
+	if err := r.checkExtensions(); err != nil {
+		return nil, err
+	}
+
+	objects, err := openObjectStore(filepath.Join(r.commonDir, "objects"), 0)
+	if err != nil {
+		return nil, err
+	}
+	r.objects = objects
+
+	r.shallow = make(map[Hash]bool)
+	if f, err := os.Open(filepath.Join(r.commonDir, "shallow")); err == nil {
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			if h, err := NewHash(strings.TrimSpace(scanner.Text())); err == nil {
+				r.shallow[h] = true
+			}
+		}
+	}
+
+	return r, nil
+}
+
+// checkExtensions rejects repositories using formats that can't be read,
+// newer repository versions, SHA-256 object names and the reftable reference storage
+func (r *Repository) checkExtensions() error {
+	f, err := os.Open(filepath.Join(r.commonDir, "config"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	section := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			section = strings.ToLower(strings.Trim(line, "[] \t"))
+			continue
+		}
+		key, value, _ := strings.Cut(line, "=")
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.ToLower(strings.TrimSpace(value))
+		switch {
+		case section == "core" && key == "repositoryformatversion" && value != "0" && value != "1":
+			return fmt.Errorf("unsupported repository format version: %s", value)
+		case section != "extensions":
+			continue
+		case key == "objectformat" && value != "sha1":
+			return fmt.Errorf("unsupported object format: %s", value)
+		case key == "refstorage" && value != "files":
+			return fmt.Errorf("unsupported ref storage: %s", value)
+		}
+	}
+	return scanner.Err()
+}
+
+func (r *Repository) Close() error {
+	return r.objects.Close()
+}
+
+// ReadObject returns the type and content of an object. The content may be
+// shared with a cache and must not be modified.
+func (r *Repository) ReadObject(h Hash) (ObjectType, []byte, error) {
+	t, data, err := r.objects.read(h)
+	if err != nil {
+		return 0, nil, fmt.Errorf("could not read object %s: %w", h, err)
+	}
+	return t, data, nil
+}
+
+func (r *Repository) readObjectOfType(h Hash, want ObjectType) ([]byte, error) {
+	t, data, err := r.ReadObject(h)
+	if err != nil {
+		return nil, err
+	}
+	if t != want {
+		return nil, fmt.Errorf("object %s is a %s, not a %s", h, t, want)
+	}
+	return data, nil
+}
+
+// Commit reads a commit. The parents of the commits at the boundary of a
+// shallow clone are omitted, as they are missing from the repository.
+func (r *Repository) Commit(h Hash) (*Commit, error) {
+	data, err := r.readObjectOfType(h, ObjectCommit)
+	if err != nil {
+		return nil, err
+	}
+	c, err := parseCommit(h, data)
+	if err != nil {
+		return nil, err
+	}
+	if r.shallow[h] {
+		c.Parents = nil
+	}
+	return c, nil
+}
+
+// Tree reads the entries of a tree, the zero hash is the empty tree
+func (r *Repository) Tree(h Hash) ([]TreeEntry, error) {
+	if h.IsZero() {
+		return nil, nil
+	}
+	data, err := r.readObjectOfType(h, ObjectTree)
+	if err != nil {
+		return nil, err
+	}
+	return parseTree(h, data)
+}
+
+// Blob reads the content of a blob, the zero hash is an empty blob
+func (r *Repository) Blob(h Hash) ([]byte, error) {
+	if h.IsZero() {
+		return nil, nil
+	}
+	return r.readObjectOfType(h, ObjectBlob)
+}
+
+// maxPeelDepth guards against cycles of tags in corrupt repositories
+const maxPeelDepth = 100
+
+// PeelToCommit follows annotated tags to the commit they point to. It returns
+// false for references to other objects, e.g. tags of trees or blobs.
+func (r *Repository) PeelToCommit(h Hash) (Hash, bool, error) {
+	for range maxPeelDepth {
+		t, data, err := r.ReadObject(h)
+		if err != nil {
+			return h, false, err
+		}
+		switch t {
+		case ObjectCommit:
+			return h, true, nil
+		case ObjectTag:
+			tag, err := parseTag(h, data)
+			if err != nil {
+				return h, false, err
+			}
+			h = tag.Object
+		default:
+			return h, false, nil
+		}
+	}
+	return h, false, fmt.Errorf("too many nested tags: %s", h)
+}
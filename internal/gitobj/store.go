@@ -0,0 +1,102 @@
+package gitobj
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// objectStore reads objects from the packfiles and loose objects of an
+// objects directory and of its alternates
+type objectStore struct {
+	dir        string
+	packs      []*packFile
+	alternates []*objectStore
+}
+
+// maxAlternateDepth matches the limit of git on chained alternates
+const maxAlternateDepth = 5
+
+func openObjectStore(dir string, depth int) (*objectStore, error) {
+	s := &objectStore{dir: dir}
+
+	indexes, err := filepath.Glob(filepath.Join(dir, "pack", "*.idx"))
+	if err != nil {
+		return nil, err
+	}
+	for _, index := range indexes {
+		pack, err := openPackFile(index, s)
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+		s.packs = append(s.packs, pack)
+	}
+
+	// Objects borrowed from other repositories, e.g. with git clone --shared
+	if depth < maxAlternateDepth {
+		f, err := os.Open(filepath.Join(dir, "info", "alternates"))
+		if err == nil {
+			defer f.Close()
+			scanner := bufio.NewScanner(f)
+			for scanner.Scan() {
+				line := strings.TrimSpace(scanner.Text())
+				if line == "" || strings.HasPrefix(line, "#") {
+					continue
+				}
+				if !filepath.IsAbs(line) {
+					line = filepath.Join(dir, line)
+				}
+				alternate, err := openObjectStore(line, depth+1)
+				if err != nil {
+					s.Close()
+					return nil, err
+				}
+				s.alternates = append(s.alternates, alternate)
+			}
+		}
+	}
+
+	return s, nil
+}
+
+// read returns the type and content of an object. The content may be shared
+// with a cache and must not be modified.
+func (s *objectStore) read(h Hash) (ObjectType, []byte, error) {
+	for _, pack := range s.packs {
+		t, data, err := pack.read(h)
+		if !errors.Is(err, ErrObjectNotFound) {
+			return t, data, err
+		}
+	}
+
+	t, data, err := readLooseObject(s.dir, h)
+	if !errors.Is(err, ErrObjectNotFound) {
+		return t, data, err
+	}
+
+	for _, alternate := range s.alternates {
+		t, data, err := alternate.read(h)
+		if !errors.Is(err, ErrObjectNotFound) {
+			return t, data, err
+		}
+	}
+	return 0, nil, ErrObjectNotFound
+}
+
+func (s *objectStore) Close() error {
+	var firstErr error
+	for _, pack := range s.packs {
+		if err := pack.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	for _, alternate := range s.alternates {
+		if err := alternate.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
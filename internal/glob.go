@@ -0,0 +1,242 @@
+package internal
+
+import "strings"
+
+// pathPattern is a glob with the syntax of .gitignore and .gitattributes
+// files, matched against slash-separated paths relative to the repository
+type pathPattern struct {
+	glob     string // without the leading ! and / and the trailing /
+	base     string // directory of the file the pattern comes from, empty for the root
+	anchored bool   // has a slash, matched against the path from base instead of the file name
+	dirOnly  bool   // ends with a slash, only matches directories
+	negate   bool   // starts with !, re-includes what earlier patterns matched
+}
+
+// parsePathPattern parses a pattern read from a file in the base directory.
+// A leading ! negates the pattern, use \! for a literal one.
+func parsePathPattern(pattern, base string) pathPattern {
+	p := pathPattern{base: base}
+	if strings.HasPrefix(pattern, "!") {
+		p.negate = true
+		pattern = pattern[1:]
+	}
+	if strings.HasSuffix(pattern, "/") {
+		p.dirOnly = true
+		pattern = strings.TrimRight(pattern, "/")
+	}
+	if strings.Contains(pattern, "/") {
+		p.anchored = true
+		pattern = strings.TrimPrefix(pattern, "/")
+	}
+	p.glob = pattern
+	return p
+}
+
+// match reports whether the pattern matches a file or directory path
+func (p *pathPattern) match(path string, isDir bool) bool {
+	if p.dirOnly && !isDir {
+		return false
+	}
+	if p.base != "" {
+		if !strings.HasPrefix(path, p.base+"/") {
+			return false
+		}
+		path = path[len(p.base)+1:]
+	}
+	if !p.anchored {
+		path = path[strings.LastIndexByte(path, '/')+1:]
+	}
+	return wildmatch(p.glob, path)
+}
+
+// pathPatterns is an ordered list of patterns, like the lines of a .gitignore
+type pathPatterns []pathPattern
+
+// parseIgnore parses the patterns of a .gitignore-style file. Blank lines and
+// comments are skipped, trailing spaces are removed unless escaped.
+func parseIgnore(data []byte) pathPatterns {
+	var patterns pathPatterns
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\\ ") {
+			line = line[:len(line)-1]
+		}
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		patterns = append(patterns, parsePathPattern(line, ""))
+	}
+	return patterns
+}
+
+// match reports whether a file matches the patterns. A pattern matches a file
+// when it matches its path or one of its directories, and the last pattern
+// matching it wins, so ! patterns unmatch it. Unlike .gitignore, files inside a
+// matched directory can be unmatched, e.g. vendor/ then !vendor/patched/.
+func (ps pathPatterns) match(file string) bool {
+	for i := len(ps) - 1; i >= 0; i-- {
+		if ps[i].matchFile(file) {
+			return !ps[i].negate
+		}
+	}
+	return false
+}
+
+// matchFile reports whether the pattern matches a file or one of its directories
+func (p *pathPattern) matchFile(file string) bool {
+	if p.match(file, false) {
+		return true
+	}
+	for i := 0; i < len(file); i++ {
+		if file[i] == '/' && p.match(file[:i], true) {
+			return true
+		}
+	}
+	return false
+}
+
+// wildmatch matches a path against a glob like git does: * and ? don't match
+// slashes, **/ matches any number of directories, a trailing /** matches
+// everything inside a directory and \ escapes the next character
+func wildmatch(pattern, path string) bool {
+	return wildmatchFrom(pattern, 0, path, 0)
+}
+
+func wildmatchFrom(p string, i int, s string, j int) bool {
+	for i < len(p) {
+		switch p[i] {
+		case '*':
+			k := i
+			for k < len(p) && p[k] == '*' {
+				k++
+			}
+			// ** is only special as a whole path component
+			if k-i >= 2 && (i == 0 || p[i-1] == '/') && (k == len(p) || p[k] == '/') {
+				if k == len(p) {
+					return true
+				}
+				for {
+					if wildmatchFrom(p, k+1, s, j) {
+						return true
+					}
+					slash := strings.IndexByte(s[j:], '/')
+					if slash < 0 {
+						return false
+					}
+					j += slash + 1
+				}
+			}
+			for ; ; j++ {
+				if wildmatchFrom(p, k, s, j) {
+					return true
+				}
+				if j == len(s) || s[j] == '/' {
+					return false
+				}
+			}
+		case '?':
+			if j == len(s) || s[j] == '/' {
+				return false
+			}
+			i++
+			j++
+		case '[':
+			if j == len(s) || s[j] == '/' {
+				return false
+			}
+			matched, end, ok := matchBracket(p, i, s[j])
+			if !ok {
+				// An unterminated bracket is a literal [
+				if s[j] != '[' {
+					return false
+				}
+				i++
+				j++
+				continue
+			}
+			if !matched {
+				return false
+			}
+			i = end
+			j++
+		case '\\':
+			if i+1 < len(p) {
+				i++
+			}
+			fallthrough
+		default:
+			if j == len(s) || s[j] != p[i] {
+				return false
+			}
+			i++
+			j++
+		}
+	}
+	return j == len(s)
+}
+
+// matchBracket matches c against the bracket expression starting at p[i],
+// such as [a-z], [!0-9] or [[:alpha:]], and returns the index after it. It
+// returns false for ok when the expression is not terminated.
+func matchBracket(p string, i int, c byte) (matched bool, end int, ok bool) {
+	k := i + 1
+	negate := k < len(p) && (p[k] == '!' || p[k] == '^')
+	if negate {
+		k++
+	}
+	for first := true; k < len(p); first = false {
+		lo := p[k]
+		switch {
+		case lo == ']' && !first:
+			return matched != negate, k + 1, true
+		case lo == '[' && strings.HasPrefix(p[k:], "[:"):
+			if close := strings.Index(p[k+2:], ":]"); close >= 0 {
+				matched = matched || matchCharClass(p[k+2:k+2+close], c)
+				k += close + 4
+				continue
+			}
+		case lo == '\\' && k+1 < len(p):
+			k++
+			lo = p[k]
+		}
+		hi := lo
+		if k+2 < len(p) && p[k+1] == '-' && p[k+2] != ']' {
+			hi = p[k+2]
+			if hi == '\\' && k+3 < len(p) {
+				hi = p[k+3]
+				k++
+			}
+			k += 2
+		}
+		if lo <= c && c <= hi {
+			matched = true
+		}
+		k++
+	}
+	return false, 0, false
+}
+
+// matchCharClass matches c against a POSIX character class such as alpha
+func matchCharClass(class string, c byte) bool {
+	switch class {
+	case "alnum":
+		return matchCharClass("alpha", c) || matchCharClass("digit", c)
+	case "alpha":
+		return matchCharClass("lower", c) || matchCharClass("upper", c)
+	case "blank":
+		return c == ' ' || c == '\t'
+	case "digit":
+		return '0' <= c && c <= '9'
+	case "lower":
+		return 'a' <= c && c <= 'z'
+	case "punct":
+		return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+	case "space":
+		return strings.IndexByte(" \t\n\r\v\f", c) >= 0
+	case "upper":
+		return 'A' <= c && c <= 'Z'
+	case "xdigit":
+		return matchCharClass("digit", c) || ('a' <= c && c <= 'f') || ('A' <= c && c <= 'F')
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWildmatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "cmd/main.go", false},
+		{"cmd/*.go", "cmd/main.go", true},
+		{"cmd/*", "cmd/sub/main.go", false},
+		{"**/*.go", "main.go", true},
+		{"**/*.go", "a/b/c/main.go", true},
+		{"docs/**", "docs/a/b.md", true},
+		{"docs/**", "docs", false},
+		{"a/**/b", "a/b", true},
+		{"a/**/b", "a/x/y/b", true},
+		{"a/**/b", "a/x/yb", false},
+		{"a**b", "a/b", false},
+		{"a**b", "axxb", true},
+		{"?.txt", "a.txt", true},
+		{"?.txt", "ab.txt", false},
+		{"[abc].txt", "b.txt", true},
+		{"[!abc].txt", "b.txt", false},
+		{"[^abc].txt", "d.txt", true},
+		{"[a-c]x", "bx", true},
+		{"[a-c]x", "dx", false},
+		{"[]]x", "]x", true},
+		{"[[:digit:]]*", "1.log", true},
+		{"[[:digit:]]*", "a.log", false},
+		{"[x", "[x", true},
+		{`\*.go`, "*.go", true},
+		{`\*.go`, "a.go", false},
+		{`\#notes`, "#notes", true},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, wildmatch(tt.pattern, tt.path), "%s %s", tt.pattern, tt.path)
+	}
+}
+
+func TestPathPatternMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		base    string
+		path    string
+		isDir   bool
+		want    bool
+	}{
+		{"*.pb.go", "", "api/v1/user.pb.go", false, true},
+		{"/main.go", "", "main.go", false, true},
+		{"/main.go", "", "cmd/main.go", false, false},
+		{"gen/", "", "api/gen", true, true},
+		{"gen/", "", "api/gen", false, false},
+		{"*.json", "web", "web/data/a.json", false, true},
+		{"*.json", "web", "api/a.json", false, false},
+		{"data/*.json", "web", "web/data/a.json", false, true},
+		{"data/*.json", "web", "data/a.json", false, false},
+	}
+	for _, tt := range tests {
+		p := parsePathPattern(tt.pattern, tt.base)
+		assert.Equal(t, tt.want, p.match(tt.path, tt.isDir), "%s in %q, %s", tt.pattern, tt.base, tt.path)
+	}
+}
+
+func TestPathPatternsMatch(t *testing.T) {
+	patterns := parseIgnore([]byte(`# generated code
+docs/**
+!docs/api/**
+build/
+*.log   
+\!important.txt
+\#notes
+`))
+	tests := []struct {
+		path  string
+		match bool
+	}{
+		{"docs/guide.md", true},
+		{"docs/api/users.md", false},
+		{"build/out.js", true},
+		{"web/build/out.js", true},
+		{"build.go", false},
+		{"logs/app.log", true},
+		{"!important.txt", true},
+		{"important.txt", false},
+		{"#notes", true},
+		{"main.go", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.match, patterns.match(tt.path), tt.path)
+	}
+
+	// Unlike .gitignore, files inside a matched directory can be unmatched
+	patterns = parseIgnore([]byte("vendor/\n!vendor/keep.go\n"))
+	assert.False(t, patterns.match("vendor/keep.go"))
+	assert.True(t, patterns.match("vendor/lib/lib.go"))
+}
@@ -0,0 +1,113 @@
+package internal
+
+import (
+	"time"
+)
+
+const (
+	HeatmapCommits = "commits"
+	HeatmapLines   = "lines"
+)
+
+const (
+	heatmapMaxWeeks = 53 // longer periods only show their last year
+	heatmapLevels   = 4  // shades used for days with activity
+)
+
+// heatmap is a calendar grid of daily activity with one column per week
+// and one row per weekday, starting on Sunday
+type heatmap struct {
+	Weeks  int
+	Cells  []heatmapCell
+	Months []heatmapMonth
+}
+
+type heatmapCell struct {
+	Week    int
+	Weekday int
+	Date    time.Time
+	Value   int
+	Level   int // 0 for no activity, up to heatmapLevels for the most active days
+}
+
+type heatmapMonth struct {
+	Week  int // column of the first week starting in the month
+	Label string
+}
+
+// newHeatmap builds the grid of the days between from and to
+func newHeatmap(stats *GitStats, from, to time.Time, metric string) *heatmap {
+	from = startOfDay(from)
+	to = startOfDay(to)
+	if to.Before(from) {
+		from, to = to, from
+	}
+
+	// Show only the last year of longer periods
+	first := from.AddDate(0, 0, -int(from.Weekday()))
+	if earliest := to.AddDate(0, 0, -int(to.Weekday())-7*(heatmapMaxWeeks-1)); first.Before(earliest) {
+		first = earliest
+		from = earliest
+	}
+
+	h := &heatmap{}
+	maxValue := 0
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		stats := stats.Days[dayKey(day)]
+		value := stats.Commits
+		if metric == HeatmapLines {
+			value = stats.Lines
+		}
+		maxValue = max(maxValue, value)
+
+		h.Cells = append(h.Cells, heatmapCell{
+			Week:    daysBetween(first, day) / 7,
+			Weekday: int(day.Weekday()),
+			Date:    day,
+			Value:   value,
+		})
+	}
+
+	for i := range h.Cells {
+		cell := &h.Cells[i]
+		if cell.Value > 0 {
+			// Round up so that every active day is visible
+			cell.Level = (cell.Value*heatmapLevels + maxValue - 1) / maxValue
+		}
+		h.Weeks = max(h.Weeks, cell.Week+1)
+	}
+
+	// Label each month above its first full week
+	lastWeek := -heatmapMaxWeeks
+	for i, cell := range h.Cells {
+		if i > 0 && cell.Date.Day() != 1 {
+			continue
+		}
+		week := cell.Week
+		if cell.Weekday != 0 {
+			week++
+		}
+		// Skip labels that would overlap with the previous one
+		if week-lastWeek < 3 || week >= h.Weeks {
+			continue
+		}
+		h.Months = append(h.Months, heatmapMonth{
+			Week:  week,
+			Label: cell.Date.Format("Jan"),
+		})
+		lastWeek = week
+	}
+
+	return h
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// daysBetween returns the number of calendar days from a to b, ignoring DST changes
+func daysBetween(a, b time.Time) int {
+	ua := time.Date(a.Year(), a.Month(), a.Day(), 0, 0, 0, 0, time.UTC)
+	ub := time.Date(b.Year(), b.Month(), b.Day(), 0, 0, 0, 0, time.UTC)
+	return int(ub.Sub(ua).Hours() / 24)
+}
@@ -0,0 +1,60 @@
+package internal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewHeatmap(t *testing.T) {
+	stats := &GitStats{
+		Days: map[string]DayStats{
+			"2025-03-01": {Commits: 1, Lines: 100},
+			"2025-03-04": {Commits: 4, Lines: 10},
+			"2025-04-02": {Commits: 2, Lines: 1},
+		},
+	}
+	from := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)  // Saturday
+	to := time.Date(2025, 4, 15, 18, 30, 0, 0, time.UTC) // Tuesday
+
+	h := newHeatmap(stats, from, to, HeatmapCommits)
+	assert.Equal(t, 8, h.Weeks)
+	assert.Len(t, h.Cells, 46)
+	assert.Equal(t, []heatmapMonth{
+		{Week: 1, Label: "Mar"},
+		{Week: 6, Label: "Apr"},
+	}, h.Months)
+
+	first := h.Cells[0]
+	assert.Equal(t, 0, first.Week)
+	assert.Equal(t, int(time.Saturday), first.Weekday)
+	assert.Equal(t, 1, first.Level)
+
+	levels := map[string]int{}
+	for _, cell := range h.Cells {
+		if cell.Value > 0 {
+			levels[cell.Date.Format(time.DateOnly)] = cell.Level
+		}
+	}
+	assert.Equal(t, map[string]int{"2025-03-01": 1, "2025-03-04": 4, "2025-04-02": 2}, levels)
+
+	h = newHeatmap(stats, from, to, HeatmapLines)
+	levels = map[string]int{}
+	for _, cell := range h.Cells {
+		if cell.Value > 0 {
+			levels[cell.Date.Format(time.DateOnly)] = cell.Level
+		}
+	}
+	assert.Equal(t, map[string]int{"2025-03-01": 4, "2025-03-04": 1, "2025-04-02": 1}, levels)
+}
+
+func TestNewHeatmapLimitsToLastYear(t *testing.T) {
+	from := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC)
+
+	h := newHeatmap(&GitStats{}, from, to, HeatmapCommits)
+	assert.Equal(t, heatmapMaxWeeks, h.Weeks)
+	assert.Equal(t, to, h.Cells[len(h.Cells)-1].Date)
+	assert.Equal(t, time.Sunday, h.Cells[0].Date.Weekday())
+}
@@ -0,0 +1,152 @@
+package internal
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// JSONSchemaVersion is the version of the JSON document written by --format json.
+// It is bumped whenever a field is renamed, removed or changes its meaning.
+// Adding new fields does not change the version.
+const JSONSchemaVersion = 1
+
+type JSONReport struct {
+	SchemaVersion int           `json:"schemaVersion"`
+	DateRange     JSONDateRange `json:"dateRange"`
+	Repositories  int           `json:"repositories"`
+	RefSelection  []string      `json:"refSelection"` // requested refs, e.g. branches or origin/*
+	Refs          []string      `json:"refs"`         // names of the refs read in any repository
+	JSONStats
+	Repos   []JSONRepo   `json:"repos,omitempty"`   // only with --by-repo
+	Authors []JSONAuthor `json:"authors,omitempty"` // only with --by-author, ranked by lines changed
+}
+
+type JSONRepo struct {
+	Path string   `json:"path"`
+	Name string   `json:"name"`
+	Refs []string `json:"refs"` // names of the refs the commits were read from
+	JSONStats
+}
+
+type JSONAuthor struct {
+	Rank  int    `json:"rank"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	JSONStats
+}
+
+type JSONDateRange struct {
+	Since *time.Time `json:"since"` // null when not set
+	Until *time.Time `json:"until"` // null when not set
+	Label string     `json:"label"` // same label as the text and PNG output
+}
+
+type JSONStats struct {
+	FilesChanged int            `json:"filesChanged"`
+	Insertions   int            `json:"insertions"`
+	Deletions    int            `json:"deletions"`
+	MovedLines   int            `json:"movedLines,omitempty"`  // only with --moved, lines kept by renamed and copied files
+	BinaryFiles  int            `json:"binaryFiles,omitempty"` // files git considers binary, they have no line counts
+	BinaryBytes  int            `json:"binaryBytes,omitempty"` // only with --binary-bytes, size change of the binary files
+	Commits      int            `json:"commits"`
+	ActiveDays   int            `json:"activeDays"`  // distinct days with at least one commit
+	FirstCommit  *time.Time     `json:"firstCommit"` // null when there are no commits
+	LastCommit   *time.Time     `json:"lastCommit"`  // null when there are no commits
+	Languages    map[string]int `json:"languages"`   // lines changed per language
+}
+
+func NewJSONReport(r *Report, opts *RunOptions) *JSONReport {
+	stats := &r.Total
+	report := &JSONReport{
+		SchemaVersion: JSONSchemaVersion,
+		DateRange: JSONDateRange{
+			Label: r.DateRange,
+		},
+		Repositories: stats.Repositories,
+		RefSelection: nonNil(r.RefSelection),
+		Refs:         nonNil(r.Refs),
+		JSONStats:    newJSONStats(stats),
+	}
+	if !r.Since.IsZero() {
+		since := r.Since
+		report.DateRange.Since = &since
+	}
+	if !r.Until.IsZero() {
+		until := r.Until
+		report.DateRange.Until = &until
+	}
+	if opts.ByRepo {
+		report.Repos = make([]JSONRepo, 0, len(r.Repos))
+		for i := range r.Repos {
+			report.Repos = append(report.Repos, JSONRepo{
+				Path:      r.Repos[i].Path,
+				Name:      r.Repos[i].Name,
+				Refs:      nonNil(r.Repos[i].Refs),
+				JSONStats: newJSONStats(&r.Repos[i].Stats),
+			})
+		}
+	}
+	if opts.ByAuthor {
+		report.Authors = make([]JSONAuthor, 0, len(r.Authors))
+		for i := range r.Authors {
+			report.Authors = append(report.Authors, JSONAuthor{
+				Rank:      i + 1,
+				Name:      r.Authors[i].Name,
+				Email:     r.Authors[i].Email,
+				JSONStats: newJSONStats(&r.Authors[i].Stats),
+			})
+		}
+	}
+	return report
+}
+
+// nonNil returns an empty list for nil, so that lists are never null in the document
+func nonNil(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
+
+func newJSONStats(stats *GitStats) JSONStats {
+	languages := make(map[string]int, len(stats.Languages))
+	for lang, lines := range stats.Languages {
+		languages[lang] = lines
+	}
+	jsonStats := JSONStats{
+		FilesChanged: stats.FilesChanged,
+		Insertions:   stats.Insertions,
+		Deletions:    stats.Deletions,
+		MovedLines:   stats.MovedLines,
+		BinaryFiles:  stats.BinaryFiles,
+		BinaryBytes:  stats.BinaryBytes,
+		Commits:      stats.Commits,
+		ActiveDays:   stats.ActiveDays(),
+		Languages:    languages,
+	}
+	if !stats.FirstCommit.IsZero() {
+		firstCommit := stats.FirstCommit
+		jsonStats.FirstCommit = &firstCommit
+	}
+	if !stats.LastCommit.IsZero() {
+		lastCommit := stats.LastCommit
+		jsonStats.LastCommit = &lastCommit
+	}
+	return jsonStats
+}
+
+// WriteJSON writes the report as indented JSON
+func WriteJSON(w io.Writer, report *JSONReport) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+// JSONRenderer writes the report as a JSON document, also when no
+// repositories were found so that consumers always get a valid document
+type JSONRenderer struct{}
+
+func (r *JSONRenderer) Render(w io.Writer, report *Report, opts *RunOptions) error {
+	return WriteJSON(w, NewJSONReport(report, opts))
+}
@@ -0,0 +1,158 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// mailmap maps the identities authors committed with to their canonical
+// identity, with the syntax of the .mailmap files of git:
+//
+//	Proper Name <commit@email>
+//	<proper@email> <commit@email>
+//	Proper Name <proper@email> <commit@email>
+//	Proper Name <proper@email> Commit Name <commit@email>
+type mailmap struct {
+	entries map[string]mailmapEntry // by commit email and name, lowercased
+}
+
+// mailmapEntry is the canonical identity of a commit identity, an empty name
+// or email keeps the one of the commit
+type mailmapEntry struct {
+	name  string
+	email string
+}
+
+// DefaultMailmapFile returns the path of the mailmap applied to every
+// repository, gitbrag/mailmap under $XDG_CONFIG_HOME or under the user
+// config directory of the platform
+func DefaultMailmapFile() (string, error) {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		var err error
+		dir, err = os.UserConfigDir()
+		if err != nil {
+			return "", err
+		}
+	}
+	return filepath.Join(dir, "gitbrag", "mailmap"), nil
+}
+
+// readMailmap reads a mailmap file
+func readMailmap(path string) (*mailmap, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseMailmap(data), nil
+}
+
+// readRepoMailmap reads the .mailmap at the root of a repository, it returns
+// nil when there is none
+func readRepoMailmap(dir string) (*mailmap, error) {
+	m, err := readMailmap(filepath.Join(dir, ".mailmap"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return m, err
+}
+
+// parseMailmap parses the lines of a mailmap, later lines override earlier
+// ones for the same commit identity. Lines without an email are skipped.
+func parseMailmap(data []byte) *mailmap {
+	m := &mailmap{entries: make(map[string]mailmapEntry)}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		name1, email1, rest, ok := cutMailmapIdentity(line)
+		if !ok {
+			continue
+		}
+		name2, email2, _, ok := cutMailmapIdentity(rest)
+		if !ok {
+			// Proper Name <commit@email>
+			m.entries[mailmapKey("", email1)] = mailmapEntry{name: name1}
+			continue
+		}
+		m.entries[mailmapKey(name2, email2)] = mailmapEntry{name: name1, email: email1}
+	}
+	return m
+}
+
+// cutMailmapIdentity cuts "Name <email>" at the start of s, the name may be
+// empty, and returns what follows
+func cutMailmapIdentity(s string) (name, email, rest string, ok bool) {
+	start := strings.IndexByte(s, '<')
+	if start < 0 {
+		return "", "", "", false
+	}
+	end := strings.IndexByte(s[start:], '>')
+	if end < 0 {
+		return "", "", "", false
+	}
+	end += start
+	return strings.TrimSpace(s[:start]), strings.TrimSpace(s[start+1 : end]), s[end+1:], true
+}
+
+func mailmapKey(name, email string) string {
+	return strings.ToLower(email) + "\x00" + strings.ToLower(name)
+}
+
+// lookup returns the canonical identity of an author and whether the mailmap
+// has it. Entries with the commit name win over the ones with only the email,
+// names and emails are compared ignoring case.
+func (m *mailmap) lookup(name, email string) (string, string, bool) {
+	if m == nil {
+		return name, email, false
+	}
+	entry, ok := m.entries[mailmapKey(name, email)]
+	if !ok {
+		entry, ok = m.entries[mailmapKey("", email)]
+	}
+	if !ok {
+		return name, email, false
+	}
+	if entry.name != "" {
+		name = entry.name
+	}
+	if entry.email != "" {
+		email = entry.email
+	}
+	return name, email, true
+}
+
+// mapAuthors replaces the authors and co-authors of the commits with their
+// canonical identity, from the mailmap of the repository and then the global
+// one. The global mailmap wins, it may map the commit identity or the one of
+// the repository mailmap.
+func mapAuthors(commits []commit, repo, global *mailmap) {
+	if repo == nil && global == nil {
+		return
+	}
+	for i := range commits {
+		c := &commits[i]
+		c.AuthorName, c.AuthorEmail = mapIdentity(c.AuthorName, c.AuthorEmail, repo, global)
+		for j, coauthor := range c.CoAuthors {
+			id := parseAuthorIdentity(coauthor)
+			id.name, id.email = mapIdentity(id.name, id.email, repo, global)
+			c.CoAuthors[j] = id.String()
+		}
+	}
+}
+
+func mapIdentity(name, email string, repo, global *mailmap) (string, string) {
+	if mappedName, mappedEmail, ok := global.lookup(name, email); ok {
+		return mappedName, mappedEmail
+	}
+	name, email, _ = repo.lookup(name, email)
+	name, email, _ = global.lookup(name, email)
+	return name, email
+}
@@ -0,0 +1,56 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMailmapLookup(t *testing.T) {
+	m := parseMailmap([]byte(`# comments and lines without an email are skipped
+no email here
+Jane Roe <jane@old.example.com>
+<jane@example.com> <Jane@Laptop.local>
+Jane Roe <jane@example.com> <root@localhost>
+John Doe <john@example.com> john <root@localhost>
+`))
+	tests := []struct {
+		name, email         string
+		wantName, wantEmail string
+		found               bool
+	}{
+		{"jane", "JANE@old.example.com", "Jane Roe", "JANE@old.example.com", true},
+		{"Jane", "jane@laptop.local", "Jane", "jane@example.com", true},
+		{"root", "root@localhost", "Jane Roe", "jane@example.com", true},
+		{"John", "root@localhost", "John Doe", "john@example.com", true},
+		{"Alex", "alex@example.com", "Alex", "alex@example.com", false},
+	}
+	for _, tt := range tests {
+		name, email, found := m.lookup(tt.name, tt.email)
+		assert.Equal(t, []any{tt.wantName, tt.wantEmail, tt.found}, []any{name, email, found}, "%s <%s>", tt.name, tt.email)
+	}
+}
+
+func TestMapAuthors(t *testing.T) {
+	repo := parseMailmap([]byte("Jane Roe <jane@example.com> <jane@laptop.local>\n"))
+	global := parseMailmap([]byte("<jane@personal.example.com> <jane@example.com>\nJohn Doe <john@example.com> <root@localhost>\n"))
+	commits := []commit{
+		{AuthorName: "jane", AuthorEmail: "jane@laptop.local"},
+		{AuthorName: "Jane Roe", AuthorEmail: "jane@example.com"},
+		{AuthorName: "root", AuthorEmail: "root@localhost"},
+		{AuthorName: "Alex", AuthorEmail: "alex@example.com", CoAuthors: []string{"jane <jane@laptop.local>", "Bob <bob@example.com>"}},
+	}
+
+	mapAuthors(commits, repo, global)
+	var authors []string
+	for _, c := range commits {
+		authors = append(authors, c.AuthorName+" <"+c.AuthorEmail+">")
+	}
+	assert.Equal(t, []string{
+		"Jane Roe <jane@personal.example.com>",
+		"Jane Roe <jane@personal.example.com>",
+		"John Doe <john@example.com>",
+		"Alex <alex@example.com>",
+	}, authors)
+	assert.Equal(t, []string{"Jane Roe <jane@personal.example.com>", "Bob <bob@example.com>"}, commits[3].CoAuthors)
+}
@@ -0,0 +1,202 @@
+package internal
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/radulucut/gitbrag/internal/gitobj"
+	"github.com/radulucut/gitbrag/internal/utils"
+)
+
+// Backends reading the commits of a repository
+const (
+	BackendExec   = "exec"   // runs git log, the default
+	BackendNative = "native" // reads the object database directly, without a git binary
+)
+
+// Backends returns the supported backends
+func Backends() []string {
+	return []string{BackendExec, BackendNative}
+}
+
+// logBounds limits the history read from the refs, nil reads all of it
+type logBounds struct {
+	since time.Time       // commits committed before, and their parents, aren't read, like git log --since
+	from  []gitRef        // refs whose history was already read, each must be in the history of the refs
+	known map[string]bool // commits of the history of from, which aren't read again
+}
+
+// errRefsRewound is returned when one of logBounds.from isn't in the history
+// of the refs anymore, so the history already read isn't all kept
+var errRefsRewound = errors.New("refs rewound")
+
+// readCommits reads the commits of a repository with the backend of the options
+func readCommits(ctx context.Context, dir string, opts *GitStatsOptions, refs []gitRef, bounds *logBounds) ([]commit, error) {
+	if bounds == nil {
+		bounds = &logBounds{}
+	}
+	if opts.Backend == BackendNative {
+		return readNativeLog(ctx, dir, opts, refs, bounds)
+	}
+	return readGitLog(ctx, dir, opts, refs, bounds)
+}
+
+// readNativeLog reads the commits of the refs like readGitLog, walking the
+// history from the object database instead of running git log
+func readNativeLog(ctx context.Context, dir string, opts *GitStatsOptions, refs []gitRef, bounds *logBounds) ([]commit, error) {
+	repo, err := gitobj.Open(dir)
+	if err != nil {
+		return nil, utils.NewInternalError("failed to open git repository: " + err.Error())
+	}
+	defer repo.Close()
+	readBlob := func(hash string) ([]byte, error) {
+		h, err := gitobj.NewHash(hash)
+		if err != nil {
+			return nil, err
+		}
+		return repo.Blob(h)
+	}
+
+	// Walk from the most recent commit like git log
+	queue := &commitQueue{}
+	seen := make(map[gitobj.Hash]bool)
+	push := func(h gitobj.Hash) error {
+		if seen[h] {
+			return nil
+		}
+		seen[h] = true
+		if bounds.known[h.String()] {
+			return nil
+		}
+		c, err := repo.Commit(h)
+		if err != nil {
+			return err
+		}
+		heap.Push(queue, c)
+		return nil
+	}
+	for _, ref := range refs {
+		h, err := gitobj.NewHash(ref.Hash)
+		if err != nil {
+			return nil, utils.NewInternalError("invalid ref " + ref.Name + ": " + err.Error())
+		}
+		if err := push(h); err != nil {
+			return nil, utils.NewInternalError("failed to read commit: " + err.Error())
+		}
+	}
+
+	var commits []commit
+	for queue.Len() > 0 {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		c := heap.Pop(queue).(*gitobj.Commit)
+		// Like git log --since, the history isn't walked past older commits
+		if !bounds.since.IsZero() && c.Committer.When.Unix() < bounds.since.Unix() {
+			continue
+		}
+		parents := c.Parents
+		if opts.Merges == MergesFirstParent && len(parents) > 1 {
+			parents = parents[:1]
+		}
+		for _, parent := range parents {
+			if err := push(parent); err != nil {
+				return nil, utils.NewInternalError("failed to read commit: " + err.Error())
+			}
+		}
+		merge := len(c.Parents) > 1
+		if merge && opts.Merges == MergesExclude {
+			continue
+		}
+
+		_, authorZone := c.Author.When.Zone()
+		entry := commit{
+			Hash:        c.Hash.String(),
+			AuthorName:  c.Author.Name,
+			AuthorEmail: c.Author.Email,
+			Date:        time.Unix(c.Committer.When.Unix(), 0),
+			AuthorDate:  time.Unix(c.Author.When.Unix(), 0),
+			AuthorZone:  authorZone,
+			CoAuthors:   trailerValues(c.Message, coauthorTrailer),
+			merge:       merge,
+		}
+		// Merges are shown without a diff unless they are diffed against their
+		// first parent, root commits add all their files
+		if !merge || opts.Merges == MergesInclude || opts.Merges == MergesFirstParent {
+			parentTree := gitobj.ZeroHash
+			if len(c.Parents) > 0 {
+				parent, err := repo.Commit(c.Parents[0])
+				if err != nil {
+					return nil, utils.NewInternalError("failed to read commit: " + err.Error())
+				}
+				parentTree = parent.Tree
+			}
+			changes, err := repo.DiffTrees(parentTree, c.Tree)
+			if err != nil {
+				return nil, utils.NewInternalError("failed to diff commit " + entry.Hash + ": " + err.Error())
+			}
+			var raw []rawChange
+			for _, change := range changes {
+				file := fileChange{
+					Path:       change.Path,
+					OldPath:    change.OldPath,
+					Insertions: change.Insertions,
+					Deletions:  change.Deletions,
+					Binary:     change.Binary,
+				}
+				if opts.Moved {
+					file.Moved = change.Moved
+				}
+				if opts.BinaryBytes && change.Binary {
+					file.SizeDelta = change.NewSize - change.OldSize
+				}
+				entry.Files = append(entry.Files, file)
+				if opts.Dedupe {
+					raw = append(raw, rawChange{
+						Path:    change.Path,
+						OldPath: change.OldPath,
+						OldMode: change.OldMode,
+						NewMode: change.NewMode,
+						OldHash: change.OldHash.String(),
+						NewHash: change.NewHash.String(),
+					})
+				}
+			}
+			if opts.Dedupe && !merge {
+				entry.PatchID, err = patchID(raw, readBlob)
+				if err != nil {
+					return nil, utils.NewInternalError("failed to read patch of commit " + entry.Hash + ": " + err.Error())
+				}
+			}
+		}
+		commits = append(commits, entry)
+	}
+
+	// The walk reaches the refs already read that are still in the history
+	for _, ref := range bounds.from {
+		h, err := gitobj.NewHash(ref.Hash)
+		if err != nil || !seen[h] {
+			return nil, errRefsRewound
+		}
+	}
+	return commits, nil
+}
+
+// commitQueue orders commits from the most recently committed, like the
+// default order of git log
+type commitQueue []*gitobj.Commit
+
+func (q commitQueue) Len() int { return len(q) }
+func (q commitQueue) Less(i, j int) bool {
+	return q[i].Committer.When.After(q[j].Committer.When)
+}
+func (q commitQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *commitQueue) Push(x any)   { *q = append(*q, x.(*gitobj.Commit)) }
+func (q *commitQueue) Pop() any {
+	old := *q
+	c := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return c
+}
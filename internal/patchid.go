@@ -0,0 +1,214 @@
+package internal
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/radulucut/gitbrag/internal/gitobj"
+)
+
+// rawChange is a changed file with the objects on both sides, as printed by git log --raw
+type rawChange struct {
+	Path    string
+	OldPath string // path the file was renamed or copied from, empty otherwise
+	OldMode uint32 // zero for added files
+	NewMode uint32 // zero for deleted files
+	OldHash string // zeros for added files
+	NewHash string // zeros for deleted files
+}
+
+const modeSubmodule = 0o160000
+
+// parseRawChange parses a line of git log --raw --no-abbrev,
+// ":100644 100644 <old> <new> M<TAB>path", renames and copies have both
+// paths, ":100644 100644 <old> <new> R090<TAB>old<TAB>new"
+func parseRawChange(line string) (rawChange, bool) {
+	meta, path, ok := strings.Cut(strings.TrimPrefix(line, ":"), "\t")
+	fields := strings.Fields(meta)
+	if !ok || len(fields) < 5 {
+		return rawChange{}, false
+	}
+	oldMode, err1 := strconv.ParseUint(fields[0], 8, 32)
+	newMode, err2 := strconv.ParseUint(fields[1], 8, 32)
+	if err1 != nil || err2 != nil {
+		return rawChange{}, false
+	}
+	var oldPath string
+	if fields[4][0] == 'R' || fields[4][0] == 'C' {
+		oldPath, path, _ = strings.Cut(path, "\t")
+		oldPath = unquotePath(oldPath)
+	}
+	return rawChange{
+		Path:    unquotePath(path),
+		OldPath: oldPath,
+		OldMode: uint32(oldMode),
+		NewMode: uint32(newMode),
+		OldHash: fields[2],
+		NewHash: fields[3],
+	}, true
+}
+
+// patchID identifies the change made by a commit independently of the commit
+// it was applied on, so that cherry-picked and rebased copies get the same
+// identity. Like git patch-id --stable, for each file it hashes the lines
+// removed and added, in the order of the diff and without line numbers or
+// context, so the change hashes the same whatever the base around it is.
+// Binary files are identified by their objects. Commits without changes,
+// such as merges, have no identity.
+func patchID(changes []rawChange, readBlob func(hash string) ([]byte, error)) (string, error) {
+	if len(changes) == 0 {
+		return "", nil
+	}
+	changes = append([]rawChange(nil), changes...)
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+
+	sum := sha1.New()
+	for _, change := range changes {
+		fmt.Fprintf(sum, "%s\x00%s\x00%o %o\x00", change.OldPath, change.Path, change.OldMode, change.NewMode)
+		oldData, err := readRawObject(change.OldMode, change.OldHash, readBlob)
+		if err != nil {
+			return "", err
+		}
+		newData, err := readRawObject(change.NewMode, change.NewHash, readBlob)
+		if err != nil {
+			return "", err
+		}
+		if gitobj.IsBinary(oldData) || gitobj.IsBinary(newData) {
+			fmt.Fprintf(sum, "binary %s %s\x00", change.OldHash, change.NewHash)
+			continue
+		}
+
+		gitobj.DiffLines(oldData, newData, func(op byte, line []byte) {
+			fmt.Fprintf(sum, "%c%s\x00", op, line)
+		})
+	}
+	return hex.EncodeToString(sum.Sum(nil)), nil
+}
+
+// readRawObject returns the content of a side of a change, nil when the file
+// is missing and the line git diffs for submodules
+func readRawObject(mode uint32, hash string, readBlob func(hash string) ([]byte, error)) ([]byte, error) {
+	switch mode {
+	case 0:
+		return nil, nil
+	case modeSubmodule:
+		h, err := gitobj.NewHash(hash)
+		if err != nil {
+			return nil, err
+		}
+		return gitobj.SubmoduleContent(h), nil
+	}
+	return readBlob(hash)
+}
+
+// catFile reads objects from a running git cat-file, with --batch to read
+// their content or with --batch-check to only read their size
+type catFile struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+}
+
+func newCatFile(ctx context.Context, dir, mode string) (*catFile, error) {
+	cmd := exec.CommandContext(ctx, "git", "cat-file", mode)
+	cmd.Dir = dir
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &catFile{cmd: cmd, stdin: stdin, stdout: bufio.NewReader(stdout)}, nil
+}
+
+// read returns the content of an object with --batch, git flushes its output
+// after each object
+func (c *catFile) read(hash string) ([]byte, error) {
+	size, err := c.size(hash)
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, size+1) // followed by a newline
+	if _, err := io.ReadFull(c.stdout, data); err != nil {
+		return nil, err
+	}
+	return data[:size], nil
+}
+
+// size returns the size of an object from its header, "<hash> <type> <size>",
+// which is all --batch-check prints
+func (c *catFile) size(hash string) (int, error) {
+	if _, err := io.WriteString(c.stdin, hash+"\n"); err != nil {
+		return 0, err
+	}
+	header, err := c.stdout.ReadString('\n')
+	if err != nil {
+		return 0, err
+	}
+	fields := strings.Fields(header)
+	if len(fields) != 3 {
+		return 0, fmt.Errorf("could not read object %s: %s", hash, strings.TrimSpace(header))
+	}
+	return strconv.Atoi(fields[2])
+}
+
+func (c *catFile) Close() error {
+	c.stdin.Close()
+	return c.cmd.Wait()
+}
+
+// dropDuplicatePatches keeps a single copy of each patch across the commits
+// of all repositories, the earliest one, and returns the commits without the
+// copies. Ties are broken by the order of the repositories and the hash.
+// Commits without a patch, such as merges, are only copies of the same commit
+// in another repository, e.g. a mirror, so they are identified by their hash.
+func dropDuplicatePatches(repos [][]commit) [][]commit {
+	type copyRef struct{ repo, index int }
+	identity := func(c commit) string {
+		if c.PatchID == "" {
+			return "commit " + c.Hash
+		}
+		return c.PatchID
+	}
+	kept := make(map[string]copyRef)
+	for r, commits := range repos {
+		for i, c := range commits {
+			id := identity(c)
+			prev, ok := kept[id]
+			if !ok {
+				kept[id] = copyRef{r, i}
+				continue
+			}
+			p := repos[prev.repo][prev.index]
+			if c.Date.Before(p.Date) || (c.Date.Equal(p.Date) && r == prev.repo && c.Hash < p.Hash) {
+				kept[id] = copyRef{r, i}
+			}
+		}
+	}
+
+	result := make([][]commit, len(repos))
+	for r, commits := range repos {
+		for i, c := range commits {
+			if kept[identity(c)] != (copyRef{r, i}) {
+				continue
+			}
+			result[r] = append(result[r], c)
+		}
+	}
+	return result
+}
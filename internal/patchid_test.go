@@ -0,0 +1,76 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPatchID(t *testing.T) {
+	blobs := map[string]string{
+		"base":       "a\nb\nc\n",
+		"edited":     "a\nB\nc\n",
+		"other-base": "x\na\nb\nc\ny\n",
+		"other-edit": "x\na\nB\nc\ny\n",
+		"unrelated":  "a\nb\nC\n",
+		"first-last": "c\na\nb\n",
+		"last-first": "b\nc\na\n",
+	}
+	read := func(hash string) ([]byte, error) {
+		return []byte(blobs[hash]), nil
+	}
+	edit := func(oldBlob, newBlob string) string {
+		id, err := patchID([]rawChange{{Path: "main.go", OldMode: 0o100644, NewMode: 0o100644, OldHash: oldBlob, NewHash: newBlob}}, read)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+
+	// The same change applied on another version of the file has the same identity
+	assert.Equal(t, edit("base", "edited"), edit("other-base", "other-edit"))
+	assert.NotEqual(t, edit("base", "edited"), edit("base", "unrelated"))
+	assert.NotEqual(t, edit("base", "edited"), edit("edited", "base"))
+
+	// Moving different lines of a file makes different changes, even though the
+	// file ends up with the same lines
+	assert.NotEqual(t, edit("base", "first-last"), edit("base", "last-first"))
+
+	// Commits without changes, such as merges, are never the same patch
+	id, err := patchID(nil, read)
+	assert.NoError(t, err)
+	assert.Empty(t, id)
+
+	// Submodules are identified by the commit they point to
+	submodule := func(h string) rawChange {
+		return rawChange{Path: "sub", NewMode: modeSubmodule, OldHash: strings.Repeat("0", 40), NewHash: h}
+	}
+	first, err := patchID([]rawChange{submodule(strings.Repeat("a", 40))}, read)
+	assert.NoError(t, err)
+	second, err := patchID([]rawChange{submodule(strings.Repeat("b", 40))}, read)
+	assert.NoError(t, err)
+	assert.NotEqual(t, first, second)
+}
+
+func TestDropDuplicatePatches(t *testing.T) {
+	date := time.Date(2025, 3, 10, 10, 0, 0, 0, time.UTC)
+	original := commit{Hash: "a1", PatchID: "fix", Date: date}
+	backport := commit{Hash: "b1", PatchID: "fix", Date: date.Add(time.Hour)}
+	merge := commit{Hash: "c1", Date: date.Add(2 * time.Hour)}
+	other := commit{Hash: "d1", PatchID: "feature", Date: date}
+
+	// The earliest copy is kept, mirrors of a repository keep the first one,
+	// also of the merges
+	repos := dropDuplicatePatches([][]commit{
+		{merge, backport},
+		{original, other, merge},
+		{original, other},
+	})
+	assert.Equal(t, [][]commit{
+		{merge},
+		{original, other},
+		nil,
+	}, repos)
+}
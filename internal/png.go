@@ -6,7 +6,7 @@ import (
 	"image/color"
 	"image/draw"
 	"image/png"
-	"os"
+	"io"
 	"sort"
 	"strconv"
 	"strings"
@@ -18,11 +18,12 @@ import (
 )
 
 type PNGRenderer struct {
-	width    int
-	height   int
-	bg       color.Color
-	fg       color.Color
-	fontFace font.Face
+	width         int
+	height        int
+	bg            color.Color
+	fg            color.Color
+	fontFace      font.Face
+	smallFontFace font.Face // used for the heatmap labels
 }
 
 func NewPNGRenderer() *PNGRenderer {
@@ -32,13 +33,18 @@ func NewPNGRenderer() *PNGRenderer {
 		// Fallback to basicfont if custom font fails
 		fontFace = basicfont.Face7x13
 	}
+	smallFontFace, err := utils.LoadFont(14)
+	if err != nil {
+		smallFontFace = basicfont.Face7x13
+	}
 
 	return &PNGRenderer{
-		width:    800,                      // Increased resolution for better quality
-		height:   800,                      // Increased resolution for better quality
-		bg:       color.RGBA{0, 0, 0, 0},   // Transparent by default
-		fg:       color.RGBA{0, 0, 0, 255}, // Black text by default
-		fontFace: fontFace,
+		width:         800,                      // Increased resolution for better quality
+		height:        800,                      // Increased resolution for better quality
+		bg:            color.RGBA{0, 0, 0, 0},   // Transparent by default
+		fg:            color.RGBA{0, 0, 0, 255}, // Black text by default
+		fontFace:      fontFace,
+		smallFontFace: smallFontFace,
 	}
 }
 
@@ -60,82 +66,159 @@ func (r *PNGRenderer) SetForegroundFromHex(hexColor string) error {
 	return nil
 }
 
-func (r *PNGRenderer) RenderToFile(stats *GitStats, opts *RunOptions) error {
+// Render draws the statistics card and writes it as a PNG image
+func (r *PNGRenderer) Render(w io.Writer, report *Report, opts *RunOptions) error {
 	if r.fontFace == nil {
 		return fmt.Errorf("font not loaded")
 	}
 
-	if opts.Lang && len(stats.Languages) > 0 {
-		r.height = 950 // Add extra space for language bar and labels
-	}
-
-	img := image.NewRGBA(image.Rect(0, 0, r.width, r.height))
+	l := layoutCard(r.fontFace, r.smallFontFace, r.width, r.height, r.fg, report, opts)
+	img := image.NewRGBA(image.Rect(0, 0, l.Width, l.Height))
 
 	// Fill background more efficiently
 	draw.Draw(img, img.Bounds(), &image.Uniform{r.bg}, image.Point{}, draw.Src)
 
+	for _, line := range l.Lines {
+		r.drawTextAntialiased(img, line.Text, line.X, line.Y, line.Color)
+	}
+
+	for _, section := range l.Sections {
+		switch section.Kind {
+		case sectionLanguages:
+			r.drawLanguageBar(img, &report.Total, section.Y)
+		case sectionHeatmap:
+			r.drawHeatmap(img, section.Heatmap, section.Y)
+		case sectionRows:
+			r.drawRowsSection(img, section.Rows, section.Y)
+		}
+	}
+
+	if err := png.Encode(w, img); err != nil {
+		return fmt.Errorf("failed to encode PNG: %w", err)
+	}
+
+	return nil
+}
+
+// statLines returns the files changed, insertions, deletions and binary files
+// lines of the card, padded so that the numbers are aligned when the lines are
+// centered. The binary files line is empty when no binary file changed.
+func statLines(stats *GitStats, binaryBytes bool) (string, string, string, string) {
 	// add start padding to align numbers
 	filesStr := fmt.Sprint(stats.FilesChanged)
 	insertionsStr := fmt.Sprint(stats.Insertions)
 	deletionsStr := fmt.Sprint(stats.Deletions)
+	binaryStr := fmt.Sprint(stats.BinaryFiles)
 
 	maxLen := max(len(filesStr), len(insertionsStr), len(deletionsStr))
+	if stats.BinaryFiles > 0 {
+		maxLen = max(maxLen, len(binaryStr))
+	}
 
 	filesStr = fmt.Sprintf("%*s files changed", maxLen, filesStr)
 	insertionsStr = fmt.Sprintf("%*s insertions(+)", maxLen, insertionsStr)
 	deletionsStr = fmt.Sprintf("%*s deletions(-)", maxLen, deletionsStr)
+	if stats.BinaryFiles > 0 {
+		binaryStr = fmt.Sprintf("%*s %s", maxLen, binaryStr, binaryFilesLabel(stats, binaryBytes))
+	} else {
+		binaryStr = ""
+	}
 
 	// add end padding to center text
-	maxLen = max(maxLen, len(filesStr), len(insertionsStr), len(deletionsStr))
+	maxLen = max(maxLen, len(filesStr), len(insertionsStr), len(deletionsStr), len(binaryStr))
 	filesStr = fmt.Sprintf("%-*s", maxLen, filesStr)
 	insertionsStr = fmt.Sprintf("%-*s", maxLen, insertionsStr)
 	deletionsStr = fmt.Sprintf("%-*s", maxLen, deletionsStr)
+	if binaryStr != "" {
+		binaryStr = fmt.Sprintf("%-*s", maxLen, binaryStr)
+	}
 
-	greenColor := color.RGBA{26, 127, 55, 255} // Green for insertions
-	redColor := color.RGBA{209, 36, 47, 255}   // Red for deletions
+	return filesStr, insertionsStr, deletionsStr, binaryStr
+}
 
-	// Draw date range if available
-	yOffset := 280
-	if opts.DateRange != "" {
-		// Calculate text width to center it
-		textWidth := font.MeasureString(r.fontFace, opts.DateRange).Ceil()
-		dateRangeX := (r.width - textWidth) / 2
-		r.drawTextAntialiased(img, opts.DateRange, dateRangeX, yOffset, r.fg)
+// binaryFilesLabel returns the label following the number of binary files,
+// with their size change when it was counted
+func binaryFilesLabel(stats *GitStats, binaryBytes bool) string {
+	label := "binary files"
+	if stats.BinaryFiles == 1 {
+		label = "binary file"
 	}
+	if binaryBytes {
+		label += " (" + formatByteDelta(stats.BinaryBytes) + ")"
+	}
+	return label
+}
 
-	// Center each stat line
-	filesWidth := font.MeasureString(r.fontFace, filesStr).Ceil()
-	filesX := (r.width - filesWidth) / 2
-	r.drawTextAntialiased(img, filesStr, filesX, yOffset+100, r.fg)
-
-	insertionsWidth := font.MeasureString(r.fontFace, insertionsStr).Ceil()
-	insertionsX := (r.width - insertionsWidth) / 2
-	r.drawTextAntialiased(img, insertionsStr, insertionsX, yOffset+150, greenColor)
+// formatByteDelta formats a size change with its sign in binary units, e.g. +1.5 MiB
+func formatByteDelta(n int) string {
+	sign := "+"
+	if n < 0 {
+		sign = "-"
+		n = -n
+	}
+	if n < 1024 {
+		return fmt.Sprintf("%s%d B", sign, n)
+	}
+	size := float64(n) / 1024
+	unit := 0
+	for size >= 1024 && unit < 3 {
+		size /= 1024
+		unit++
+	}
+	return fmt.Sprintf("%s%.1f %ciB", sign, size, "KMGT"[unit])
+}
 
-	deletionsWidth := font.MeasureString(r.fontFace, deletionsStr).Ceil()
-	deletionsX := (r.width - deletionsWidth) / 2
-	r.drawTextAntialiased(img, deletionsStr, deletionsX, yOffset+200, redColor)
+// commitsLine returns the number of commits and active days shown below the stats
+func commitsLine(stats *GitStats) string {
+	return fmt.Sprintf("%s on %s", pluralize(stats.Commits, "commit"), pluralize(stats.ActiveDays(), "day"))
+}
 
-	// Draw language breakdown if requested
-	if opts.Lang && len(stats.Languages) > 0 {
-		r.drawLanguageBar(img, stats, yOffset+280)
+// cardDateRange returns the date range shown at the top of the card
+func cardDateRange(report *Report) string {
+	if report.DateRange != "" {
+		return report.DateRange
 	}
+	return commitsDateRange(&report.Total)
+}
 
-	// Save to file
-	f, err := os.Create(opts.Output)
-	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
+// breakdownRows returns the rows of the repository and author sections
+func breakdownRows(report *Report) (repoRows, authorRows []pngRow) {
+	for _, repo := range report.Repos {
+		repoRows = append(repoRows, pngRow{Label: repo.Name, Insertions: repo.Stats.Insertions, Deletions: repo.Stats.Deletions})
 	}
-	defer f.Close()
+	for i, author := range report.Authors {
+		label := fmt.Sprintf("%d. %s", i+1, truncateLabel(author.Name, maxPNGLabelLen))
+		authorRows = append(authorRows, pngRow{Label: label, Insertions: author.Stats.Insertions, Deletions: author.Stats.Deletions})
+	}
+	return repoRows, authorRows
+}
 
-	if err := png.Encode(f, img); err != nil {
-		return fmt.Errorf("failed to encode PNG: %w", err)
+// commitsDateRange returns the days of the first and last commits
+func commitsDateRange(stats *GitStats) string {
+	if stats.Commits == 0 {
+		return ""
 	}
+	first := stats.FirstCommit.Format("Jan 2, 2006")
+	last := stats.LastCommit.Format("Jan 2, 2006")
+	if first == last {
+		return first
+	}
+	return fmt.Sprintf("%s - %s", first, last)
+}
 
-	return nil
+// pluralize formats n followed by the singular or plural form of word
+func pluralize(n int, word string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, word)
+	}
+	return fmt.Sprintf("%d %ss", n, word)
 }
 
 func (r *PNGRenderer) drawTextAntialiased(img *image.RGBA, text string, x, y int, col color.Color) {
+	r.drawTextWithFace(img, r.fontFace, text, x, y, col)
+}
+
+func (r *PNGRenderer) drawTextWithFace(img *image.RGBA, face font.Face, text string, x, y int, col color.Color) {
 	// Use proper fixed-point positioning for better text rendering
 	point := fixed.Point26_6{
 		X: fixed.I(x),
@@ -145,7 +228,7 @@ func (r *PNGRenderer) drawTextAntialiased(img *image.RGBA, text string, x, y int
 	d := &font.Drawer{
 		Dst:  img,
 		Src:  image.NewUniform(col),
-		Face: r.fontFace,
+		Face: face,
 		Dot:  point,
 	}
 
@@ -203,12 +286,8 @@ type LanguageInfo struct {
 	Color      color.RGBA
 }
 
-// drawLanguageBar draws a horizontal bar chart showing language breakdown
-func (r *PNGRenderer) drawLanguageBar(img *image.RGBA, stats *GitStats, yOffset int) {
-	if len(stats.Languages) == 0 {
-		return
-	}
-
+// languageBreakdown returns the top 3 languages by lines changed and the sum of the others
+func languageBreakdown(stats *GitStats) []LanguageInfo {
 	// Calculate total lines
 	totalLines := 0
 	for _, lines := range stats.Languages {
@@ -216,7 +295,7 @@ func (r *PNGRenderer) drawLanguageBar(img *image.RGBA, stats *GitStats, yOffset
 	}
 
 	if totalLines == 0 {
-		return
+		return nil
 	}
 
 	// Sort languages by lines (descending)
@@ -232,7 +311,10 @@ func (r *PNGRenderer) drawLanguageBar(img *image.RGBA, stats *GitStats, yOffset
 	}
 
 	sort.Slice(languages, func(i, j int) bool {
-		return languages[i].Lines > languages[j].Lines
+		if languages[i].Lines != languages[j].Lines {
+			return languages[i].Lines > languages[j].Lines
+		}
+		return languages[i].Name < languages[j].Name
 	})
 
 	// Group into top 3 and others
@@ -259,6 +341,16 @@ func (r *PNGRenderer) drawLanguageBar(img *image.RGBA, stats *GitStats, yOffset
 		})
 	}
 
+	return displayLangs
+}
+
+// drawLanguageBar draws a horizontal bar chart showing language breakdown
+func (r *PNGRenderer) drawLanguageBar(img *image.RGBA, stats *GitStats, yOffset int) {
+	displayLangs := languageBreakdown(stats)
+	if len(displayLangs) == 0 {
+		return
+	}
+
 	// Draw the bar
 	barWidth := 600
 	barHeight := 40
@@ -304,6 +396,161 @@ func (r *PNGRenderer) drawLanguageBar(img *image.RGBA, stats *GitStats, yOffset
 	}
 }
 
+const (
+	maxPNGRows     = 10 // rows listed in a PNG section, the rest are summarized
+	maxPNGLabelLen = 24
+	pngRowHeight   = 40
+)
+
+// pngRow is a single line of a repository or author section
+type pngRow struct {
+	Label      string
+	Insertions int
+	Deletions  int
+}
+
+// rowsSectionHeight returns the height needed to draw a section with n rows
+func rowsSectionHeight(n int) int {
+	if n > maxPNGRows {
+		n = maxPNGRows + 1 // "and N more" line
+	}
+	return n*pngRowHeight + 20
+}
+
+// truncateLabel shortens s to at most n characters
+func truncateLabel(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
+
+// drawRowsSection draws one line per row with its insertions and deletions
+func (r *PNGRenderer) drawRowsSection(img *image.RGBA, rows []pngRow, yOffset int) {
+	greenColor := color.RGBA{26, 127, 55, 255}
+	redColor := color.RGBA{209, 36, 47, 255}
+
+	l := newRowsLayout(r.fontFace, r.width, rows)
+
+	y := yOffset
+	for _, row := range l.Rows {
+		r.drawTextAntialiased(img, row.Label, l.X, y, r.fg)
+		r.drawTextAntialiased(img, l.insertions(row), l.InsertionsX, y, greenColor)
+		r.drawTextAntialiased(img, l.deletions(row), l.DeletionsX, y, redColor)
+		y += pngRowHeight
+	}
+
+	if l.Hidden > 0 {
+		more := fmt.Sprintf("and %d more", l.Hidden)
+		r.drawTextAntialiased(img, more, l.X, y, r.fg)
+	}
+}
+
+// rowsLayout holds the positions of the columns of a repository or author section
+type rowsLayout struct {
+	Rows          []pngRow // visible rows
+	Hidden        int      // rows summarized in the "and N more" line
+	X             int
+	InsertionsX   int
+	DeletionsX    int
+	insertionsLen int
+	deletionsLen  int
+}
+
+func newRowsLayout(face font.Face, width int, rows []pngRow) *rowsLayout {
+	l := &rowsLayout{
+		Rows: rows,
+	}
+	if len(rows) > maxPNGRows {
+		l.Hidden = len(rows) - maxPNGRows
+		l.Rows = rows[:maxPNGRows]
+	}
+
+	labelLen := 0
+	for _, row := range l.Rows {
+		labelLen = max(labelLen, len([]rune(row.Label)))
+		l.insertionsLen = max(l.insertionsLen, len(fmt.Sprintf("+%d", row.Insertions)))
+		l.deletionsLen = max(l.deletionsLen, len(fmt.Sprintf("-%d", row.Deletions)))
+	}
+
+	// Center the whole table based on its widest row
+	rowWidth := font.MeasureString(face, strings.Repeat(" ", labelLen+l.insertionsLen+l.deletionsLen+4)).Ceil()
+	l.X = (width - rowWidth) / 2
+	l.InsertionsX = l.X + font.MeasureString(face, strings.Repeat(" ", labelLen+2)).Ceil()
+	l.DeletionsX = l.InsertionsX + font.MeasureString(face, strings.Repeat(" ", l.insertionsLen+2)).Ceil()
+	return l
+}
+
+// insertions returns the right aligned insertions of a row
+func (l *rowsLayout) insertions(row pngRow) string {
+	return fmt.Sprintf("%*s", l.insertionsLen, fmt.Sprintf("+%d", row.Insertions))
+}
+
+// deletions returns the right aligned deletions of a row
+func (l *rowsLayout) deletions(row pngRow) string {
+	return fmt.Sprintf("%*s", l.deletionsLen, fmt.Sprintf("-%d", row.Deletions))
+}
+
+const (
+	heatmapMaxStep     = 20 // maximum distance between two cells
+	heatmapLabelHeight = 24 // space above the grid for the month labels
+)
+
+// heatmapStep returns the distance between two cells so that the grid fits in the bar width
+func heatmapStep(face font.Face, h *heatmap) int {
+	labelWidth := font.MeasureString(face, "Wed ").Ceil()
+	return max(4, min(heatmapMaxStep, (600-labelWidth)/max(1, h.Weeks)))
+}
+
+// heatmapHeight returns the height of the heatmap section
+func heatmapHeight(face font.Face, h *heatmap) int {
+	return heatmapLabelHeight + 7*heatmapStep(face, h) + 40
+}
+
+// drawHeatmap draws a calendar grid of daily activity shaded from the foreground color
+func (r *PNGRenderer) drawHeatmap(img *image.RGBA, h *heatmap, yOffset int) {
+	step := heatmapStep(r.smallFontFace, h)
+	gap := max(1, step/5)
+	cellSize := step - gap
+
+	labelWidth := font.MeasureString(r.smallFontFace, "Wed ").Ceil()
+	gridWidth := h.Weeks*step - gap
+	x := (r.width - labelWidth - gridWidth) / 2
+	gridX := x + labelWidth
+	gridY := yOffset + heatmapLabelHeight
+
+	// Month labels above the columns
+	for _, month := range h.Months {
+		r.drawTextWithFace(img, r.smallFontFace, month.Label, gridX+month.Week*step, gridY-8, r.fg)
+	}
+
+	// Weekday labels on the left of every other row
+	for weekday, label := range []string{"", "Mon", "", "Wed", "", "Fri", ""} {
+		if label != "" {
+			r.drawTextWithFace(img, r.smallFontFace, label, x, gridY+weekday*step+cellSize/2+5, r.fg)
+		}
+	}
+
+	for _, cell := range h.Cells {
+		cellX := gridX + cell.Week*step
+		cellY := gridY + cell.Weekday*step
+		cellRect := image.Rect(cellX, cellY, cellX+cellSize, cellY+cellSize)
+		draw.Draw(img, cellRect, &image.Uniform{heatmapShade(r.fg, cell.Level)}, image.Point{}, draw.Over)
+	}
+}
+
+// heatmapShade returns the foreground color faded according to the activity level
+func heatmapShade(fg color.Color, level int) color.NRGBA {
+	c := color.NRGBAModel.Convert(fg).(color.NRGBA)
+	opacity := 0.12 // days without activity
+	if level > 0 {
+		opacity = float64(level) / heatmapLevels
+	}
+	c.A = uint8(float64(c.A) * opacity)
+	return c
+}
+
 // drawFilledCircle draws a filled circle at the given position
 func (r *PNGRenderer) drawFilledCircle(img *image.RGBA, centerX, centerY, radius int, col color.RGBA) {
 	// Use midpoint circle algorithm to draw a filled circle
@@ -1,7 +1,11 @@
 package internal
 
 import (
+	"bytes"
+	"fmt"
 	"image/color"
+	"image/png"
+	"reflect"
 	"testing"
 )
 
@@ -113,3 +117,87 @@ func TestSetBackgroundFromHex(t *testing.T) {
 		t.Error("SetBackgroundFromHex() expected error for invalid color")
 	}
 }
+
+func TestRenderByRepo(t *testing.T) {
+	var repos []RepoStats
+	for i := 0; i < maxPNGRows+2; i++ {
+		repos = append(repos, NewRepoStats(fmt.Sprintf("/work/repo-%d", i), GitStats{Insertions: i, Deletions: 1}))
+	}
+	report := NewReport(repos)
+
+	out := new(bytes.Buffer)
+	r := NewPNGRenderer()
+	if err := r.Render(out, report, &RunOptions{ByRepo: true}); err != nil {
+		t.Fatalf("Render() unexpected error: %v", err)
+	}
+
+	cfg, err := png.DecodeConfig(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 10 repositories plus the "and 2 more" line
+	expectedHeight := 800 + (maxPNGRows+1)*pngRowHeight + 20
+	if cfg.Width != 800 || cfg.Height != expectedHeight {
+		t.Errorf("Render() size = %dx%d, want 800x%d", cfg.Width, cfg.Height, expectedHeight)
+	}
+}
+
+func TestRenderBinaryFiles(t *testing.T) {
+	report := NewReport([]RepoStats{NewRepoStats("/work/assets", GitStats{FilesChanged: 2, BinaryFiles: 1, BinaryBytes: 2048})})
+
+	out := new(bytes.Buffer)
+	r := NewPNGRenderer()
+	if err := r.Render(out, report, &RunOptions{BinaryBytes: true}); err != nil {
+		t.Fatalf("Render() unexpected error: %v", err)
+	}
+
+	cfg, err := png.DecodeConfig(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The binary files line adds a line below the deletions
+	if cfg.Height != 850 {
+		t.Errorf("Render() height = %d, want 850", cfg.Height)
+	}
+}
+
+func TestStatLines(t *testing.T) {
+	stats := &GitStats{FilesChanged: 12, Insertions: 340, Deletions: 5, BinaryFiles: 3, BinaryBytes: -1536}
+
+	files, insertions, deletions, binary := statLines(stats, true)
+	want := []string{
+		" 12 files changed          ",
+		"340 insertions(+)          ",
+		"  5 deletions(-)           ",
+		"  3 binary files (-1.5 KiB)",
+	}
+	if got := []string{files, insertions, deletions, binary}; !reflect.DeepEqual(got, want) {
+		t.Errorf("statLines() = %q, want %q", got, want)
+	}
+
+	if _, _, _, binary := statLines(&GitStats{FilesChanged: 1}, true); binary != "" {
+		t.Errorf("statLines() binary = %q, want empty without binary files", binary)
+	}
+}
+
+func TestFormatByteDelta(t *testing.T) {
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{0, "+0 B"},
+		{1023, "+1023 B"},
+		{-1024, "-1.0 KiB"},
+		{1536, "+1.5 KiB"},
+		{5 << 20, "+5.0 MiB"},
+		{-3 << 30, "-3.0 GiB"},
+		{2 << 40, "+2.0 TiB"},
+		{2048 << 40, "+2048.0 TiB"},
+	}
+	for _, tt := range tests {
+		if got := formatByteDelta(tt.n); got != tt.want {
+			t.Errorf("formatByteDelta(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"io"
 	"math"
 	"os"
+	"sync"
 
 	"golang.org/x/term"
 )
@@ -15,6 +16,8 @@ type Printer struct {
 	ErrWriter io.Writer
 
 	disableStyling bool
+
+	mu sync.Mutex // keeps lines written from concurrent goroutines whole
 }
 
 func NewPrinter(
@@ -37,26 +40,38 @@ func NewPrinter(
 }
 
 func (p *Printer) Print(a ...any) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	fmt.Fprint(p.OutWriter, a...)
 }
 
 func (p *Printer) Println(a ...any) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	fmt.Fprintln(p.OutWriter, a...)
 }
 
 func (p *Printer) Printf(format string, a ...any) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	fmt.Fprintf(p.OutWriter, format, a...)
 }
 
 func (p *Printer) ErrPrint(a ...any) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	fmt.Fprint(p.ErrWriter, a...)
 }
 
 func (p *Printer) ErrPrintln(a ...any) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	fmt.Fprintln(p.ErrWriter, a...)
 }
 
 func (p *Printer) ErrPrintf(format string, a ...any) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	fmt.Fprintf(p.ErrWriter, format, a...)
 }
 
@@ -0,0 +1,223 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/radulucut/gitbrag/internal/gitobj"
+	"github.com/radulucut/gitbrag/internal/utils"
+)
+
+// Ref selections, any other selection is a glob of ref names
+const (
+	RefsHead     = "head"     // the checked out commit
+	RefsBranches = "branches" // local branches, the default
+	RefsRemotes  = "remotes"  // remote-tracking branches, e.g. origin/main
+	RefsTags     = "tags"
+	RefsAll      = "all" // every ref and HEAD, like git log --all
+)
+
+// gitRef is a ref whose commits are read
+type gitRef struct {
+	Name string // full name, e.g. refs/remotes/origin/main, or HEAD
+	Hash string // commit the ref points to, annotated tags are peeled
+}
+
+// resolveRefs lists the refs of the repository matching the selections of
+// the options, sorted by name. Refs that don't point to a commit are skipped.
+func resolveRefs(ctx context.Context, dir string, opts *GitStatsOptions) ([]gitRef, error) {
+	var refs []gitRef
+	var err error
+	if opts.Backend == BackendNative {
+		refs, err = listNativeRefs(dir)
+	} else {
+		refs, err = listGitRefs(ctx, dir)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	selections := opts.Refs
+	if len(selections) == 0 {
+		selections = []string{RefsBranches}
+	}
+	var selected []gitRef
+	for _, ref := range refs {
+		for _, selection := range selections {
+			if matchRef(selection, ref.Name) {
+				selected = append(selected, ref)
+				break
+			}
+		}
+	}
+	return selected, nil
+}
+
+// matchRef reports whether a ref belongs to a selection. Globs match full
+// names or names without the refs/, refs/heads/, refs/remotes/ or refs/tags/
+// prefix, and * also matches slashes, so origin/* selects every branch of origin.
+func matchRef(selection, name string) bool {
+	switch selection {
+	case RefsHead:
+		return name == "HEAD"
+	case RefsBranches:
+		return strings.HasPrefix(name, "refs/heads/")
+	case RefsRemotes:
+		return strings.HasPrefix(name, "refs/remotes/")
+	case RefsTags:
+		return strings.HasPrefix(name, "refs/tags/")
+	case RefsAll:
+		return true
+	}
+
+	pattern, err := refGlob(selection)
+	if err != nil {
+		return false
+	}
+	for _, prefix := range []string{"", "refs/", "refs/heads/", "refs/remotes/", "refs/tags/"} {
+		if short, ok := strings.CutPrefix(name, prefix); ok && pattern.MatchString(short) {
+			return true
+		}
+	}
+	return false
+}
+
+// refGlob compiles a glob with *, ? and [...] to a regular expression
+func refGlob(glob string) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			sb.WriteString(".*")
+		case '?':
+			sb.WriteString(".")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated bracket in ref pattern: %s", glob)
+			}
+			class := glob[i+1 : i+1+end]
+			if negated, ok := strings.CutPrefix(class, "!"); ok {
+				class = "^" + negated
+			}
+			sb.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString("$")
+	return regexp.Compile(sb.String())
+}
+
+// ValidateRefs checks the ref selections before any repository is read
+func ValidateRefs(selections []string) error {
+	for _, selection := range selections {
+		if selection == "" {
+			return fmt.Errorf("invalid refs: empty selection")
+		}
+		switch selection {
+		case RefsHead, RefsBranches, RefsRemotes, RefsTags, RefsAll:
+			continue
+		}
+		if _, err := refGlob(selection); err != nil {
+			return fmt.Errorf("invalid refs: %w", err)
+		}
+	}
+	return nil
+}
+
+// listGitRefs lists all refs and HEAD with git for-each-ref
+func listGitRefs(ctx context.Context, dir string) ([]gitRef, error) {
+	cmd := exec.CommandContext(ctx, "git", "for-each-ref",
+		"--format=%(objectname) %(objecttype) %(*objectname) %(*objecttype) %(refname)")
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, utils.NewInternalError("failed to execute git command: " + err.Error())
+	}
+
+	var refs []gitRef
+	// HEAD is missing before the first commit of the current branch
+	cmd = exec.CommandContext(ctx, "git", "rev-parse", "-q", "--verify", "HEAD^{commit}")
+	cmd.Dir = dir
+	if head, err := cmd.Output(); err == nil {
+		refs = append(refs, gitRef{Name: "HEAD", Hash: strings.TrimSpace(string(head))})
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		fields := strings.SplitN(line, " ", 5)
+		if len(fields) < 5 {
+			continue
+		}
+		ref := gitRef{Name: fields[4]}
+		switch {
+		case fields[1] == "commit":
+			ref.Hash = fields[0]
+		case fields[3] == "commit":
+			ref.Hash = fields[2]
+		case fields[3] == "tag":
+			// Tags of tags are peeled by git itself
+			cmd := exec.CommandContext(ctx, "git", "rev-parse", "-q", "--verify", fields[0]+"^{commit}")
+			cmd.Dir = dir
+			hash, err := cmd.Output()
+			if err != nil {
+				continue
+			}
+			ref.Hash = strings.TrimSpace(string(hash))
+		default:
+			continue
+		}
+		refs = append(refs, ref)
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		return refs[i].Name < refs[j].Name
+	})
+	return refs, nil
+}
+
+// listNativeRefs lists all refs and HEAD from the files of the repository
+func listNativeRefs(dir string) ([]gitRef, error) {
+	repo, err := gitobj.Open(dir)
+	if err != nil {
+		return nil, utils.NewInternalError("failed to open git repository: " + err.Error())
+	}
+	defer repo.Close()
+
+	all, err := repo.References("refs/")
+	if err != nil {
+		return nil, utils.NewInternalError("failed to read refs: " + err.Error())
+	}
+	if head, ok, err := repo.Head(); err != nil {
+		return nil, utils.NewInternalError("failed to read HEAD: " + err.Error())
+	} else if ok {
+		all = append(all, gitobj.Ref{Name: "HEAD", Hash: head})
+	}
+
+	var refs []gitRef
+	for _, ref := range all {
+		h, ok, err := repo.PeelToCommit(ref.Hash)
+		if err != nil || !ok {
+			continue
+		}
+		refs = append(refs, gitRef{Name: ref.Name, Hash: h.String()})
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		return refs[i].Name < refs[j].Name
+	})
+	return refs, nil
+}
+
+// refNames returns the names of the refs
+func refNames(refs []gitRef) []string {
+	names := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		names = append(names, ref.Name)
+	}
+	return names
+}
@@ -0,0 +1,35 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchRef(t *testing.T) {
+	tests := []struct {
+		selection string
+		name      string
+		match     bool
+	}{
+		{RefsHead, "HEAD", true},
+		{RefsHead, "refs/heads/main", false},
+		{RefsBranches, "refs/heads/feature/x", true},
+		{RefsBranches, "refs/remotes/origin/main", false},
+		{RefsRemotes, "refs/remotes/origin/main", true},
+		{RefsTags, "refs/tags/v1.0", true},
+		{RefsAll, "refs/stash", true},
+		{"origin/*", "refs/remotes/origin/release/1.0", true},
+		{"origin/release/*", "refs/remotes/origin/main", false},
+		{"main", "refs/heads/main", true},
+		{"main", "refs/remotes/origin/main", false},
+		{"v1.?", "refs/tags/v1.2", true},
+		{"v[0-1].*", "refs/tags/v2.0", false},
+		{"release/[!0]*", "refs/heads/release/1.0", true},
+		{"refs/notes/*", "refs/notes/commits", true},
+		{"stash", "refs/stash", true},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.match, matchRef(tt.selection, tt.name), "%s %s", tt.selection, tt.name)
+	}
+}
@@ -0,0 +1,113 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+	FormatPNG  = "png"
+	FormatSVG  = "svg"
+)
+
+// Renderer writes a report in a single output format
+type Renderer interface {
+	Render(w io.Writer, report *Report, opts *RunOptions) error
+}
+
+// RendererFactory creates a renderer configured from the run options,
+// e.g. with the background and text colors
+type RendererFactory func(opts *RunOptions) (Renderer, error)
+
+var (
+	renderers          = map[string]RendererFactory{}
+	rendererExtensions = map[string]string{} // file extension to format name
+)
+
+func init() {
+	RegisterRenderer(FormatText, func(opts *RunOptions) (Renderer, error) {
+		return &TextRenderer{}, nil
+	}, ".txt")
+	RegisterRenderer(FormatJSON, func(opts *RunOptions) (Renderer, error) {
+		return &JSONRenderer{}, nil
+	}, ".json")
+	RegisterRenderer(FormatPNG, func(opts *RunOptions) (Renderer, error) {
+		r := NewPNGRenderer()
+		return r, applyColors(r, opts)
+	}, ".png")
+	RegisterRenderer(FormatSVG, func(opts *RunOptions) (Renderer, error) {
+		r := NewSVGRenderer()
+		return r, applyColors(r, opts)
+	}, ".svg")
+}
+
+// RegisterRenderer makes a format available to --format and, for each of the
+// given file extensions, to --output. Registering a format again replaces it.
+func RegisterRenderer(format string, factory RendererFactory, extensions ...string) {
+	renderers[format] = factory
+	for _, ext := range extensions {
+		rendererExtensions[strings.ToLower(ext)] = format
+	}
+}
+
+// NewRenderer creates the renderer registered for format
+func NewRenderer(format string, opts *RunOptions) (Renderer, error) {
+	factory, ok := renderers[format]
+	if !ok {
+		return nil, fmt.Errorf("unsupported format: %s", format)
+	}
+	switch opts.Heatmap {
+	case "", HeatmapCommits, HeatmapLines:
+	default:
+		return nil, fmt.Errorf("unsupported heatmap: %s", opts.Heatmap)
+	}
+	return factory(opts)
+}
+
+// RendererFormats returns the names of the registered formats in alphabetical order
+func RendererFormats() []string {
+	formats := make([]string, 0, len(renderers))
+	for format := range renderers {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// formatForFile returns the format registered for the extension of path,
+// files with an unknown extension are exported as PNG
+func formatForFile(path string) string {
+	if format, ok := rendererExtensions[outputExtension(path)]; ok {
+		return format
+	}
+	return FormatPNG
+}
+
+// outputExtension returns the extension of path as formats are registered with
+func outputExtension(path string) string {
+	return strings.ToLower(filepath.Ext(path))
+}
+
+type colorSetter interface {
+	SetBackgroundFromHex(hexColor string) error
+	SetForegroundFromHex(hexColor string) error
+}
+
+func applyColors(r colorSetter, opts *RunOptions) error {
+	if opts.Background != "" {
+		if err := r.SetBackgroundFromHex(opts.Background); err != nil {
+			return fmt.Errorf("invalid background color: %w", err)
+		}
+	}
+	if opts.Color != "" {
+		if err := r.SetForegroundFromHex(opts.Color); err != nil {
+			return fmt.Errorf("invalid text color: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type countRenderer struct{}
+
+func (r *countRenderer) Render(w io.Writer, report *Report, opts *RunOptions) error {
+	_, err := fmt.Fprintf(w, "%d,%d\n", report.Total.Insertions, report.Total.Deletions)
+	return err
+}
+
+func TestRegisterRenderer(t *testing.T) {
+	RegisterRenderer("count", func(opts *RunOptions) (Renderer, error) {
+		return &countRenderer{}, nil
+	}, ".CNT")
+	t.Cleanup(func() {
+		delete(renderers, "count")
+		delete(rendererExtensions, ".cnt")
+	})
+
+	assert.Equal(t, []string{"count", FormatJSON, FormatPNG, FormatSVG, FormatText}, RendererFormats())
+	assert.Equal(t, "count", formatForFile("stats.cnt"))
+
+	r, err := NewRenderer("count", &RunOptions{})
+	assert.NoError(t, err)
+	out := new(bytes.Buffer)
+	assert.NoError(t, r.Render(out, NewReport([]RepoStats{NewRepoStats("/repo", GitStats{Insertions: 3, Deletions: 1})}), &RunOptions{}))
+	assert.Equal(t, "3,1\n", out.String())
+}
+
+func TestNewRenderer(t *testing.T) {
+	_, err := NewRenderer("xml", &RunOptions{})
+	assert.EqualError(t, err, "unsupported format: xml")
+
+	_, err = NewRenderer(FormatPNG, &RunOptions{Background: "zzz"})
+	assert.ErrorContains(t, err, "invalid background color")
+
+	r, err := NewRenderer(FormatSVG, &RunOptions{Color: "fff"})
+	assert.NoError(t, err)
+	assert.IsType(t, &SVGRenderer{}, r)
+}
+
+func TestFormatForFile(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"stats.png", FormatPNG},
+		{"stats.SVG", FormatSVG},
+		{"out/stats.json", FormatJSON},
+		{"stats.txt", FormatText},
+		{"stats", FormatPNG},
+		{"stats.jpeg", FormatPNG},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, formatForFile(tt.path), tt.path)
+	}
+}
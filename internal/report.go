@@ -0,0 +1,113 @@
+package internal
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Report holds the statistics collected from all scanned repositories
+type Report struct {
+	Since        time.Time // requested start of the period, zero when not set
+	Until        time.Time // requested end of the period, zero when not set
+	DateRange    string    // label of the requested period, empty when no dates were requested
+	From         time.Time // start of the reported period, the since date or the first commit
+	To           time.Time // end of the reported period, the until date, now or the last commit
+	Total        GitStats
+	RefSelection []string      // requested ref selections, see RefsBranches
+	Refs         []string      // names of the refs read in any repository, sorted
+	Repos        []RepoStats   // sorted by lines changed, most active first
+	Authors      []AuthorStats // sorted by lines changed, only set when grouping by author
+}
+
+// RepoStats holds the statistics of a single repository
+type RepoStats struct {
+	Path  string
+	Name  string
+	Refs  []string // names of the refs the commits were read from
+	Stats GitStats
+}
+
+func NewRepoStats(path string, stats GitStats) RepoStats {
+	return RepoStats{
+		Path:  path,
+		Name:  filepath.Base(path),
+		Stats: stats,
+	}
+}
+
+// SetPeriod resolves the reported period from the requested dates, falling back
+// to the dates of the first and last commits
+func (r *Report) SetPeriod(since, until, now time.Time) {
+	r.Since = since
+	r.Until = until
+	if !since.IsZero() && !until.IsZero() {
+		r.DateRange = fmt.Sprintf("%s - %s", formatOutputDate(since), formatOutputDate(until))
+	} else if !since.IsZero() {
+		r.DateRange = fmt.Sprintf("Since %s", formatOutputDate(since))
+	} else if !until.IsZero() {
+		r.DateRange = fmt.Sprintf("Until %s", formatOutputDate(until))
+	}
+
+	r.From = since
+	if r.From.IsZero() {
+		r.From = r.Total.FirstCommit
+	}
+	r.To = until
+	if r.To.IsZero() {
+		if !since.IsZero() {
+			r.To = now
+		} else {
+			r.To = r.Total.LastCommit
+		}
+	}
+}
+
+func NewReport(repos []RepoStats) *Report {
+	report := &Report{
+		Repos: repos,
+	}
+	refs := make(map[string]bool)
+	for _, repo := range repos {
+		report.Total.Add(repo.Stats)
+		report.Total.Repositories++
+		for _, ref := range repo.Refs {
+			refs[ref] = true
+		}
+	}
+	for ref := range refs {
+		report.Refs = append(report.Refs, ref)
+	}
+	sort.Strings(report.Refs)
+
+	sort.SliceStable(report.Repos, func(i, j int) bool {
+		a, b := report.Repos[i], report.Repos[j]
+		if a.Stats.LinesChanged() != b.Stats.LinesChanged() {
+			return a.Stats.LinesChanged() > b.Stats.LinesChanged()
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Path < b.Path
+	})
+
+	for _, author := range report.Total.Authors {
+		report.Authors = append(report.Authors, *author)
+	}
+	sort.Slice(report.Authors, func(i, j int) bool {
+		a, b := report.Authors[i], report.Authors[j]
+		if a.Stats.LinesChanged() != b.Stats.LinesChanged() {
+			return a.Stats.LinesChanged() > b.Stats.LinesChanged()
+		}
+		if a.Stats.Commits != b.Stats.Commits {
+			return a.Stats.Commits > b.Stats.Commits
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Email < b.Email
+	})
+
+	return report
+}
@@ -0,0 +1,204 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"image/color"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/radulucut/gitbrag/internal/utils"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+)
+
+// SVGRenderer renders the same card as PNGRenderer as a scalable SVG image
+type SVGRenderer struct {
+	width         int
+	height        int
+	bg            color.Color
+	fg            color.Color
+	fontFace      font.Face // only used to measure text, the font itself is embedded in the SVG
+	smallFontFace font.Face
+}
+
+func NewSVGRenderer() *SVGRenderer {
+	fontFace, err := utils.LoadFont(24)
+	if err != nil {
+		fontFace = basicfont.Face7x13
+	}
+	smallFontFace, err := utils.LoadFont(14)
+	if err != nil {
+		smallFontFace = basicfont.Face7x13
+	}
+
+	return &SVGRenderer{
+		width:         800,
+		height:        800,
+		bg:            color.RGBA{0, 0, 0, 0},   // Transparent by default
+		fg:            color.RGBA{0, 0, 0, 255}, // Black text by default
+		fontFace:      fontFace,
+		smallFontFace: smallFontFace,
+	}
+}
+
+func (r *SVGRenderer) SetBackgroundFromHex(hexColor string) error {
+	col, err := parseHexColor(hexColor)
+	if err != nil {
+		return err
+	}
+	r.bg = col
+	return nil
+}
+
+func (r *SVGRenderer) SetForegroundFromHex(hexColor string) error {
+	col, err := parseHexColor(hexColor)
+	if err != nil {
+		return err
+	}
+	r.fg = col
+	return nil
+}
+
+// Render writes the SVG document of the card laid out by layoutCard
+func (r *SVGRenderer) Render(w io.Writer, report *Report, opts *RunOptions) error {
+	l := layoutCard(r.fontFace, r.smallFontFace, r.width, r.height, r.fg, report, opts)
+
+	b := new(bytes.Buffer)
+	fmt.Fprintf(b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" xml:space="preserve">`+"\n", l.Width, l.Height, l.Width, l.Height)
+	fmt.Fprintf(b, `<style>@font-face { font-family: "Space Mono"; src: url(data:font/ttf;base64,%s) format("truetype"); } text { font-family: "Space Mono", monospace; font-size: 24px; white-space: pre; } .small { font-size: 14px; }</style>`+"\n", base64.StdEncoding.EncodeToString(utils.FontData()))
+
+	// Fill background
+	if _, _, _, a := r.bg.RGBA(); a > 0 {
+		fmt.Fprintf(b, `<rect width="%d" height="%d"%s/>`+"\n", l.Width, l.Height, svgFill(r.bg))
+	}
+
+	for _, line := range l.Lines {
+		r.writeText(b, line.Text, line.X, line.Y, line.Color, "")
+	}
+
+	for _, section := range l.Sections {
+		switch section.Kind {
+		case sectionLanguages:
+			r.writeLanguageBar(b, &report.Total, section.Y)
+		case sectionHeatmap:
+			r.writeHeatmap(b, section.Heatmap, section.Y)
+		case sectionRows:
+			r.writeRowsSection(b, section.Rows, section.Y)
+		}
+	}
+
+	b.WriteString("</svg>\n")
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+func (r *SVGRenderer) writeText(b *bytes.Buffer, text string, x, y int, col color.Color, class string) {
+	fmt.Fprintf(b, `<text x="%d" y="%d"%s`, x, y, svgFill(col))
+	if class != "" {
+		fmt.Fprintf(b, ` class="%s"`, class)
+	}
+	b.WriteString(">")
+	xml.EscapeText(b, []byte(text))
+	b.WriteString("</text>\n")
+}
+
+// writeLanguageBar writes the same bar chart as PNGRenderer.drawLanguageBar
+func (r *SVGRenderer) writeLanguageBar(b *bytes.Buffer, stats *GitStats, yOffset int) {
+	displayLangs := languageBreakdown(stats)
+	if len(displayLangs) == 0 {
+		return
+	}
+
+	barWidth := 600
+	barHeight := 40
+	barX := (r.width - barWidth) / 2
+	barY := yOffset
+
+	currentX := barX
+	for _, lang := range displayLangs {
+		segmentWidth := int(float64(barWidth) * lang.Percentage / 100)
+		if segmentWidth > 0 {
+			fmt.Fprintf(b, `<rect x="%d" y="%d" width="%d" height="%d"%s/>`+"\n", currentX, barY, segmentWidth, barHeight, svgFill(lang.Color))
+			currentX += segmentWidth
+		}
+	}
+
+	labelY := barY + barHeight + 40
+	circleRadius := 8
+	circleSpacing := 10
+	labelPadding := 30
+
+	currentX = barX
+	for _, lang := range displayLangs {
+		fmt.Fprintf(b, `<circle cx="%d" cy="%d" r="%d"%s/>`+"\n", currentX+circleRadius, labelY-6, circleRadius, svgFill(lang.Color))
+
+		textX := currentX + (circleRadius * 2) + circleSpacing
+		r.writeText(b, lang.Name, textX, labelY, r.fg, "")
+
+		labelWidth := font.MeasureString(r.fontFace, lang.Name).Ceil()
+		currentX = textX + labelWidth + labelPadding
+	}
+}
+
+// writeHeatmap writes the same calendar grid as PNGRenderer.drawHeatmap
+func (r *SVGRenderer) writeHeatmap(b *bytes.Buffer, h *heatmap, yOffset int) {
+	step := heatmapStep(r.smallFontFace, h)
+	gap := max(1, step/5)
+	cellSize := step - gap
+
+	labelWidth := font.MeasureString(r.smallFontFace, "Wed ").Ceil()
+	gridWidth := h.Weeks*step - gap
+	x := (r.width - labelWidth - gridWidth) / 2
+	gridX := x + labelWidth
+	gridY := yOffset + heatmapLabelHeight
+
+	for _, month := range h.Months {
+		r.writeText(b, month.Label, gridX+month.Week*step, gridY-8, r.fg, "small")
+	}
+
+	for weekday, label := range []string{"", "Mon", "", "Wed", "", "Fri", ""} {
+		if label != "" {
+			r.writeText(b, label, x, gridY+weekday*step+cellSize/2+5, r.fg, "small")
+		}
+	}
+
+	for _, cell := range h.Cells {
+		fmt.Fprintf(b, `<rect x="%d" y="%d" width="%d" height="%d"%s><title>%s: %d</title></rect>`+"\n",
+			gridX+cell.Week*step, gridY+cell.Weekday*step, cellSize, cellSize, svgFill(heatmapShade(r.fg, cell.Level)),
+			cell.Date.Format(time.DateOnly), cell.Value)
+	}
+}
+
+// writeRowsSection writes the same rows as PNGRenderer.drawRowsSection
+func (r *SVGRenderer) writeRowsSection(b *bytes.Buffer, rows []pngRow, yOffset int) {
+	greenColor := color.RGBA{26, 127, 55, 255}
+	redColor := color.RGBA{209, 36, 47, 255}
+
+	l := newRowsLayout(r.fontFace, r.width, rows)
+
+	y := yOffset
+	for _, row := range l.Rows {
+		r.writeText(b, row.Label, l.X, y, r.fg, "")
+		r.writeText(b, l.insertions(row), l.InsertionsX, y, greenColor, "")
+		r.writeText(b, l.deletions(row), l.DeletionsX, y, redColor, "")
+		y += pngRowHeight
+	}
+
+	if l.Hidden > 0 {
+		r.writeText(b, fmt.Sprintf("and %d more", l.Hidden), l.X, y, r.fg, "")
+	}
+}
+
+// svgFill returns the fill attributes of a color
+func svgFill(col color.Color) string {
+	c := color.NRGBAModel.Convert(col).(color.NRGBA)
+	fill := fmt.Sprintf(` fill="#%02x%02x%02x"`, c.R, c.G, c.B)
+	if c.A < 255 {
+		fill += fmt.Sprintf(` fill-opacity="%s"`, strconv.FormatFloat(float64(c.A)/255, 'f', 3, 64))
+	}
+	return fill
+}
@@ -0,0 +1,70 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/xml"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestSVGRender(t *testing.T) {
+	report := NewReport([]RepoStats{
+		NewRepoStats("/work/app", GitStats{
+			FilesChanged: 2,
+			Insertions:   11,
+			Deletions:    1,
+			Languages:    map[string]int{"Go": 8, "TypeScript": 4},
+		}),
+	})
+	report.DateRange = "Since Jan 1, 2025 & later"
+
+	r := NewSVGRenderer()
+	if err := r.SetBackgroundFromHex("#282a36"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.SetForegroundFromHex("f8f8f2"); err != nil {
+		t.Fatal(err)
+	}
+	out := new(bytes.Buffer)
+	if err := r.Render(out, report, &RunOptions{Lang: true}); err != nil {
+		t.Fatalf("Render() unexpected error: %v", err)
+	}
+	svg := out.String()
+
+	// The document must be well-formed XML
+	dec := xml.NewDecoder(strings.NewReader(svg))
+	for {
+		if _, err := dec.Token(); err != nil {
+			if err.Error() != "EOF" {
+				t.Fatalf("Render() produced invalid XML: %v", err)
+			}
+			break
+		}
+	}
+
+	for _, want := range []string{
+		`<svg xmlns="http://www.w3.org/2000/svg" width="800" height="950" viewBox="0 0 800 950" xml:space="preserve">`,
+		`<rect width="800" height="950" fill="#282a36"/>`,
+		`>Since Jan 1, 2025 &amp; later</text>`,
+		`fill="#f8f8f2"> 2 files changed</text>`,
+		`fill="#1a7f37">11 insertions(+)</text>`,
+		`fill="#d1242f"> 1 deletions(-) </text>`,
+		`<rect x="100" y="610" width="399" height="40" fill="#00add8"/>`,
+		`<circle cx="108" cy="684" r="8" fill="#00add8"/>`,
+		`fill="#f8f8f2">TypeScript</text>`,
+	} {
+		if !strings.Contains(svg, want) {
+			t.Errorf("Render() output does not contain %q", want)
+		}
+	}
+}
+
+func TestSVGFill(t *testing.T) {
+	if got := svgFill(color.NRGBA{R: 0x28, G: 0x2a, B: 0x36, A: 0xff}); got != ` fill="#282a36"` {
+		t.Errorf("svgFill() = %q", got)
+	}
+	if got := svgFill(color.NRGBA{R: 0x28, G: 0x2a, B: 0x36, A: 0x80}); got != ` fill="#282a36" fill-opacity="0.502"` {
+		t.Errorf("svgFill() = %q", got)
+	}
+}
@@ -0,0 +1,140 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// TextRenderer writes the human readable summary printed by default
+type TextRenderer struct{}
+
+func (r *TextRenderer) Render(w io.Writer, report *Report, opts *RunOptions) error {
+	totalStats := &report.Total
+	if totalStats.Repositories == 0 {
+		_, err := fmt.Fprintln(w, "No git repositories found in the specified directories.")
+		return err
+	}
+
+	// Print date range if available
+	if report.DateRange != "" {
+		fmt.Fprintf(w, "%s\n\n", report.DateRange)
+	}
+
+	if opts.ByRepo {
+		printRepoTable(w, report.Repos)
+		fmt.Fprintln(w)
+	}
+
+	if opts.ByAuthor {
+		printAuthorTable(w, report.Authors)
+		fmt.Fprintln(w)
+	}
+
+	commitsStr := fmt.Sprint(totalStats.Commits)
+	activeDaysStr := fmt.Sprint(totalStats.ActiveDays())
+	filesStr := fmt.Sprint(totalStats.FilesChanged)
+	insertionsStr := fmt.Sprint(totalStats.Insertions)
+	deletionsStr := fmt.Sprint(totalStats.Deletions)
+	movedStr := fmt.Sprint(totalStats.MovedLines)
+	binaryStr := fmt.Sprint(totalStats.BinaryFiles)
+
+	maxLen := max(len(commitsStr), len(activeDaysStr), len(filesStr), len(insertionsStr), len(deletionsStr))
+	if opts.Moved {
+		maxLen = max(maxLen, len(movedStr))
+	}
+	if totalStats.BinaryFiles > 0 {
+		maxLen = max(maxLen, len(binaryStr))
+	}
+
+	commitsStr = fmt.Sprintf("%*s", maxLen, commitsStr)
+	activeDaysStr = fmt.Sprintf("%*s", maxLen, activeDaysStr)
+	filesStr = fmt.Sprintf("%*s", maxLen, filesStr)
+	insertionsStr = fmt.Sprintf("%*s", maxLen, insertionsStr)
+	deletionsStr = fmt.Sprintf("%*s", maxLen, deletionsStr)
+	_, err := fmt.Fprintf(w, `%s commits
+%s active days
+%s files changed
+%s insertions(+)
+%s deletions(-)
+`, commitsStr, activeDaysStr, filesStr, insertionsStr, deletionsStr)
+	if err != nil {
+		return err
+	}
+	// Lines kept by renamed and copied files are not insertions
+	if opts.Moved {
+		if _, err := fmt.Fprintf(w, "%*s moved lines\n", maxLen, movedStr); err != nil {
+			return err
+		}
+	}
+	// Binary files have no lines, they are only shown when there are any
+	if totalStats.BinaryFiles > 0 {
+		if _, err := fmt.Fprintf(w, "%*s %s\n", maxLen, binaryStr, binaryFilesLabel(totalStats, opts.BinaryBytes)); err != nil {
+			return err
+		}
+	}
+
+	if totalStats.Commits > 0 {
+		_, err = fmt.Fprintf(w, `
+First commit: %s
+Last commit:  %s
+`, formatOutputDate(totalStats.FirstCommit), formatOutputDate(totalStats.LastCommit))
+	}
+
+	return err
+}
+
+func printRepoTable(w io.Writer, repos []RepoStats) {
+	rows := make([][]string, 0, len(repos))
+	for _, repo := range repos {
+		rows = append(rows, []string{
+			repo.Name,
+			fmt.Sprint(repo.Stats.Commits),
+			fmt.Sprint(repo.Stats.FilesChanged),
+			fmt.Sprint(repo.Stats.Insertions),
+			fmt.Sprint(repo.Stats.Deletions),
+		})
+	}
+	printTable(w, []string{"REPOSITORY", "COMMITS", "FILES", "INSERTIONS", "DELETIONS"}, rows, 1)
+}
+
+func printAuthorTable(w io.Writer, authors []AuthorStats) {
+	rows := make([][]string, 0, len(authors))
+	for i, author := range authors {
+		rows = append(rows, []string{
+			fmt.Sprint(i + 1),
+			author.Identity(),
+			fmt.Sprint(author.Stats.Commits),
+			fmt.Sprint(author.Stats.FilesChanged),
+			fmt.Sprint(author.Stats.Insertions),
+			fmt.Sprint(author.Stats.Deletions),
+		})
+	}
+	printTable(w, []string{"#", "AUTHOR", "COMMITS", "FILES", "INSERTIONS", "DELETIONS"}, rows, 2)
+}
+
+// printTable prints rows in aligned columns, the first leftAligned columns
+// are aligned to the left and the remaining ones to the right
+func printTable(w io.Writer, header []string, rows [][]string, leftAligned int) {
+	widths := make([]int, len(header))
+	for i, cell := range header {
+		widths[i] = len(cell)
+	}
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], len(cell))
+		}
+	}
+
+	for _, row := range append([][]string{header}, rows...) {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			if i < leftAligned {
+				cells[i] = fmt.Sprintf("%-*s", widths[i], cell)
+			} else {
+				cells[i] = fmt.Sprintf("%*s", widths[i], cell)
+			}
+		}
+		fmt.Fprintln(w, strings.Join(cells, "  "))
+	}
+}
@@ -0,0 +1,140 @@
+package internal
+
+import "strings"
+
+// coauthorTrailer is the trailer recording the other authors of a commit,
+// such as "Co-authored-by: Jane Doe <jane@example.com>"
+const coauthorTrailer = "Co-authored-by"
+
+// gitGeneratedPrefixes are lines git adds to messages itself, a trailer block
+// with one of them may also have other lines
+var gitGeneratedPrefixes = []string{"Signed-off-by: ", "(cherry picked from commit "}
+
+// trailerValues returns the values of the trailers of a commit message with
+// the key, ignoring case, like git log --format=%(trailers:key=<key>,valueonly,unfold)
+// with the default trailer configuration. Empty values are skipped.
+func trailerValues(message, key string) []string {
+	lines := trailerBlock(message)
+	var values []string
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		sep := trailerSeparator(line)
+		if sep < 1 || !strings.EqualFold(strings.TrimSpace(line[:sep]), key) {
+			continue
+		}
+		value := strings.TrimSpace(line[sep+1:])
+		// Lines starting with whitespace continue the value
+		for i+1 < len(lines) && isIndented(lines[i+1]) {
+			i++
+			value = strings.TrimSpace(value + " " + strings.TrimSpace(lines[i]))
+		}
+		if value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// trailerBlock returns the lines of the trailer block of a commit message, its
+// last paragraph when it is made of trailers. The title is never a trailer
+// block, and a paragraph made of trailers for at least a quarter of its lines
+// is one when it has a line generated by git, like git interpret-trailers does.
+func trailerBlock(message string) []string {
+	lines := strings.Split(message, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+	// Trailing blank and comment lines are not part of the message. Unlike git
+	// interpret-trailers, git log doesn't stop at a --- line before a patch.
+	for len(lines) > 0 && (isBlank(lines[len(lines)-1]) || strings.HasPrefix(lines[len(lines)-1], "#")) {
+		lines = lines[:len(lines)-1]
+	}
+
+	endOfTitle := 0
+	for endOfTitle < len(lines) {
+		if !strings.HasPrefix(lines[endOfTitle], "#") && isBlank(lines[endOfTitle]) {
+			break
+		}
+		endOfTitle++
+	}
+
+	trailerLines, nonTrailerLines, continuationLines := 0, 0, 0
+	recognized, onlySpaces := false, true
+	for i := len(lines) - 1; i >= endOfTitle; i-- {
+		line := lines[i]
+		switch {
+		case strings.HasPrefix(line, "#"):
+			nonTrailerLines += continuationLines
+			continuationLines = 0
+		case isBlank(line):
+			if onlySpaces {
+				continue
+			}
+			nonTrailerLines += continuationLines
+			if recognized && trailerLines*3 >= nonTrailerLines || trailerLines > 0 && nonTrailerLines == 0 {
+				return lines[i+1:]
+			}
+			return nil
+		default:
+			onlySpaces = false
+			if hasGitGeneratedPrefix(line) {
+				trailerLines++
+				continuationLines = 0
+				recognized = true
+			} else if trailerSeparator(line) >= 1 && !isIndented(line) {
+				trailerLines++
+				continuationLines = 0
+			} else if isIndented(line) {
+				continuationLines++
+			} else {
+				nonTrailerLines += continuationLines + 1
+				continuationLines = 0
+			}
+		}
+	}
+	return nil
+}
+
+// trailerSeparator returns the index of the colon after the key of a trailer
+// line, a key being letters, digits and dashes optionally followed by spaces,
+// or -1 when the line is not a trailer
+func trailerSeparator(line string) int {
+	whitespace := false
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case !whitespace && (isAlnum(c) || c == '-'):
+		case i > 0 && (c == ' ' || c == '\t'):
+			whitespace = true
+		case c == ':':
+			return i
+		default:
+			return -1
+		}
+	}
+	return -1
+}
+
+func hasGitGeneratedPrefix(line string) bool {
+	for _, prefix := range gitGeneratedPrefixes {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func isAlnum(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
+
+func isIndented(line string) bool {
+	return line != "" && (line[0] == ' ' || line[0] == '\t')
+}
+
+func isBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
@@ -0,0 +1,33 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrailerValues(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		values  []string
+	}{
+		{"trailers", "Pair\n\nCo-authored-by: Jane Roe <jane@example.com>\nCo-Authored-By: Bob <bob@example.com>\n", []string{"Jane Roe <jane@example.com>", "Bob <bob@example.com>"}},
+		{"other keys", "Fix\n\nReviewed-by: Bob <bob@example.com>\nCo-authored-by : Jane\n", []string{"Jane"}},
+		{"folded", "Fix\n\nco-authored-by:  Jane\n  Roe <jane@example.com>\n", []string{"Jane Roe <jane@example.com>"}},
+		{"empty value", "Fix\n\nCo-authored-by:\nCo-authored-by: Jane\n", []string{"Jane"}},
+		{"trailing blank lines", "Fix\n\nCo-authored-by: Jane\n\n\n", []string{"Jane"}},
+		{"crlf", "Fix\r\n\r\nCo-authored-by: Jane\r\n", []string{"Jane"}},
+		{"title only", "Co-authored-by: Jane\n", nil},
+		{"not the last paragraph", "Fix\n\nCo-authored-by: Jane\n\nThanks.\n", nil},
+		{"prose", "Fix\n\nWorked on it with\nCo-authored-by: Jane\n", nil},
+		{"signed off", "Fix\n\nSigned-off-by: Bob <bob@example.com>\nnot a trailer\nCo-authored-by: Jane\n", []string{"Jane"}},
+		{"too much prose", "Fix\n\nSigned-off-by: Bob\n1\n2\n3\n4\n5\n6\n7\nCo-authored-by: Jane\n", nil},
+		{"comments", "Fix\n\nCo-authored-by: Jane\n# Co-authored-by: Bob\n", []string{"Jane"}},
+		{"key with spaces", "Fix\n\nCo authored by: Jane\n", nil},
+		{"patch", "Fix\n\nCo-authored-by: Jane\n---\n a.txt | 1 +\n", nil},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.values, trailerValues(tt.message, coauthorTrailer), tt.name)
+	}
+}
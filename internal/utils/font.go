@@ -29,3 +29,8 @@ func LoadFont(size float64) (font.Face, error) {
 
 	return face, nil
 }
+
+// FontData returns the embedded Space Mono Regular font file
+func FontData() []byte {
+	return spaceMonoRegular
+}
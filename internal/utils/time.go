@@ -2,7 +2,9 @@ package utils
 
 import (
 	"fmt"
+	"regexp"
 	"strconv"
+	"strings"
 	_time "time"
 )
 
@@ -20,41 +22,97 @@ func (t *time) Now() _time.Time {
 	return _time.Now()
 }
 
-var durationMap = map[string]_time.Duration{
-	"m": _time.Minute,
-	"h": _time.Hour,
-	"d": _time.Hour * 24,
-}
-
-// Only supports minutes, hours, and days
-func ParseDuration(s string) (_time.Duration, error) {
-	l := 0
-	d := _time.Duration(0)
-	for i := 0; i < len(s); i++ {
-		if v, ok := durationMap[string(s[i])]; ok {
-			n, err := strconv.Atoi(s[l:i])
-			if err != nil {
-				return 0, fmt.Errorf("invalid duration: %s", s)
-			}
-			d += _time.Duration(n) * v
-			l = i + 1
-		}
+// Duration is a relative span of calendar years, months and days and of
+// clock time, such as 1y6mo or 2w3d12h
+type Duration struct {
+	Years  int
+	Months int
+	Days   int
+	Clock  _time.Duration
+}
+
+// durationUnits lists the units of ParseDuration in error messages
+const durationUnits = "y, mo, w, d, h or m"
+
+// ParseDuration parses numbers each followed by a unit: y for years, mo for
+// months, w for weeks, d for days, h for hours and m for minutes
+func ParseDuration(s string) (Duration, error) {
+	var d Duration
+	if s == "" {
+		return d, fmt.Errorf("invalid duration: empty, expected numbers followed by %s", durationUnits)
 	}
-	if l < len(s) {
-		return 0, fmt.Errorf("invalid duration: %s", s)
+	for i := 0; i < len(s); {
+		start := i
+		for i < len(s) && isDigit(s[i]) {
+			i++
+		}
+		if i == start {
+			return Duration{}, fmt.Errorf("invalid duration: %s: expected a number before %s", s, s[i:])
+		}
+		n, err := strconv.Atoi(s[start:i])
+		if err != nil || n > maxDurationValue {
+			return Duration{}, fmt.Errorf("invalid duration: %s: %s is too large", s, s[start:i])
+		}
+		unitStart := i
+		for i < len(s) && !isDigit(s[i]) {
+			i++
+		}
+		switch unit := s[unitStart:i]; unit {
+		case "y":
+			d.Years += n
+		case "mo":
+			d.Months += n
+		case "w":
+			d.Days += 7 * n
+		case "d":
+			d.Days += n
+		case "h":
+			d.Clock += _time.Duration(n) * _time.Hour
+		case "m":
+			d.Clock += _time.Duration(n) * _time.Minute
+		case "":
+			return Duration{}, fmt.Errorf("invalid duration: %s: %d has no unit, expected %s", s, n, durationUnits)
+		default:
+			return Duration{}, fmt.Errorf("invalid duration: %s: unknown unit %q, expected %s", s, unit, durationUnits)
+		}
 	}
 	return d, nil
 }
 
+// maxDurationValue keeps the numbers of a duration within the range of
+// time.Duration and of the calendar
+const maxDurationValue = 1_000_000
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+// Before returns the time the duration before t. Years and months are
+// subtracted on the calendar, keeping the day of the month unless the month
+// is shorter, so 1mo before March 31 is the last day of February. Days keep
+// the time of day across daylight saving time changes.
+func (d Duration) Before(t _time.Time) _time.Time {
+	year, month, day := t.Date()
+	hour, minute, sec := t.Clock()
+	// The first day of the month normalizes it, then the day is clamped
+	first := _time.Date(year-d.Years, month-_time.Month(d.Months), 1, hour, minute, sec, t.Nanosecond(), t.Location())
+	if last := first.AddDate(0, 1, -1).Day(); day > last {
+		day = last
+	}
+	t = first.AddDate(0, 0, day-1-d.Days)
+	return t.Add(-d.Clock)
+}
+
 var dateTimeFormats = []string{
 	"2006-01-02 15:04:05",
 	"2006-01-02 15:04",
 	"2006-01-02",
 }
 
-func ParseDateTime(s string) (_time.Time, error) {
+// ParseDateTime parses a date and time, in loc unless it has a time zone
+func ParseDateTime(s string, loc *_time.Location) (_time.Time, error) {
 	for i := range dateTimeFormats {
-		t, err := _time.ParseInLocation(dateTimeFormats[i], s, _time.Local)
+		t, err := _time.ParseInLocation(dateTimeFormats[i], s, loc)
 		if err == nil {
 			return t, nil
 		}
@@ -73,3 +131,126 @@ func ParseDateTime(s string) (_time.Time, error) {
 	}
 	return _time.Time{}, fmt.Errorf("invalid datetime: %s", s)
 }
+
+// Period is a named date range, from the start of Since to the end of Until,
+// the last second of the range
+type Period struct {
+	Since _time.Time
+	Until _time.Time
+	Label string // such as "Q3 2025" or "Week of Mar 3, 2025"
+}
+
+var (
+	yearPattern    = regexp.MustCompile(`^(\d{4})$`)
+	quarterPattern = regexp.MustCompile(`^(\d{4})-[qQ]([1-4])$`)
+	monthPattern   = regexp.MustCompile(`^(\d{4})-(\d{2})$`)
+)
+
+// ParsePeriod resolves a named period relative to now, in the location of
+// now: today, yesterday, this-week, last-week, this-month, last-month,
+// this-quarter, last-quarter, this-year and last-year, or a year (2025), a
+// quarter (2025-Q3), a month (2025-10) or a day (2025-10-14). Weeks start on
+// weekStart.
+func ParsePeriod(s string, now _time.Time, weekStart _time.Weekday) (Period, error) {
+	loc := now.Location()
+	year, month, day := now.Date()
+	today := _time.Date(year, month, day, 0, 0, 0, 0, loc)
+	thisWeek := today.AddDate(0, 0, -(int(today.Weekday())-int(weekStart)+7)%7)
+	thisMonth := _time.Date(year, month, 1, 0, 0, 0, 0, loc)
+	thisQuarter := _time.Date(year, month-(month-1)%3, 1, 0, 0, 0, 0, loc)
+	thisYear := _time.Date(year, 1, 1, 0, 0, 0, 0, loc)
+
+	switch strings.ToLower(s) {
+	case "today":
+		return dayPeriod(today), nil
+	case "yesterday":
+		return dayPeriod(today.AddDate(0, 0, -1)), nil
+	case "this-week":
+		return weekPeriod(thisWeek), nil
+	case "last-week":
+		return weekPeriod(thisWeek.AddDate(0, 0, -7)), nil
+	case "this-month":
+		return monthPeriod(thisMonth), nil
+	case "last-month":
+		return monthPeriod(thisMonth.AddDate(0, -1, 0)), nil
+	case "this-quarter":
+		return quarterPeriod(thisQuarter), nil
+	case "last-quarter":
+		return quarterPeriod(thisQuarter.AddDate(0, -3, 0)), nil
+	case "this-year":
+		return yearPeriod(thisYear), nil
+	case "last-year":
+		return yearPeriod(thisYear.AddDate(-1, 0, 0)), nil
+	}
+
+	if m := yearPattern.FindStringSubmatch(s); m != nil {
+		y, _ := strconv.Atoi(m[1])
+		return yearPeriod(_time.Date(y, 1, 1, 0, 0, 0, 0, loc)), nil
+	}
+	if m := quarterPattern.FindStringSubmatch(s); m != nil {
+		y, _ := strconv.Atoi(m[1])
+		q, _ := strconv.Atoi(m[2])
+		return quarterPeriod(_time.Date(y, _time.Month(3*q-2), 1, 0, 0, 0, 0, loc)), nil
+	}
+	if m := monthPattern.FindStringSubmatch(s); m != nil {
+		y, _ := strconv.Atoi(m[1])
+		mo, _ := strconv.Atoi(m[2])
+		if mo < 1 || mo > 12 {
+			return Period{}, fmt.Errorf("invalid range: %s: month %s is not between 01 and 12", s, m[2])
+		}
+		return monthPeriod(_time.Date(y, _time.Month(mo), 1, 0, 0, 0, 0, loc)), nil
+	}
+	if t, err := _time.ParseInLocation(_time.DateOnly, s, loc); err == nil {
+		return dayPeriod(t), nil
+	}
+	return Period{}, fmt.Errorf("invalid range: %s, expected today, yesterday, this-week, last-week, this-month, last-month, "+
+		"this-quarter, last-quarter, this-year, last-year, a year (2025), a quarter (2025-Q3), a month (2025-10) or a day (2025-10-14)", s)
+}
+
+// newPeriod returns the period from start to the second before end
+func newPeriod(start, end _time.Time, label string) Period {
+	return Period{Since: start, Until: end.Add(-_time.Second), Label: label}
+}
+
+func dayPeriod(start _time.Time) Period {
+	return newPeriod(start, start.AddDate(0, 0, 1), start.Format("Jan 2, 2006"))
+}
+
+func weekPeriod(start _time.Time) Period {
+	return newPeriod(start, start.AddDate(0, 0, 7), start.Format("Week of Jan 2, 2006"))
+}
+
+func monthPeriod(start _time.Time) Period {
+	return newPeriod(start, start.AddDate(0, 1, 0), start.Format("January 2006"))
+}
+
+func quarterPeriod(start _time.Time) Period {
+	return newPeriod(start, start.AddDate(0, 3, 0), fmt.Sprintf("Q%d %d", (int(start.Month())+2)/3, start.Year()))
+}
+
+func yearPeriod(start _time.Time) Period {
+	return newPeriod(start, start.AddDate(1, 0, 0), start.Format("2006"))
+}
+
+// ParseWeekday parses the English name of a day of the week, ignoring case
+func ParseWeekday(s string) (_time.Weekday, error) {
+	for d := _time.Sunday; d <= _time.Saturday; d++ {
+		if strings.EqualFold(s, d.String()) {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown weekday %q, expected monday, tuesday, wednesday, thursday, friday, saturday or sunday", s)
+}
+
+// LoadLocation returns the time zone with the IANA name, such as
+// Europe/Bucharest or UTC, or the local time zone for "local" or ""
+func LoadLocation(name string) (*_time.Location, error) {
+	if name == "" || strings.EqualFold(name, "local") {
+		return _time.Local, nil
+	}
+	loc, err := _time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q, expected an IANA name such as Europe/Bucharest, UTC or local", name)
+	}
+	return loc, nil
+}
@@ -8,61 +8,216 @@ import (
 )
 
 func Test_ParseDuration(t *testing.T) {
-	d, err := ParseDuration("1d1h1m")
+	tests := []struct {
+		input    string
+		expected Duration
+		err      string
+	}{
+		{"1d1h1m", Duration{Days: 1, Clock: _time.Hour + _time.Minute}, ""},
+		{"2d", Duration{Days: 2}, ""},
+		{"12h", Duration{Clock: 12 * _time.Hour}, ""},
+		{"30m", Duration{Clock: 30 * _time.Minute}, ""},
+		{"2w", Duration{Days: 14}, ""},
+		{"1w2d", Duration{Days: 9}, ""},
+		{"3mo", Duration{Months: 3}, ""},
+		{"1y", Duration{Years: 1}, ""},
+		{"1y6mo2w", Duration{Years: 1, Months: 6, Days: 14}, ""},
+		{"1mo30m", Duration{Months: 1, Clock: 30 * _time.Minute}, ""},
+		{"1d1d", Duration{Days: 2}, ""},
+		{"0d", Duration{}, ""},
+		{"", Duration{}, "invalid duration: empty, expected numbers followed by y, mo, w, d, h or m"},
+		{"1", Duration{}, "invalid duration: 1: 1 has no unit, expected y, mo, w, d, h or m"},
+		{"1d1h1", Duration{}, "invalid duration: 1d1h1: 1 has no unit, expected y, mo, w, d, h or m"},
+		{"1d1h1m1", Duration{}, "invalid duration: 1d1h1m1: 1 has no unit, expected y, mo, w, d, h or m"},
+		{"1d1h1m1s", Duration{}, `invalid duration: 1d1h1m1s: unknown unit "s", expected y, mo, w, d, h or m`},
+		{"3M", Duration{}, `invalid duration: 3M: unknown unit "M", expected y, mo, w, d, h or m`},
+		{"2weeks", Duration{}, `invalid duration: 2weeks: unknown unit "weeks", expected y, mo, w, d, h or m`},
+		{"d", Duration{}, "invalid duration: d: expected a number before d"},
+		{"1d-2h", Duration{}, `invalid duration: 1d-2h: unknown unit "d-", expected y, mo, w, d, h or m`},
+		{"99999999999999999999d", Duration{}, "invalid duration: 99999999999999999999d: 99999999999999999999 is too large"},
+		{"2000000y", Duration{}, "invalid duration: 2000000y: 2000000 is too large"},
+	}
+	for _, tt := range tests {
+		d, err := ParseDuration(tt.input)
+		if tt.err != "" {
+			assert.EqualError(t, err, tt.err, tt.input)
+		} else {
+			assert.NoError(t, err, tt.input)
+		}
+		assert.Equal(t, tt.expected, d, tt.input)
+	}
+}
+
+func Test_DurationBefore(t *testing.T) {
+	newYork, err := _time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("time zone database not available:", err)
+	}
+	tests := []struct {
+		name     string
+		duration string
+		now      _time.Time
+		expected _time.Time
+	}{
+		{"days", "1d1h1m", _time.Date(2025, 3, 10, 12, 0, 0, 0, _time.UTC), _time.Date(2025, 3, 9, 10, 59, 0, 0, _time.UTC)},
+		{"weeks", "2w", _time.Date(2025, 3, 10, 12, 0, 0, 0, _time.UTC), _time.Date(2025, 2, 24, 12, 0, 0, 0, _time.UTC)},
+		{"month", "1mo", _time.Date(2025, 3, 10, 12, 0, 0, 0, _time.UTC), _time.Date(2025, 2, 10, 12, 0, 0, 0, _time.UTC)},
+		{"end of month", "1mo", _time.Date(2025, 3, 31, 12, 0, 0, 0, _time.UTC), _time.Date(2025, 2, 28, 12, 0, 0, 0, _time.UTC)},
+		{"end of month leap year", "1mo", _time.Date(2024, 3, 31, 12, 0, 0, 0, _time.UTC), _time.Date(2024, 2, 29, 12, 0, 0, 0, _time.UTC)},
+		{"months across years", "3mo", _time.Date(2025, 1, 31, 8, 0, 0, 0, _time.UTC), _time.Date(2024, 10, 31, 8, 0, 0, 0, _time.UTC)},
+		{"months then days", "1mo1d", _time.Date(2025, 3, 31, 12, 0, 0, 0, _time.UTC), _time.Date(2025, 2, 27, 12, 0, 0, 0, _time.UTC)},
+		{"year", "1y", _time.Date(2025, 3, 10, 12, 0, 0, 0, _time.UTC), _time.Date(2024, 3, 10, 12, 0, 0, 0, _time.UTC)},
+		{"leap day", "1y", _time.Date(2024, 2, 29, 12, 0, 0, 0, _time.UTC), _time.Date(2023, 2, 28, 12, 0, 0, 0, _time.UTC)},
+		{"years and months", "1y6mo", _time.Date(2025, 3, 10, 12, 0, 0, 0, _time.UTC), _time.Date(2023, 9, 10, 12, 0, 0, 0, _time.UTC)},
+		{"daylight saving time", "1d", _time.Date(2025, 3, 9, 12, 0, 0, 0, newYork), _time.Date(2025, 3, 8, 12, 0, 0, 0, newYork)},
+		{"hours across daylight saving time", "24h", _time.Date(2025, 3, 9, 12, 0, 0, 0, newYork), _time.Date(2025, 3, 8, 11, 0, 0, 0, newYork)},
+		{"zero", "0d", _time.Date(2025, 3, 10, 12, 0, 0, 0, _time.UTC), _time.Date(2025, 3, 10, 12, 0, 0, 0, _time.UTC)},
+	}
+	for _, tt := range tests {
+		d, err := ParseDuration(tt.duration)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, tt.expected, d.Before(tt.now), tt.name)
+	}
+}
+
+func Test_ParseDateTime(t *testing.T) {
+	tm, err := ParseDateTime("2024-01-01 12:03:04", _time.Local)
+	assert.NoError(t, err)
+	assert.Equal(t, _time.Date(2024, 1, 1, 12, 3, 4, 0, _time.Local), tm)
+
+	tm, err = ParseDateTime("2024-01-01 23:45", _time.Local)
 	assert.NoError(t, err)
-	assert.Equal(t, 24*_time.Hour+_time.Hour+_time.Minute, d)
+	assert.Equal(t, _time.Date(2024, 1, 1, 23, 45, 0, 0, _time.Local), tm)
 
-	d, err = ParseDuration("2d")
+	tm, err = ParseDateTime("2024-12-31", _time.Local)
 	assert.NoError(t, err)
-	assert.Equal(t, 48*_time.Hour, d)
+	assert.Equal(t, _time.Date(2024, 12, 31, 0, 0, 0, 0, _time.Local), tm)
 
-	d, err = ParseDuration("12h")
+	tm, err = ParseDateTime("2024-12-31T23:59:59Z", _time.Local)
 	assert.NoError(t, err)
-	assert.Equal(t, 12*_time.Hour, d)
+	assert.Equal(t, _time.Date(2024, 12, 31, 23, 59, 59, 0, _time.UTC), tm)
 
-	d, err = ParseDuration("30m")
+	tm, err = ParseDateTime("Tue, 13 Aug 2024 08:01:29 GMT", _time.Local)
 	assert.NoError(t, err)
-	assert.Equal(t, 30*_time.Minute, d)
+	assert.Equal(t, _time.Date(2024, 8, 13, 8, 1, 29, 0, _time.FixedZone("GMT", 0)), tm)
 
-	d, err = ParseDuration("1")
-	assert.EqualError(t, err, "invalid duration: 1")
-	assert.Equal(t, _time.Duration(0), d)
+	tm, err = ParseDateTime("Tue, 13 Aug 2024 08:01:29 +0200", _time.Local)
+	assert.NoError(t, err)
+	assert.Equal(t, _time.Date(2024, 8, 13, 8, 1, 29, 0, _time.FixedZone("", 2*60*60)), tm)
 
-	d, err = ParseDuration("1d1h1")
-	assert.EqualError(t, err, "invalid duration: 1d1h1")
-	assert.Equal(t, _time.Duration(0), d)
+	// Dates without a time zone are in the given one
+	eet := _time.FixedZone("EET", 2*60*60)
+	tm, err = ParseDateTime("2025-01-01", eet)
+	assert.NoError(t, err)
+	assert.Equal(t, _time.Date(2025, 1, 1, 0, 0, 0, 0, eet), tm)
 
-	d, err = ParseDuration("1d1h1m1")
-	assert.EqualError(t, err, "invalid duration: 1d1h1m1")
-	assert.Equal(t, _time.Duration(0), d)
+	tm, err = ParseDateTime("2025-01-01T00:00:00Z", eet)
+	assert.NoError(t, err)
+	assert.Equal(t, _time.Date(2025, 1, 1, 0, 0, 0, 0, _time.UTC), tm)
 
-	d, err = ParseDuration("1d1h1m1s")
-	assert.EqualError(t, err, "invalid duration: 1d1h1m1s")
-	assert.Equal(t, _time.Duration(0), d)
+	_, err = ParseDateTime("2025-13-01", eet)
+	assert.EqualError(t, err, "invalid datetime: 2025-13-01")
 }
 
-func Test_ParseDateTime(t *testing.T) {
-	tm, err := ParseDateTime("2024-01-01 12:03:04")
+func Test_LoadLocation(t *testing.T) {
+	loc, err := LoadLocation("")
 	assert.NoError(t, err)
-	assert.Equal(t, _time.Date(2024, 1, 1, 12, 3, 4, 0, _time.Local), tm)
+	assert.Equal(t, _time.Local, loc)
 
-	tm, err = ParseDateTime("2024-01-01 23:45")
+	loc, err = LoadLocation("Local")
 	assert.NoError(t, err)
-	assert.Equal(t, _time.Date(2024, 1, 1, 23, 45, 0, 0, _time.Local), tm)
+	assert.Equal(t, _time.Local, loc)
 
-	tm, err = ParseDateTime("2024-12-31")
+	loc, err = LoadLocation("UTC")
 	assert.NoError(t, err)
-	assert.Equal(t, _time.Date(2024, 12, 31, 0, 0, 0, 0, _time.Local), tm)
+	assert.Equal(t, _time.UTC, loc)
+
+	_, err = LoadLocation("Mars/Olympus_Mons")
+	assert.EqualError(t, err, `unknown time zone "Mars/Olympus_Mons", expected an IANA name such as Europe/Bucharest, UTC or local`)
+}
+
+func Test_ParsePeriod(t *testing.T) {
+	// Monday, Mar 31, 2025
+	now := _time.Date(2025, 3, 31, 15, 4, 5, 0, _time.UTC)
+	date := func(year int, month _time.Month, day int) _time.Time {
+		return _time.Date(year, month, day, 0, 0, 0, 0, _time.UTC)
+	}
+	end := func(year int, month _time.Month, day int) _time.Time {
+		return _time.Date(year, month, day, 23, 59, 59, 0, _time.UTC)
+	}
+	tests := []struct {
+		input     string
+		now       _time.Time
+		weekStart _time.Weekday
+		expected  Period
+		err       string
+	}{
+		{"today", now, _time.Monday, Period{date(2025, 3, 31), end(2025, 3, 31), "Mar 31, 2025"}, ""},
+		{"yesterday", now, _time.Monday, Period{date(2025, 3, 30), end(2025, 3, 30), "Mar 30, 2025"}, ""},
+		{"yesterday", date(2025, 1, 1), _time.Monday, Period{date(2024, 12, 31), end(2024, 12, 31), "Dec 31, 2024"}, ""},
+		{"this-week", now, _time.Monday, Period{date(2025, 3, 31), end(2025, 4, 6), "Week of Mar 31, 2025"}, ""},
+		{"this-week", now, _time.Sunday, Period{date(2025, 3, 30), end(2025, 4, 5), "Week of Mar 30, 2025"}, ""},
+		{"last-week", now, _time.Monday, Period{date(2025, 3, 24), end(2025, 3, 30), "Week of Mar 24, 2025"}, ""},
+		{"last-week", now, _time.Tuesday, Period{date(2025, 3, 18), end(2025, 3, 24), "Week of Mar 18, 2025"}, ""},
+		{"last-week", date(2025, 1, 2), _time.Monday, Period{date(2024, 12, 23), end(2024, 12, 29), "Week of Dec 23, 2024"}, ""},
+		{"this-month", now, _time.Monday, Period{date(2025, 3, 1), end(2025, 3, 31), "March 2025"}, ""},
+		{"last-month", now, _time.Monday, Period{date(2025, 2, 1), end(2025, 2, 28), "February 2025"}, ""},
+		{"last-month", date(2025, 1, 15), _time.Monday, Period{date(2024, 12, 1), end(2024, 12, 31), "December 2024"}, ""},
+		{"this-quarter", now, _time.Monday, Period{date(2025, 1, 1), end(2025, 3, 31), "Q1 2025"}, ""},
+		{"last-quarter", now, _time.Monday, Period{date(2024, 10, 1), end(2024, 12, 31), "Q4 2024"}, ""},
+		{"this-quarter", date(2025, 8, 20), _time.Monday, Period{date(2025, 7, 1), end(2025, 9, 30), "Q3 2025"}, ""},
+		{"this-year", now, _time.Monday, Period{date(2025, 1, 1), end(2025, 12, 31), "2025"}, ""},
+		{"last-year", now, _time.Monday, Period{date(2024, 1, 1), end(2024, 12, 31), "2024"}, ""},
+		{"Last-Year", now, _time.Monday, Period{date(2024, 1, 1), end(2024, 12, 31), "2024"}, ""},
+		{"2023", now, _time.Monday, Period{date(2023, 1, 1), end(2023, 12, 31), "2023"}, ""},
+		{"2025-Q3", now, _time.Monday, Period{date(2025, 7, 1), end(2025, 9, 30), "Q3 2025"}, ""},
+		{"2025-q4", now, _time.Monday, Period{date(2025, 10, 1), end(2025, 12, 31), "Q4 2025"}, ""},
+		{"2024-02", now, _time.Monday, Period{date(2024, 2, 1), end(2024, 2, 29), "February 2024"}, ""},
+		{"2025-10-14", now, _time.Monday, Period{date(2025, 10, 14), end(2025, 10, 14), "Oct 14, 2025"}, ""},
+		{"2025-13", now, _time.Monday, Period{}, "invalid range: 2025-13: month 13 is not between 01 and 12"},
+		{"2025-Q5", now, _time.Monday, Period{}, "invalid range: 2025-Q5, expected today, yesterday, this-week, last-week, this-month, last-month, this-quarter, last-quarter, this-year, last-year, a year (2025), a quarter (2025-Q3), a month (2025-10) or a day (2025-10-14)"},
+		{"next-week", now, _time.Monday, Period{}, "invalid range: next-week, expected today, yesterday, this-week, last-week, this-month, last-month, this-quarter, last-quarter, this-year, last-year, a year (2025), a quarter (2025-Q3), a month (2025-10) or a day (2025-10-14)"},
+	}
+	for _, tt := range tests {
+		period, err := ParsePeriod(tt.input, tt.now, tt.weekStart)
+		if tt.err != "" {
+			assert.EqualError(t, err, tt.err, tt.input)
+		} else {
+			assert.NoError(t, err, tt.input)
+		}
+		assert.Equal(t, tt.expected, period, tt.input)
+	}
+}
 
-	tm, err = ParseDateTime("2024-12-31T23:59:59Z")
+func Test_ParsePeriod_DaylightSavingTime(t *testing.T) {
+	newYork, err := _time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("time zone database not available:", err)
+	}
+	// Clocks go forward on Mar 9, 2025 and back on Nov 2, 2025
+	period, err := ParsePeriod("last-week", _time.Date(2025, 3, 12, 9, 0, 0, 0, newYork), _time.Monday)
 	assert.NoError(t, err)
-	assert.Equal(t, _time.Date(2024, 12, 31, 23, 59, 59, 0, _time.UTC), tm)
+	assert.Equal(t, _time.Date(2025, 3, 3, 0, 0, 0, 0, newYork), period.Since)
+	assert.Equal(t, _time.Date(2025, 3, 9, 23, 59, 59, 0, newYork), period.Until)
 
-	tm, err = ParseDateTime("Tue, 13 Aug 2024 08:01:29 GMT")
+	period, err = ParsePeriod("today", _time.Date(2025, 11, 2, 9, 0, 0, 0, newYork), _time.Monday)
 	assert.NoError(t, err)
-	assert.Equal(t, _time.Date(2024, 8, 13, 8, 1, 29, 0, _time.FixedZone("GMT", 0)), tm)
+	assert.Equal(t, _time.Date(2025, 11, 2, 0, 0, 0, 0, newYork), period.Since)
+	assert.Equal(t, _time.Date(2025, 11, 2, 23, 59, 59, 0, newYork), period.Until)
+	assert.Equal(t, 25*_time.Hour-_time.Second, period.Until.Sub(period.Since))
+}
 
-	tm, err = ParseDateTime("Tue, 13 Aug 2024 08:01:29 +0200")
+func Test_ParseWeekday(t *testing.T) {
+	d, err := ParseWeekday("Sunday")
 	assert.NoError(t, err)
-	assert.Equal(t, _time.Date(2024, 8, 13, 8, 1, 29, 0, _time.FixedZone("", 2*60*60)), tm)
+	assert.Equal(t, _time.Sunday, d)
+
+	d, err = ParseWeekday("monday")
+	assert.NoError(t, err)
+	assert.Equal(t, _time.Monday, d)
+
+	_, err = ParseWeekday("mon")
+	assert.EqualError(t, err, `unknown weekday "mon", expected monday, tuesday, wednesday, thursday, friday, saturday or sunday`)
 }
@@ -1,6 +1,11 @@
 package main
 
-import "github.com/radulucut/gitbrag/cmd/gitbrag"
+import (
+	// Time zones of --tz on systems without a time zone database, such as Windows
+	_ "time/tzdata"
+
+	"github.com/radulucut/gitbrag/cmd/gitbrag"
+)
 
 var (
 	version = "dev"
@@ -0,0 +1,72 @@
+package gitbrag_test
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"github.com/radulucut/gitbrag/pkg/gitbrag"
+)
+
+func ExampleCollect() {
+	report, err := gitbrag.Collect(context.Background(), gitbrag.Options{
+		Dirs:  []string{"./projects"},
+		Since: time.Now().AddDate(0, 0, -7),
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("%d commits, %d insertions(+), %d deletions(-)",
+		report.Total.Commits, report.Total.Insertions, report.Total.Deletions)
+}
+
+func ExampleNewPNGRenderer() {
+	report, err := gitbrag.Collect(context.Background(), gitbrag.Options{
+		Dirs:     []string{"./projects"},
+		Since:    time.Now().AddDate(0, -1, 0),
+		ByAuthor: true,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	renderer, err := gitbrag.NewPNGRenderer(gitbrag.RenderOptions{
+		Background: "#282a36",
+		Color:      "#f8f8f2",
+		Lang:       true,
+		Heatmap:    gitbrag.HeatmapCommits,
+		ByAuthor:   true,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Any io.Writer works, e.g. an HTTP response or a chat upload
+	f, err := os.Create("stats.png")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+	if err := renderer.Render(f, &report); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func ExampleNewRenderer() {
+	report, err := gitbrag.Collect(context.Background(), gitbrag.Options{
+		Dirs: []string{"./projects"},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	renderer, err := gitbrag.NewRenderer(gitbrag.FormatJSON, gitbrag.RenderOptions{ByRepo: true})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := renderer.Render(os.Stdout, &report); err != nil {
+		log.Fatal(err)
+	}
+}
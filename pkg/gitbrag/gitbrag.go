@@ -0,0 +1,155 @@
+// Package gitbrag collects git statistics from local repositories and renders
+// them in the same formats as the gitbrag command, for use from other Go programs.
+package gitbrag
+
+import (
+	"context"
+	"io"
+	"regexp"
+	"time"
+
+	"github.com/radulucut/gitbrag/internal"
+	"github.com/radulucut/gitbrag/internal/utils"
+)
+
+type (
+	// Report holds the statistics collected from all scanned repositories
+	Report = internal.Report
+	// GitStats holds the totals of a repository, an author or the whole report
+	GitStats = internal.GitStats
+	// RepoStats holds the statistics of a single repository
+	RepoStats = internal.RepoStats
+	// AuthorStats holds the statistics of a single author
+	AuthorStats = internal.AuthorStats
+	// DayStats holds the activity of a single day
+	DayStats = internal.DayStats
+)
+
+// Backends reading the history of the repositories
+const (
+	BackendExec   = internal.BackendExec   // runs git log, needs git on the PATH
+	BackendNative = internal.BackendNative // reads the object database directly
+)
+
+// Ref selections of Options.Refs, any other selection is a glob of ref names
+// such as origin/release/* where * also matches slashes
+const (
+	RefsHead     = internal.RefsHead
+	RefsBranches = internal.RefsBranches
+	RefsRemotes  = internal.RefsRemotes
+	RefsTags     = internal.RefsTags
+	RefsAll      = internal.RefsAll
+)
+
+// Dates of Options.DateField
+const (
+	DateCommitter = internal.DateCommitter // when the commit was last committed, rebased or cherry-picked, like git log --since
+	DateAuthor    = internal.DateAuthor    // when the change was first written
+)
+
+// Merge policies of Options.Merges. By default merges are counted as commits
+// without their changes, which are counted in the merged commits.
+const (
+	MergesInclude     = internal.MergesInclude     // also count the changes of merges against their first parent
+	MergesExclude     = internal.MergesExclude     // skip merges
+	MergesFirstParent = internal.MergesFirstParent // follow only the first parent of merges, what landed on the mainline
+)
+
+// Attribution modes of Options.Coauthors for the Co-authored-by trailers of
+// commits, used by the author filters and Options.ByAuthor
+const (
+	CoauthorsCredit = internal.CoauthorsCredit // credit co-authors with the whole commit
+	CoauthorsSplit  = internal.CoauthorsSplit  // split the lines of a commit evenly between its authors
+	CoauthorsIgnore = internal.CoauthorsIgnore // attribute commits to their author only
+)
+
+// Options selects the repositories and commits to collect
+type Options struct {
+	Dirs           []string       // repositories or directories searched for repositories
+	Since          time.Time      // only commits after this date, zero for no limit
+	Until          time.Time      // only commits before this date, zero for no limit
+	DateField      string         // DateCommitter or DateAuthor, the date of the commits Since, Until and the days use, DateCommitter when empty
+	Location       *time.Location // time zone of the days commits are grouped in and of the period label, time.Local when nil
+	AuthorTime     bool           // group commits into days in the time zone of their author instead of Location
+	Author         string         // only commits whose author name or email matches, like a single entry of Authors
+	Authors        []string       // only commits whose author "Name <email>" matches one of the basic regular expressions, ignoring case
+	ExcludeAuthors []string       // commits whose author matches one of the patterns are not counted, e.g. bots
+	AuthorsFile    string         // only commits of the identities listed in the file, one "Name <email>", email or name per line
+	Coauthors      string         // CoauthorsCredit or CoauthorsSplit to attribute commits to their Co-authored-by trailers too, CoauthorsIgnore when empty
+	MailmapFile    string         // mailmap merging author identities in every repository, e.g. DefaultMailmapFile(), empty for none
+	NoMailmap      bool           // ignore the .mailmap of the repositories
+	ExcludeFiles   *regexp.Regexp // files matching the pattern are not counted
+	Include        []string       // gitignore-style patterns such as **/*.go, only the files matching them are counted
+	Exclude        []string       // gitignore-style patterns of the files not to count, after the .gitbragignore of each repository
+	ExcludeDirs    *regexp.Regexp // directories matching the pattern are not searched
+	ByAuthor       bool           // group the statistics by author in Report.Authors
+	Jobs           int            // repositories scanned in parallel, the number of CPUs when not set
+	CacheDir       string         // directory to cache the commits of unchanged repositories in, empty to disable caching
+	Backend        string         // BackendExec or BackendNative, BackendExec when empty
+	Refs           []string       // refs to read commits from, RefsBranches when empty
+	Dedupe         bool           // count cherry-picked and rebased copies of a change once, also across repositories
+	Merges         string         // MergesInclude, MergesExclude or MergesFirstParent, merges are counted without their changes when empty
+	Moved          bool           // count the lines kept by renamed and copied files in GitStats.MovedLines
+	BinaryBytes    bool           // count the size change of binary files in GitStats.BinaryBytes
+	Generated      bool           // also count the files .gitattributes marks as generated, vendored or documentation and generated files such as lockfiles
+	Warnings       io.Writer      // receives warnings about skipped directories, discarded when nil
+}
+
+// Collect scans the directories for git repositories and returns their statistics.
+// Directories that can't be read are skipped with a warning. It returns the
+// context error if ctx is canceled before all repositories are scanned.
+func Collect(ctx context.Context, opts Options) (Report, error) {
+	warnings := opts.Warnings
+	if warnings == nil {
+		warnings = io.Discard
+	}
+	authors := opts.Authors
+	if opts.Author != "" {
+		authors = append([]string{opts.Author}, authors...)
+	}
+	core := internal.NewCore(utils.NewTime(), internal.NewPrinter(nil, io.Discard, warnings))
+	report, err := core.Collect(ctx, &internal.RunOptions{
+		Dirs:           opts.Dirs,
+		Since:          opts.Since,
+		Until:          opts.Until,
+		DateField:      opts.DateField,
+		Location:       opts.Location,
+		AuthorTime:     opts.AuthorTime,
+		Authors:        authors,
+		ExcludeAuthors: opts.ExcludeAuthors,
+		AuthorsFile:    opts.AuthorsFile,
+		Coauthors:      opts.Coauthors,
+		MailmapFile:    opts.MailmapFile,
+		NoMailmap:      opts.NoMailmap,
+		ExcludeFiles:   opts.ExcludeFiles,
+		Include:        opts.Include,
+		Exclude:        opts.Exclude,
+		ExcludeDirs:    opts.ExcludeDirs,
+		ByAuthor:       opts.ByAuthor,
+		CacheDir:       opts.CacheDir,
+		Jobs:           opts.Jobs,
+		Backend:        opts.Backend,
+		Refs:           opts.Refs,
+		Dedupe:         opts.Dedupe,
+		Merges:         opts.Merges,
+		Moved:          opts.Moved,
+		BinaryBytes:    opts.BinaryBytes,
+		Generated:      opts.Generated,
+	})
+	if err != nil {
+		return Report{}, err
+	}
+	return *report, nil
+}
+
+// DefaultMailmapFile returns the mailmap read by the gitbrag command, gitbrag/mailmap
+// under $XDG_CONFIG_HOME or under the user config directory of the platform
+func DefaultMailmapFile() (string, error) {
+	return internal.DefaultMailmapFile()
+}
+
+// DefaultCacheDir returns the cache directory used by the gitbrag command,
+// gitbrag under $XDG_CACHE_HOME or under the user cache directory of the platform
+func DefaultCacheDir() (string, error) {
+	return internal.DefaultCacheDir()
+}
@@ -0,0 +1,243 @@
+package gitbrag
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image/png"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// createGitRepo creates a repository with a commit by Test User and a later one by John Doe
+func createGitRepo(t *testing.T) string {
+	dir := filepath.Join(t.TempDir(), "app")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, dir, "2025-03-10T10:00:00Z", "init", "-b", "main")
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, dir, "2025-03-10T10:00:00Z", "add", ".")
+	runGit(t, dir, "2025-03-10T10:00:00Z", "commit", "-m", "initial commit")
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("# App\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, dir, "2025-03-12T09:30:00Z", "add", ".")
+	runGit(t, dir, "2025-03-12T09:30:00Z", "commit", "-m", "add readme", "--author", "John Doe <john.doe@example.com>")
+	return dir
+}
+
+func runGit(t *testing.T, dir, date string, args ...string) {
+	args = append([]string{"-c", "user.name=Test User", "-c", "user.email=test@example.com"}, args...)
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_AUTHOR_DATE="+date, "GIT_COMMITTER_DATE="+date)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Log(string(out))
+		t.Fatal(err)
+	}
+}
+
+func TestCollect(t *testing.T) {
+	dir := createGitRepo(t)
+
+	report, err := Collect(context.Background(), Options{Dirs: []string{filepath.Dir(dir)}, ByAuthor: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, report.Total.Repositories)
+	assert.Equal(t, 2, report.Total.Commits)
+	assert.Equal(t, 2, report.Total.FilesChanged)
+	assert.Equal(t, 4, report.Total.Insertions)
+	assert.Equal(t, 0, report.Total.Deletions)
+	assert.Equal(t, map[string]int{"Go": 3, "Markdown": 1}, report.Total.Languages)
+	assert.Equal(t, "", report.DateRange)
+
+	assert.Len(t, report.Repos, 1)
+	assert.Equal(t, "app", report.Repos[0].Name)
+
+	assert.Len(t, report.Authors, 2)
+	assert.Equal(t, "Test User <test@example.com>", report.Authors[0].Identity())
+	assert.Equal(t, "John Doe <john.doe@example.com>", report.Authors[1].Identity())
+}
+
+func TestCollectFilters(t *testing.T) {
+	dir := createGitRepo(t)
+
+	since := time.Date(2025, 3, 11, 0, 0, 0, 0, time.UTC)
+	report, err := Collect(context.Background(), Options{Dirs: []string{dir}, Since: since, Author: "John Doe"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, report.Total.Commits)
+	assert.Equal(t, 1, report.Total.Insertions)
+	assert.Equal(t, since, report.From)
+	assert.Equal(t, "Since Mar 11, 2025", report.DateRange)
+	assert.Empty(t, report.Authors)
+}
+
+func TestCollectWarnings(t *testing.T) {
+	warnings := new(bytes.Buffer)
+	report, err := Collect(context.Background(), Options{Dirs: []string{filepath.Join(t.TempDir(), "missing")}, Warnings: warnings})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 0, report.Total.Repositories)
+	assert.Contains(t, warnings.String(), "Warning: could not access")
+
+	_, err = Collect(context.Background(), Options{})
+	assert.EqualError(t, err, "no directories specified")
+}
+
+func TestCollectCanceled(t *testing.T) {
+	dir := createGitRepo(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := Collect(ctx, Options{Dirs: []string{dir}})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestRenderers(t *testing.T) {
+	dir := createGitRepo(t)
+	report, err := Collect(context.Background(), Options{Dirs: []string{dir}, ByAuthor: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := RenderOptions{Background: "282a36", Color: "f8f8f2", Lang: true, Heatmap: HeatmapCommits, ByRepo: true, ByAuthor: true}
+
+	text, err := NewTextRenderer(RenderOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := new(bytes.Buffer)
+	if err := text.Render(out, &report); err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, out.String(), "4 insertions(+)\n")
+
+	jsonRenderer, err := NewJSONRenderer(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if err := jsonRenderer.Render(out, &report); err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Insertions int               `json:"insertions"`
+		Authors    []json.RawMessage `json:"authors"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 4, doc.Insertions)
+	assert.Len(t, doc.Authors, 2)
+
+	pngRenderer, err := NewPNGRenderer(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if err := pngRenderer.Render(out, &report); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := png.DecodeConfig(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 800, cfg.Width)
+
+	svgRenderer, err := NewSVGRenderer(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if err := svgRenderer.Render(out, &report); err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, out.String(), `width="800"`)
+}
+
+func TestRenderMoved(t *testing.T) {
+	dir := createGitRepo(t)
+	runGit(t, dir, "2025-03-13T10:00:00Z", "mv", "main.go", "app.go")
+	runGit(t, dir, "2025-03-13T10:00:00Z", "commit", "-m", "rename main.go")
+	report, err := Collect(context.Background(), Options{Dirs: []string{dir}, Moved: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 3, report.Total.MovedLines)
+
+	out := new(bytes.Buffer)
+	for _, moved := range []bool{false, true} {
+		text, err := NewTextRenderer(RenderOptions{Moved: moved})
+		if err != nil {
+			t.Fatal(err)
+		}
+		out.Reset()
+		if err := text.Render(out, &report); err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, moved, strings.Contains(out.String(), "3 moved lines\n"), moved)
+	}
+}
+
+func TestRenderBinaryBytes(t *testing.T) {
+	dir := createGitRepo(t)
+	if err := os.WriteFile(filepath.Join(dir, "logo.png"), make([]byte, 100), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, dir, "2025-03-13T10:00:00Z", "add", "logo.png")
+	runGit(t, dir, "2025-03-13T10:00:00Z", "commit", "-m", "add logo")
+	report, err := Collect(context.Background(), Options{Dirs: []string{dir}, BinaryBytes: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 100, report.Total.BinaryBytes)
+
+	out := new(bytes.Buffer)
+	for _, binaryBytes := range []bool{false, true} {
+		opts := RenderOptions{BinaryBytes: binaryBytes}
+		text, err := NewTextRenderer(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out.Reset()
+		if err := text.Render(out, &report); err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, binaryBytes, strings.Contains(out.String(), "1 binary file (+100 B)\n"), binaryBytes)
+
+		svgRenderer, err := NewSVGRenderer(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out.Reset()
+		if err := svgRenderer.Render(out, &report); err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, binaryBytes, strings.Contains(out.String(), "1 binary file (+100 B)"), binaryBytes)
+	}
+}
+
+func TestNewRenderer(t *testing.T) {
+	assert.Equal(t, []string{FormatJSON, FormatPNG, FormatSVG, FormatText}, Formats())
+
+	_, err := NewRenderer("xml", RenderOptions{})
+	assert.EqualError(t, err, "unsupported format: xml")
+
+	_, err = NewPNGRenderer(RenderOptions{Heatmap: "weeks"})
+	assert.EqualError(t, err, "unsupported heatmap: weeks")
+
+	_, err = NewSVGRenderer(RenderOptions{Color: "not a color"})
+	assert.ErrorContains(t, err, "invalid text color")
+}
@@ -0,0 +1,90 @@
+package gitbrag
+
+import (
+	"io"
+
+	"github.com/radulucut/gitbrag/internal"
+)
+
+const (
+	FormatText = internal.FormatText
+	FormatJSON = internal.FormatJSON
+	FormatPNG  = internal.FormatPNG
+	FormatSVG  = internal.FormatSVG
+)
+
+const (
+	HeatmapCommits = internal.HeatmapCommits
+	HeatmapLines   = internal.HeatmapLines
+)
+
+// RenderOptions configures the sections and colors of the output
+type RenderOptions struct {
+	Background  string // background color of images in hex format, transparent by default
+	Color       string // text color of images in hex format, black by default
+	Lang        bool   // show the language breakdown in images
+	Heatmap     string // HeatmapCommits or HeatmapLines to draw a heatmap in images, empty to disable
+	ByRepo      bool   // show the statistics per repository
+	ByAuthor    bool   // show the author leaderboard, requires Options.ByAuthor
+	Moved       bool   // show the moved lines in the text summary, requires Options.Moved
+	BinaryBytes bool   // show the size change of binary files next to their count, requires Options.BinaryBytes
+}
+
+// Renderer writes a report in a single output format
+type Renderer interface {
+	Render(w io.Writer, report *Report) error
+}
+
+type renderer struct {
+	renderer internal.Renderer
+	opts     *internal.RunOptions
+}
+
+func (r *renderer) Render(w io.Writer, report *Report) error {
+	return r.renderer.Render(w, report, r.opts)
+}
+
+// NewRenderer creates the renderer of one of Formats
+func NewRenderer(format string, opts RenderOptions) (Renderer, error) {
+	runOpts := &internal.RunOptions{
+		Format:      format,
+		Background:  opts.Background,
+		Color:       opts.Color,
+		Lang:        opts.Lang,
+		Heatmap:     opts.Heatmap,
+		ByRepo:      opts.ByRepo,
+		ByAuthor:    opts.ByAuthor,
+		Moved:       opts.Moved,
+		BinaryBytes: opts.BinaryBytes,
+	}
+	r, err := internal.NewRenderer(format, runOpts)
+	if err != nil {
+		return nil, err
+	}
+	return &renderer{renderer: r, opts: runOpts}, nil
+}
+
+// Formats returns the names of the supported output formats
+func Formats() []string {
+	return internal.RendererFormats()
+}
+
+// NewTextRenderer creates a renderer of the text summary printed by the command
+func NewTextRenderer(opts RenderOptions) (Renderer, error) {
+	return NewRenderer(FormatText, opts)
+}
+
+// NewJSONRenderer creates a renderer of the JSON document written by --format json
+func NewJSONRenderer(opts RenderOptions) (Renderer, error) {
+	return NewRenderer(FormatJSON, opts)
+}
+
+// NewPNGRenderer creates a renderer of the statistics card as a PNG image
+func NewPNGRenderer(opts RenderOptions) (Renderer, error) {
+	return NewRenderer(FormatPNG, opts)
+}
+
+// NewSVGRenderer creates a renderer of the statistics card as an SVG image
+func NewSVGRenderer(opts RenderOptions) (Renderer, error) {
+	return NewRenderer(FormatSVG, opts)
+}